@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/agent"
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/common/tlsconfig"
+)
+
+// Транспорты отправки метрик.
+const (
+	TransportJSON  = "json"
+	TransportPlain = "plain"
+	TransportGRPC  = "grpc"
+)
+
+// Destination — сервер, которому агент отправляет метрики, со своими ключами и режимом отправки.
+type Destination struct {
+	// Name используется в логах, по умолчанию совпадает с Address.
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	// Transport — json (по умолчанию), plain или grpc; для grpc Address — адрес gRPC-сервера.
+	Transport   string `json:"transport"`
+	Gzip        bool   `json:"gzip"`
+	Batch       bool   `json:"batch"`
+	RateLimit   int    `json:"rate_limit"`
+	Key         string `json:"key"`
+	CryptoKey   string `json:"crypto_key"`
+	SigningKey  string `json:"signing_key"`
+	APIKey      string `json:"api_key"`
+	BearerToken string `json:"bearer_token"`
+	TLS         bool   `json:"tls"`
+	TLSCAFile   string `json:"tls_ca_file"`
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+	// SpoolDir — собственный каталог спула получателя; пустой — без спула.
+	SpoolDir string `json:"spool_dir"`
+}
+
+type destination struct {
+	cfg        Destination
+	sender     agent.Sender
+	spool      *agent.Spool
+	aggregator *agent.Aggregator
+	stats      *agent.SenderStats
+}
+
+// resolveDestinations возвращает получателей из файла конфигурации,
+// а без них — единственного получателя из флагов и переменных окружения.
+func resolveDestinations(cfg *Config) ([]Destination, error) {
+	if len(cfg.Destinations) == 0 {
+		transport, address := TransportJSON, cfg.Address
+		switch {
+		case cfg.Plain:
+			transport = TransportPlain
+		case cfg.GrpcEnabled:
+			transport, address = TransportGRPC, cfg.GrpcAddress
+		}
+
+		return []Destination{{
+			Name:        address,
+			Address:     address,
+			Transport:   transport,
+			Gzip:        cfg.EnableGzip,
+			Batch:       cfg.Batch,
+			RateLimit:   cfg.RateLimit,
+			Key:         cfg.SecretKey,
+			CryptoKey:   cfg.CryptoKey,
+			SigningKey:  cfg.SigningKey,
+			APIKey:      cfg.APIKey,
+			BearerToken: cfg.BearerToken,
+			TLS:         cfg.TLS,
+			TLSCAFile:   cfg.TLSCAFile,
+			TLSCertFile: cfg.TLSCertFile,
+			TLSKeyFile:  cfg.TLSKeyFile,
+			SpoolDir:    cfg.SpoolDir,
+		}}, nil
+	}
+
+	names := make(map[string]struct{}, len(cfg.Destinations))
+	spools := make(map[string]struct{}, len(cfg.Destinations))
+	destinations := make([]Destination, 0, len(cfg.Destinations))
+	for i, dst := range cfg.Destinations {
+		if dst.Address == "" {
+			return nil, fmt.Errorf("destination %d: address is required", i+1)
+		}
+		if dst.Name == "" {
+			dst.Name = dst.Address
+		}
+		if dst.Transport == "" {
+			dst.Transport = TransportJSON
+		}
+		switch dst.Transport {
+		case TransportJSON, TransportPlain, TransportGRPC:
+		default:
+			return nil, fmt.Errorf("destination %s: unknown transport %q", dst.Name, dst.Transport)
+		}
+		if _, dup := names[dst.Name]; dup {
+			return nil, fmt.Errorf("duplicate destination %q", dst.Name)
+		}
+		names[dst.Name] = struct{}{}
+		if dst.SpoolDir != "" {
+			// Спул хранит пачки одного получателя, общий каталог перемешал бы их.
+			if _, dup := spools[dst.SpoolDir]; dup {
+				return nil, fmt.Errorf("destination %s: spool dir %s is already used", dst.Name, dst.SpoolDir)
+			}
+			spools[dst.SpoolDir] = struct{}{}
+		}
+		destinations = append(destinations, dst)
+	}
+
+	return destinations, nil
+}
+
+func openDestinations(cfg *Config, telemetry *agent.Telemetry) ([]*destination, error) {
+	configs, err := resolveDestinations(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opened := make([]*destination, 0, len(configs))
+	closeOpened := func() {
+		for _, dst := range opened {
+			dst.sender.Close()
+		}
+	}
+	for _, dstCfg := range configs {
+		dst, err := openDestination(cfg, dstCfg, telemetry.Sender(dstCfg.Name))
+		if err != nil {
+			closeOpened()
+			return nil, fmt.Errorf("destination %s: %w", dstCfg.Name, err)
+		}
+		opened = append(opened, dst)
+	}
+
+	return opened, nil
+}
+
+func openDestination(cfg *Config, dstCfg Destination, stats *agent.SenderStats) (*destination, error) {
+	aggregator, err := agent.NewAggregator(cfg.GaugeAggregation, cfg.GaugeAggregationRules)
+	if err != nil {
+		return nil, err
+	}
+
+	var spool *agent.Spool
+	if dstCfg.SpoolDir != "" {
+		spool, err = agent.NewSpool(dstCfg.SpoolDir, cfg.SpoolMaxBytes, time.Duration(cfg.SpoolMaxAge)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sender, err := createSender(dstCfg, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &destination{cfg: dstCfg, sender: sender, spool: spool, aggregator: aggregator, stats: stats}, nil
+}
+
+func createSender(cfg Destination, stats *agent.SenderStats) (agent.Sender, error) {
+	var tlsConfig *tls.Config
+	if cfg.TLS || cfg.TLSCAFile != "" || cfg.TLSCertFile != "" {
+		c, err := tlsconfig.NewClientConfig(tlsconfig.Options{
+			CertFile: cfg.TLSCertFile,
+			KeyFile:  cfg.TLSKeyFile,
+			CAFile:   cfg.TLSCAFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = c
+	}
+
+	var keySigner *signature.KeySigner
+	if cfg.SigningKey != "" {
+		s, err := signature.LoadKeySigner(cfg.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		keySigner = s
+	}
+
+	if cfg.Transport == TransportPlain {
+		return agent.NewMetricURLSender(cfg.Address).
+			WithKeySigner(keySigner).
+			WithTLS(tlsConfig).
+			WithAPIKey(cfg.APIKey).
+			WithBearerToken(cfg.BearerToken).
+			WithStats(stats), nil
+	}
+
+	var signer *signature.Signer
+	if cfg.Key != "" {
+		signer = signature.NewSign(cfg.Key)
+	}
+
+	var encrypter *agent.Encrypter
+	if cfg.CryptoKey != "" {
+		e, err := agent.NewEncrypterFromFile(cfg.CryptoKey)
+		if err != nil {
+			return nil, err
+		}
+		encrypter = e
+	}
+
+	if cfg.Transport == TransportGRPC {
+		return agent.NewGRPCSender(cfg.Address).
+			WithTLS(tlsConfig).
+			WithSigner(signer).
+			WithKeySigner(keySigner).
+			WithEncrypter(encrypter).
+			WithAPIKey(cfg.APIKey).
+			WithBearerToken(cfg.BearerToken).
+			WithStats(stats), nil
+	}
+
+	return agent.NewJSONSender(cfg.Address, cfg.Gzip, signer, encrypter).
+		WithKeySigner(keySigner).
+		WithTLS(tlsConfig).
+		WithAPIKey(cfg.APIKey).
+		WithBearerToken(cfg.BearerToken).
+		WithStats(stats), nil
+}
@@ -8,32 +8,50 @@ import (
 	"syscall"
 	"time"
 
-	"strconv"
-	"strings"
-
 	"github.com/GoLessons/go-musthave-metrics/internal/agent"
 	"github.com/GoLessons/go-musthave-metrics/internal/agent/collector"
+	"github.com/GoLessons/go-musthave-metrics/internal/agent/push"
 	"github.com/GoLessons/go-musthave-metrics/internal/agent/reader"
 	"github.com/GoLessons/go-musthave-metrics/internal/common/buildinfo"
-	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
-	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
-	"github.com/GoLessons/go-musthave-metrics/internal/model"
-	fileconfig "github.com/GoLessons/go-musthave-metrics/pkg/file-config"
+	"github.com/GoLessons/go-musthave-metrics/pkg/settings"
 	"github.com/spf13/cobra"
 )
 
 type Config struct {
-	Address        string `env:"ADDRESS" envDefault:"localhost:8080"`
-	ReportInterval int    `env:"REPORT_INTERVAL" envDefault:"10"`
-	PollInterval   int    `env:"POLL_INTERVAL" envDefault:"2"`
-	Plain          bool   `env:"PLAIN" envDefault:"false"`
-	EnableGzip     bool   `env:"GZIP" envDefault:"false"`
-	Batch          bool   `env:"BATCH" envDefault:"false"`
-	SecretKey      string `env:"KEY" envDefault:""`
-	RateLimit      int    `env:"RATE_LIMIT" envDefault:"0"`
-	CryptoKey      string `env:"CRYPTO_KEY" envDefault:""`
-	GrpcEnabled    bool   `env:"GRPC_ENABLED" envDefault:"false"`
-	GrpcAddress    string `env:"GRPC_ADDRESS" envDefault:"localhost:50051"`
+	Address        string `env:"ADDRESS" flag:"address,a" usage:"HTTP server address"`
+	ReportInterval int    `env:"REPORT_INTERVAL" flag:"report,r" usage:"Report interval in seconds"`
+	PollInterval   int    `env:"POLL_INTERVAL" flag:"poll,p" usage:"Poll interval in seconds"`
+	Plain          bool   `env:"PLAIN" flag:"plain" usage:"Use plain text format instead of JSON"`
+	EnableGzip     bool   `env:"GZIP" flag:"gzip" usage:"Disable gzip compression for JSON requests"`
+	Batch          bool   `env:"BATCH" flag:"batch,b" usage:"Send metrics in batch mode"`
+	SecretKey      string `env:"KEY" flag:"key,k" usage:"SecretKey for signing metrics" secret:"true"`
+	RateLimit      int    `env:"RATE_LIMIT" flag:"rate-limit,l" usage:"Rate limit for sending metrics"`
+	CryptoKey      string `env:"CRYPTO_KEY" flag:"crypto-key" usage:"Public key or certificate path for payload encryption"`
+	GrpcEnabled    bool   `env:"GRPC_ENABLED" flag:"grpc" usage:"Enable gRPC sending"`
+	GrpcAddress    string `env:"GRPC_ADDRESS" flag:"grpc-address" usage:"gRPC server address"`
+	APIKey         string `env:"API_KEY" flag:"api-key" usage:"Tenant API key" secret:"true"`
+	BearerToken    string `env:"BEARER_TOKEN" flag:"bearer-token" usage:"JWT for bearer authentication" secret:"true"`
+	TLS            bool   `env:"TLS" flag:"tls" usage:"Use TLS for HTTP and gRPC (implied by --tls-ca and --tls-cert)"`
+	TLSCAFile      string `env:"TLS_CA_FILE" flag:"tls-ca" usage:"CA bundle for server certificate verification"`
+	TLSCertFile    string `env:"TLS_CERT_FILE" flag:"tls-cert" usage:"Client certificate for mTLS"`
+	TLSKeyFile     string `env:"TLS_KEY_FILE" flag:"tls-key" usage:"Client private key for mTLS"`
+	SigningKey     string `env:"SIGNING_KEY" flag:"signing-key" usage:"Agent Ed25519 or ECDSA private key for request signing"`
+	SpoolDir       string `env:"SPOOL_DIR" flag:"spool-dir" usage:"Directory for buffering unsent metrics while the server is unreachable"`
+	SpoolMaxBytes  int64  `env:"SPOOL_MAX_BYTES" flag:"spool-max-bytes" usage:"Spool size limit in bytes"`
+	SpoolMaxAge    int    `env:"SPOOL_MAX_AGE" flag:"spool-max-age" usage:"Max age of spooled gauges in seconds (0 keeps them forever)"`
+	PushAddress    string `env:"PUSH_ADDRESS" flag:"push-address" usage:"Local TCP address accepting /update and /updates from applications"`
+	PushSocket     string `env:"PUSH_SOCKET" flag:"push-socket" usage:"Unix socket accepting /update and /updates from applications"`
+	// SelfTelemetry добавляет к отправке метрики работы самого агента (Agent*).
+	SelfTelemetry bool `env:"SELF_TELEMETRY" flag:"self-telemetry" usage:"Send agent's own send and collection metrics"`
+	// GaugeAggregation — свёртка gauge между отправками: last, min, max или avg.
+	GaugeAggregation string `env:"GAUGE_AGGREGATION" flag:"gauge-aggregation" usage:"How gauges are folded between reports: last, min, max or avg"`
+	// GaugeAggregationRules задаются только в файле конфигурации: шаблон имени → способ свёртки.
+	GaugeAggregationRules map[string]string `json:"gauge_aggregation_rules"`
+	// Destinations задаются только в файле конфигурации; если список не пуст,
+	// он заменяет получателя из флагов и переменных окружения. В отчёте скрываются целиком: в них ключи.
+	Destinations []Destination `json:"destinations" secret:"true"`
+	// Readers задаются только в файле конфигурации: включение, интервал, префикс и фильтры читателей.
+	Readers map[string]reader.Config `json:"readers"`
 }
 
 var buildVersion string
@@ -41,23 +59,30 @@ var buildDate string
 var buildCommit string
 
 func main() {
-	buildinfo.PrintBuildInfo(buildVersion, buildDate, buildCommit)
 	var rootCmd = &cobra.Command{
 		Use:   "agent",
 		Short: "Metrics agent for collecting and sending metrics",
 	}
 
-	cfg, err := loadConfig(rootCmd)
+	cfg, loader, err := loadConfig(rootCmd)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	var report settings.Report
 	rootCmd.PreRunE = func(cmd *cobra.Command, args []string) error {
-		return overrideWithEnv(cfg)
+		report, err = loader.WithFile(configFilePath(cmd)).Load()
+		return err
 	}
 
 	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if printConfig, _ := cmd.Flags().GetBool("print-config"); printConfig {
+			_, err := report.WriteTo(os.Stdout)
+			return err
+		}
+
+		buildinfo.PrintBuildInfo(buildVersion, buildDate, buildCommit)
 		if cfg.ReportInterval <= 0 {
 			return fmt.Errorf("report interval must be positive, got %d", cfg.ReportInterval)
 		}
@@ -85,32 +110,60 @@ func run(cfg *Config) error {
 		fmt.Println("Получен сигнал завершения, завершаем работу...")
 	}()
 
-	metricsStorage := storage.NewMemStorage[model.Metrics]()
-	readers := []agent.Reader{reader.NewRuntimeMetricsReader(), reader.NewSystemMetricsReader()}
+	pollDuration := time.Duration(cfg.PollInterval) * time.Second
+	readers, err := reader.DefaultRegistry().Build(cfg.Readers, pollDuration)
+	if err != nil {
+		return err
+	}
 	simpleReader := reader.NewSimpleMetricsReader()
-	sender, err := createSender(cfg)
+
+	var telemetry *agent.Telemetry
+	if cfg.SelfTelemetry {
+		telemetry = agent.NewTelemetry()
+		readers = append(readers, telemetry)
+	}
+
+	destinations, err := openDestinations(cfg, telemetry)
 	if err != nil {
 		return err
 	}
-	defer sender.Close()
+	defer func() {
+		for _, dst := range destinations {
+			dst.sender.Close()
+		}
+	}()
+
+	if cfg.PushAddress != "" || cfg.PushSocket != "" {
+		listeners, err := push.Listen(cfg.PushAddress, cfg.PushSocket)
+		if err != nil {
+			return err
+		}
+		pushReader := reader.NewPushMetricsReader()
+		readers = append(readers, pushReader)
+		pushDone := make(chan struct{})
+		go func() {
+			defer close(pushDone)
+			if err := push.Serve(ctx, push.NewHandler(pushReader), listeners); err != nil {
+				fmt.Printf("Ошибка локального приёма метрик: %v\n", err)
+			}
+		}()
+		defer func() { <-pushDone }()
+	}
 
-	pollDuration := time.Duration(cfg.PollInterval) * time.Second
 	dumpInterval := time.Duration(cfg.ReportInterval) * time.Second
 
-	collectAndSendMetrics(ctx, metricsStorage, readers, simpleReader, sender, pollDuration, dumpInterval, cfg.RateLimit, cfg.Batch)
+	collectAndSendMetrics(ctx, destinations, readers, simpleReader, telemetry, pollDuration, dumpInterval)
 
 	return nil
 }
 
 func collectAndSendMetrics(
 	ctx context.Context,
-	stg storage.Storage[model.Metrics],
+	destinations []*destination,
 	readers []agent.Reader,
 	simpleReader *reader.SimpleMetricsReader,
-	sender agent.Sender,
+	telemetry *agent.Telemetry,
 	pollDuration, dumpInterval time.Duration,
-	rateLimit int,
-	batch bool,
 ) {
 	pollTicker := time.NewTicker(pollDuration)
 	defer pollTicker.Stop()
@@ -118,163 +171,53 @@ func collectAndSendMetrics(
 	dumpTicker := time.NewTicker(dumpInterval)
 	defer dumpTicker.Stop()
 
-	sendChan, stopSender := collector.StartSenderPipeline(ctx, sender, rateLimit, batch, 1)
+	// У каждого получателя свой отправитель с повторами, размыкателем и спулом.
+	pipelines := make([]collector.Destination, 0, len(destinations))
+	stops := make([]func(), 0, len(destinations))
+	for _, dst := range destinations {
+		sendChan, stop := collector.StartSenderPipeline(ctx, dst.sender, dst.cfg.RateLimit, dst.cfg.Batch, dst.spool, dst.stats, 1)
+		pipelines = append(pipelines, collector.Destination{Name: dst.cfg.Name, Aggregator: dst.aggregator, Out: sendChan})
+		stops = append(stops, stop)
+	}
+	stopSenders := func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
 
-	collector.RunAgentLoop(ctx, pollTicker, dumpTicker, stg, readers, simpleReader, sendChan, stopSender)
+	collector.RunAgentLoop(ctx, pollTicker, dumpTicker, pipelines, readers, simpleReader, telemetry, stopSenders)
 }
 
-func loadConfig(cmd *cobra.Command) (*Config, error) {
-	envAddress := os.Getenv("ADDRESS")
-	if envAddress == "" {
-		envAddress = "localhost:8080"
+// loadConfig объявляет флаги агента; значения из файла, окружения и флагов применяет возвращённый загрузчик.
+func loadConfig(cmd *cobra.Command) (*Config, *settings.Loader, error) {
+	cfg := &Config{
+		Address:          "localhost:8080",
+		ReportInterval:   10,
+		PollInterval:     2,
+		GrpcAddress:      "localhost:50051",
+		SpoolMaxBytes:    10 << 20,
+		SpoolMaxAge:      3600,
+		GaugeAggregation: agent.GaugeLast,
+		SelfTelemetry:    true,
 	}
 
-	defaults := &Config{
-		Address:        envAddress,
-		ReportInterval: 10,
-		PollInterval:   2,
-		Plain:          false,
-		EnableGzip:     false,
-		Batch:          false,
-		SecretKey:      "",
-		RateLimit:      0,
-		CryptoKey:      "",
-		GrpcEnabled:    false,
-		GrpcAddress:    "localhost:50051",
-	}
-	if configPath := getFileConfigPath(); configPath != "" {
-		if err := fileconfig.LoadInto(configPath, defaults); err != nil {
-			return nil, fmt.Errorf("ошибка чтения файла конфигурации: %w", err)
-		}
+	loader, err := settings.New(cfg)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	cfg := &Config{}
-
-	cmd.Flags().StringP("config", "c", "", "Path to agent config JSON")
-	cmd.Flags().StringVarP(&cfg.Address, "address", "a", defaults.Address, "HTTP server address")
-	cmd.Flags().IntVarP(&cfg.ReportInterval, "report", "r", defaults.ReportInterval, "Report interval in seconds")
-	cmd.Flags().IntVarP(&cfg.PollInterval, "poll", "p", defaults.PollInterval, "Poll interval in seconds")
-	cmd.Flags().BoolVarP(&cfg.Plain, "plain", "", defaults.Plain, "Use plain text format instead of JSON")
-	cmd.Flags().BoolVarP(&cfg.EnableGzip, "gzip", "", defaults.EnableGzip, "Disable gzip compression for JSON requests")
-	cmd.Flags().BoolVarP(&cfg.Batch, "batch", "b", defaults.Batch, "Send metrics in batch mode")
-	cmd.Flags().StringVarP(&cfg.SecretKey, "key", "k", defaults.SecretKey, "SecretKey for signing metrics")
-	cmd.Flags().IntVarP(&cfg.RateLimit, "rate-limit", "l", defaults.RateLimit, "Rate limit for sending metrics")
-	cmd.Flags().StringVarP(&cfg.CryptoKey, "crypto-key", "", defaults.CryptoKey, "Public key or certificate path for payload encryption")
-	cmd.Flags().BoolVarP(&cfg.GrpcEnabled, "grpc", "", defaults.GrpcEnabled, "Enable gRPC sending")
-	cmd.Flags().StringVarP(&cfg.GrpcAddress, "grpc-address", "", defaults.GrpcAddress, "gRPC server address")
+	cmd.Flags().StringP("config", "c", "", "Path to agent config file (JSON, YAML or TOML)")
+	cmd.Flags().Bool("print-config", false, "Print the effective configuration with value sources and exit")
+	loader.DefinePFlags(cmd.Flags())
 
-	return cfg, nil
+	return cfg, loader, nil
 }
 
-func getFileConfigPath() string {
+// configFilePath возвращает путь к файлу конфигурации: переменная CONFIG важнее флага --config/-c.
+func configFilePath(cmd *cobra.Command) string {
 	if v := os.Getenv("CONFIG"); v != "" {
 		return v
 	}
-	args := os.Args[1:]
-	for i := 0; i < len(args); i++ {
-		a := args[i]
-		if strings.HasPrefix(a, "-c=") {
-			return strings.TrimPrefix(a, "-c=")
-		}
-		if strings.HasPrefix(a, "-config=") {
-			return strings.TrimPrefix(a, "-config=")
-		}
-		if a == "-c" || a == "-config" {
-			if i+1 < len(args) {
-				return args[i+1]
-			}
-		}
-	}
-	return ""
-}
-
-func overrideWithEnv(cfg *Config) error {
-	if v := os.Getenv("ADDRESS"); v != "" {
-		cfg.Address = v
-	}
-	if v := os.Getenv("REPORT_INTERVAL"); v != "" {
-		n, err := strconv.Atoi(v)
-		if err != nil {
-			return fmt.Errorf("ошибка парсинга REPORT_INTERVAL: %w", err)
-		}
-		cfg.ReportInterval = n
-	}
-	if v := os.Getenv("POLL_INTERVAL"); v != "" {
-		n, err := strconv.Atoi(v)
-		if err != nil {
-			return fmt.Errorf("ошибка парсинга POLL_INTERVAL: %w", err)
-		}
-		cfg.PollInterval = n
-	}
-	if v := os.Getenv("PLAIN"); v != "" {
-		b, err := strconv.ParseBool(v)
-		if err != nil {
-			return fmt.Errorf("ошибка парсинга PLAIN: %w", err)
-		}
-		cfg.Plain = b
-	}
-	if v := os.Getenv("GZIP"); v != "" {
-		b, err := strconv.ParseBool(v)
-		if err != nil {
-			return fmt.Errorf("ошибка парсинга GZIP: %w", err)
-		}
-		cfg.EnableGzip = b
-	}
-	if v := os.Getenv("BATCH"); v != "" {
-		b, err := strconv.ParseBool(v)
-		if err != nil {
-			return fmt.Errorf("ошибка парсинга BATCH: %w", err)
-		}
-		cfg.Batch = b
-	}
-	if v := os.Getenv("KEY"); v != "" {
-		cfg.SecretKey = v
-	}
-	if v := os.Getenv("RATE_LIMIT"); v != "" {
-		n, err := strconv.Atoi(v)
-		if err != nil {
-			return fmt.Errorf("ошибка парсинга RATE_LIMIT: %w", err)
-		}
-		cfg.RateLimit = n
-	}
-	if v := os.Getenv("CRYPTO_KEY"); v != "" {
-		cfg.CryptoKey = v
-	}
-	if v := os.Getenv("GRPC_ENABLED"); v != "" {
-		b, err := strconv.ParseBool(v)
-		if err != nil {
-			return fmt.Errorf("ошибка парсинга GRPC_ENABLED: %w", err)
-		}
-		cfg.GrpcEnabled = b
-	}
-	if v := os.Getenv("GRPC_ADDRESS"); v != "" {
-		cfg.GrpcAddress = v
-	}
-	return nil
-}
-
-func createSender(cfg *Config) (agent.Sender, error) {
-	if cfg.Plain {
-		return agent.NewMetricURLSender(cfg.Address), nil
-	}
-
-	var signer *signature.Signer
-	if cfg.SecretKey != "" {
-		signer = signature.NewSign(cfg.SecretKey)
-	}
-
-	var encrypter *agent.Encrypter
-	if cfg.CryptoKey != "" {
-		e, err := agent.NewEncrypterFromFile(cfg.CryptoKey)
-		if err != nil {
-			return nil, err
-		}
-		encrypter = e
-	}
-
-	if cfg.GrpcEnabled {
-		return agent.NewGRPCSender(cfg.GrpcAddress), nil
-	}
-
-	return agent.NewJSONSender(cfg.Address, cfg.EnableGzip, signer, encrypter), nil
+	path, _ := cmd.Flags().GetString("config")
+	return path
 }
@@ -11,6 +11,7 @@ import (
 	config "github.com/GoLessons/go-musthave-metrics/internal/server/config"
 	container2 "github.com/GoLessons/go-musthave-metrics/internal/server/container"
 	servergrpc "github.com/GoLessons/go-musthave-metrics/internal/server/grpc"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
 	"github.com/GoLessons/go-musthave-metrics/pkg/container"
 	"go.uber.org/zap"
 )
@@ -20,6 +21,20 @@ var buildDate string
 var buildCommit string
 
 func main() {
+	launch, err := config.ParseLaunch()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if launch.PrintConfig {
+		if err := config.PrintConfig(os.Stdout); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	buildinfo.PrintBuildInfo(buildVersion, buildDate, buildCommit)
 	c, err := container2.InitContainer()
 	if err != nil {
@@ -50,13 +65,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	healthChecks, err := container.GetService[health.Health](c, "health")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	grpcState := healthChecks.State("grpc")
+
 	serverLogger.Info("grpc server listening", zap.String("address", listener.Addr().String()))
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
+	grpcState.Set(nil)
 	go func() {
 		if err := server.Serve(listener); err != nil {
+			grpcState.Set(err)
 			serverLogger.Error("grpc server error", zap.Error(err))
 		}
 	}()
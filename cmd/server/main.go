@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"fmt"
 	"log"
@@ -13,22 +14,32 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/common/buildinfo"
-	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/common/tlsconfig"
+	containerConfig "github.com/GoLessons/go-musthave-metrics/internal/config"
 	apiModel "github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/audit"
 	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
 	container2 "github.com/GoLessons/go-musthave-metrics/internal/server/container"
 	database "github.com/GoLessons/go-musthave-metrics/internal/server/db"
-	"github.com/GoLessons/go-musthave-metrics/internal/server/model"
+	servergrpc "github.com/GoLessons/go-musthave-metrics/internal/server/grpc"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/middleware"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/reload"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
 	"github.com/GoLessons/go-musthave-metrics/pkg/container"
 	"github.com/GoLessons/go-musthave-metrics/pkg/repeater"
 	"github.com/go-chi/chi/v5"
 	"github.com/goccy/go-json"
 	"go.uber.org/zap"
+	gogrpc "google.golang.org/grpc"
 )
 
 var buildVersion string
@@ -46,13 +57,28 @@ func preWarmDecoders() {
 }
 
 func main() {
+	launch, err := config2.ParseLaunch()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if launch.PrintConfig {
+		if err := config2.PrintConfig(os.Stdout); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	buildinfo.PrintBuildInfo(buildVersion, buildDate, buildCommit)
 	c, err := container2.InitContainer()
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
-	mainCtx := context.Background()
+	mainCtx, cancelMain := context.WithCancel(context.Background())
+	defer cancelMain()
 
 	serverLogger, err := container.GetService[zap.Logger](c, "logger")
 	if err != nil {
@@ -82,7 +108,20 @@ func main() {
 		}()
 	}
 
-	serverLogger.Info("Server config", zap.Any("cfg", cfg))
+	// Секреты в журнал не попадают: печатается тот же замаскированный отчёт, что и по -print-config.
+	if _, report, err := config2.LoadConfigReport(nil); err == nil {
+		fields := make([]zap.Field, 0, len(report))
+		for _, entry := range report {
+			fields = append(fields, zap.String(entry.Path, entry.Value))
+		}
+		serverLogger.Info("Server config", fields...)
+	}
+
+	healthChecks, err := container.GetService[health.Health](c, "health")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	var db *sql.DB
 	if cfg.DatabaseDsn != "" {
@@ -97,60 +136,51 @@ func main() {
 				fmt.Printf("Error: %v\n", err)
 			}
 		}(db)
-
-		if err := tryMigrateDB(cfg, db, serverLogger); err != nil {
-			serverLogger.Warn("Migrations error", zap.Error(err))
-		}
 	}
 
-	storageCounter, err := container.GetService[storage.MemStorage[model.Counter]](c, "counterStorage")
+	metricServices, err := container.GetService[service.TenantMetricServices](c, "tenantServices")
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	storageGauge, err := container.GetService[storage.MemStorage[model.Gauge]](c, "gaugeStorage")
+	restorer, err := container.GetService[service.MetricRestorer](c, "restorer")
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	metricService := service.NewMetricService(storageCounter, storageGauge)
-
-	restorer, err := container.GetService[service.MetricRestorer](c, "restorer")
+	dumper, err := container.GetService[service.MetricDumper](c, "dumper")
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
-	if cfg.DumpConfig.Restore {
-		try := repeater.NewRepeater(func(err error) {
-			serverLogger.Info("Неудачная попытка восстановить состояние", zap.Error(err))
-		})
-		repeatStrategy := repeater.NewFixedDelaysStrategy(
-			database.NewPostgresErrorClassifier().IsRetriable,
-			time.Second*1,
-			time.Second*3,
-			time.Second*5,
-		)
-		_, err := try.Repeat(
-			repeatStrategy,
-			func() (any, error) {
-				err := service.RestoreState(metricService, *restorer)
-				return nil, err
-			},
-		)
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
-		}
-		serverLogger.Info("server state restored", zap.String("FILE_STORAGE_PATH", cfg.DumpConfig.FileStoragePath))
+
+	auditSubject, err := container.GetService[audit.AuditSubject](c, "auditSubject")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
+	// Проверка регистрируется всегда: аудит можно включить перезагрузкой конфигурации, а без наблюдателей она успешна.
+	healthChecks.Register("audit", auditSubject.Ping)
 
-	dumper, err := container.GetService[service.MetricDumper](c, "dumper")
+	// Этапы запуска идут после открытия порта: /healthz отвечает сразу, а /readyz — только когда они завершены.
+	// До тех пор шлюз запуска отклоняет остальные HTTP-запросы и вызовы gRPC, кроме проб.
+	startupGate, err := container.GetService[health.Gate](c, "startupGate")
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	var migrationsState *health.State
+	if db != nil {
+		migrationsState = healthChecks.State("migrations")
+	}
+	var restoreState *health.State
+	if cfg.DumpConfig.Restore {
+		restoreState = healthChecks.State("restore")
+	}
+	dumperState := healthChecks.State("dumper")
+	dumperState.Set(nil)
 
 	r, err := container.GetService[chi.Mux](c, "router")
 	if err != nil {
@@ -164,6 +194,19 @@ func main() {
 	}
 	defer listener.Close()
 
+	if cfg.TLSConfig.Enabled() {
+		tlsConfig, err := tlsconfig.NewServerConfig(tlsconfig.Options{
+			CertFile: cfg.TLSConfig.CertFile,
+			KeyFile:  cfg.TLSConfig.KeyFile,
+			CAFile:   cfg.TLSConfig.ClientCAFile,
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
 	serverLogger.Info("server listening", zap.String("address", listener.Addr().String()))
 
 	server := &http.Server{
@@ -173,6 +216,11 @@ func main() {
 		Handler:      r,
 	}
 
+	storeInterval := &atomic.Uint64{}
+	storeInterval.Store(cfg.DumpConfig.StoreInterval)
+	reloader := newReloader(c, cfg, serverLogger, auditSubject, storeInterval)
+	go reloader.Run(mainCtx, launch.File, time.Duration(cfg.ConfigWatchInterval)*time.Second)
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
@@ -182,9 +230,52 @@ func main() {
 		}
 	}()
 
-	go iterateFunc(mainCtx, cfg.DumpConfig.StoreInterval, func() {
-		storeMetrics(serverLogger, metricService, dumper)
-	})
+	var grpcServer *gogrpc.Server
+	if cfg.GrpcEnabled {
+		grpcServer = startGRPCServer(c, cfg, serverLogger, healthChecks.State("grpc"))
+	}
+
+	if migrationsState != nil {
+		err := tryMigrateDB(cfg, db, serverLogger)
+		if err != nil {
+			serverLogger.Warn("Migrations error", zap.Error(err))
+		}
+		migrationsState.Set(err)
+	}
+
+	if restoreState != nil {
+		try := repeater.NewRepeater(func(err error) {
+			serverLogger.Info("Неудачная попытка восстановить состояние", zap.Error(err))
+		})
+		repeatStrategy := createStoreRetryStrategy()
+		_, err := try.RepeatContext(
+			mainCtx,
+			repeatStrategy,
+			func() (any, error) {
+				err := service.RestoreState(metricServices, *restorer)
+				return nil, err
+			},
+		)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		restoreState.Set(nil)
+		serverLogger.Info("server state restored", zap.String("FILE_STORAGE_PATH", cfg.DumpConfig.FileStoragePath))
+		for tenantID, metricService := range metricServices.All() {
+			counters, gauges := metricService.Size()
+			serverLogger.Info("restored metrics", zap.String("tenant", tenantID), zap.Int("counters", counters), zap.Int("gauges", gauges))
+		}
+	}
+	startupGate.Open()
+
+	storeDone := make(chan struct{})
+	go func() {
+		defer close(storeDone)
+		iterateFunc(mainCtx, storeInterval.Load, func(ctx context.Context) {
+			storeMetrics(ctx, serverLogger, metricServices, dumper, dumperState)
+		})
+	}()
 
 	<-quit
 	serverLogger.Debug("Получен сигнал завершения работы")
@@ -194,6 +285,17 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		serverLogger.Debug("Ошибка при завершении работы сервера", zap.Error(err))
 	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+	// Запросы завершены: удалённый аудит дописывает очередь, остаток уходит в запасной файл.
+	if err := auditSubject.Close(); err != nil {
+		serverLogger.Error("Ошибка при закрытии аудита", zap.Error(err))
+	}
+
+	// Отмена прерывает повторы текущего сохранения, финальное сохранение ограничено shutdownStoreTimeout.
+	cancelMain()
+	<-storeDone
 
 	// Запись heap-профиля при завершении (если включено)
 	if cfg.PprofOnShutdown {
@@ -219,23 +321,32 @@ func main() {
 	serverLogger.Debug("Сервер остановлен")
 }
 
-func storeMetrics(serverLogger *zap.Logger, metricService *service.MetricService, dumper *service.MetricDumper) {
-	try := repeater.NewRepeater(func(err error) {
-		serverLogger.Error("Ошибка сохранения состояния", zap.Error(err))
-	})
-	repeatStrategy := repeater.NewFixedDelaysStrategy(
+// shutdownStoreTimeout ограничивает финальное сохранение состояния при остановке сервера.
+const shutdownStoreTimeout = 5 * time.Second
+
+func createStoreRetryStrategy() repeater.Strategy {
+	return repeater.NewExponentialBackoffStrategy(
 		database.NewPostgresErrorClassifier().IsRetriable,
 		time.Second*1,
-		time.Second*3,
 		time.Second*5,
+		3,
 	)
-	_, err := try.Repeat(
-		repeatStrategy,
+}
+
+// storeMetrics сохраняет состояние; итог последней попытки попадает в проверку готовности.
+func storeMetrics(ctx context.Context, serverLogger *zap.Logger, metricServices *service.TenantMetricServices, dumper *service.MetricDumper, state *health.State) {
+	try := repeater.NewRepeater(func(err error) {
+		serverLogger.Error("Ошибка сохранения состояния", zap.Error(err))
+	})
+	_, err := try.RepeatContext(
+		ctx,
+		createStoreRetryStrategy(),
 		func() (any, error) {
-			err := service.StoreState(metricService, *dumper)
+			err := service.StoreState(metricServices, *dumper)
 			return nil, err
 		},
 	)
+	state.Set(err)
 	if err != nil {
 		serverLogger.Error("Ошибка сохранения состояния после повторов", zap.Error(err))
 		return
@@ -243,6 +354,61 @@ func storeMetrics(serverLogger *zap.Logger, metricService *service.MetricService
 	serverLogger.Info("Состояние сервера сохранено")
 }
 
+// startGRPCServer запускает gRPC-сервер в том же процессе, с общим хранилищем и проверками готовности.
+func startGRPCServer(c container.Container, cfg *config2.Config, serverLogger *zap.Logger, state *health.State) *gogrpc.Server {
+	grpcServer, err := servergrpc.BuildGRPCServer(c)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	grpcListener, err := net.Listen("tcp", cfg.GrpcAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	serverLogger.Info("grpc server listening", zap.String("address", grpcListener.Addr().String()))
+	state.Set(nil)
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			state.Set(err)
+			serverLogger.Error("grpc server error", zap.Error(err))
+		}
+	}()
+
+	return grpcServer
+}
+
+// newReloader собирает перезагрузку конфигурации из разделяемых объектов контейнера.
+func newReloader(c container.Container, cfg *config2.Config, serverLogger *zap.Logger, auditSubject *audit.AuditSubject, storeInterval *atomic.Uint64) *reload.Reloader {
+	var registry *telemetry.Registry
+	if svc, err := container.GetService[telemetry.Registry](c, "telemetry"); err == nil {
+		registry = svc
+	}
+
+	targets := reload.Targets{
+		Audit: auditSubject,
+		AuditObservers: func(next *config2.Config) []audit.Auditor {
+			return containerConfig.AuditObservers(next, registry)
+		},
+		StoreInterval: storeInterval,
+	}
+	if svc, err := container.GetService[security.TrustedSubnet](c, "trustedSubnet"); err == nil {
+		targets.TrustedSubnet = svc
+	}
+	if svc, err := container.GetService[signature.Signer](c, "signer"); err == nil {
+		targets.Signer = svc
+	}
+	if svc, err := container.GetService[middleware.Decrypter](c, "decrypter"); err == nil {
+		targets.Decrypter = svc
+	}
+	if svc, err := container.GetService[zap.AtomicLevel](c, "logLevel"); err == nil {
+		targets.LogLevel = svc
+	}
+
+	return reload.NewReloader(cfg, targets, serverLogger)
+}
+
 func tryMigrateDB(cfg *config2.Config, db *sql.DB, serverLogger *zap.Logger) error {
 	if cfg.DatabaseDsn != "" {
 		migrator := database.NewMigrator(db, serverLogger)
@@ -253,17 +419,21 @@ func tryMigrateDB(cfg *config2.Config, db *sql.DB, serverLogger *zap.Logger) err
 	return nil
 }
 
-func iterateFunc(ctx context.Context, interval uint64, callable func()) {
-	ticker := time.NewTicker(time.Duration(interval) * time.Second)
-	defer ticker.Stop()
+// iterateFunc вызывает callable с периодом interval в секундах; период перечитывается перед каждым ожиданием.
+func iterateFunc(ctx context.Context, interval func() uint64, callable func(ctx context.Context)) {
+	timer := time.NewTimer(time.Duration(interval()) * time.Second)
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			callable()
+			finalCtx, cancel := context.WithTimeout(context.Background(), shutdownStoreTimeout)
+			callable(finalCtx)
+			cancel()
 			return
-		case <-ticker.C:
-			callable()
+		case <-timer.C:
+			callable(ctx)
+			timer.Reset(time.Duration(interval()) * time.Second)
 		}
 	}
 }
@@ -0,0 +1,294 @@
+package agent
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+)
+
+// Способы свёртки значений gauge между отправками.
+const (
+	GaugeLast = "last"
+	GaugeMin  = "min"
+	GaugeMax  = "max"
+	GaugeAvg  = "avg"
+)
+
+// gaugeIdleReports — сколько отчётов подряд gauge без новых значений ещё отправляется с последним значением;
+// после этого он забывается, чтобы исчезнувшие метрики не копились и не уходили вечно.
+const gaugeIdleReports = 10
+
+// Report — снимок агрегатов, переданный на отправку. Done сообщает итог:
+// при ошибке неотправленные значения возвращаются в агрегатор и уйдут со следующим отчётом.
+type Report struct {
+	Metrics []model.Metrics
+	done    func(error)
+}
+
+func NewReport(metrics []model.Metrics) Report {
+	return Report{Metrics: metrics}
+}
+
+func (r Report) Done(err error) {
+	if r.done != nil {
+		r.done(err)
+	}
+}
+
+type gaugeWindow struct {
+	last  float64
+	min   float64
+	max   float64
+	sum   float64
+	count int64
+	// idle — отчёты подряд, в которые окно ушло без новых значений.
+	idle int
+}
+
+func (w *gaugeWindow) observe(value float64) {
+	if w.count == 0 || value < w.min {
+		w.min = value
+	}
+	if w.count == 0 || value > w.max {
+		w.max = value
+	}
+	w.last = value
+	w.sum += value
+	w.count++
+}
+
+// merge возвращает в окно значения неотправленного снимка; более свежее last не затирается.
+func (w *gaugeWindow) merge(sent gaugeWindow) {
+	if sent.count == 0 {
+		return
+	}
+	if w.count == 0 {
+		w.last = sent.last
+		w.min, w.max = sent.min, sent.max
+	} else {
+		w.min = min(w.min, sent.min)
+		w.max = max(w.max, sent.max)
+	}
+	w.sum += sent.sum
+	w.count += sent.count
+}
+
+func (w *gaugeWindow) value(mode string) float64 {
+	if w.count == 0 {
+		return w.last
+	}
+
+	switch mode {
+	case GaugeMin:
+		return w.min
+	case GaugeMax:
+		return w.max
+	case GaugeAvg:
+		return w.sum / float64(w.count)
+	default:
+		return w.last
+	}
+}
+
+// Aggregator копит метрики между отправками: приросты counter суммируются,
+// gauge сворачиваются выбранным способом (последнее, минимум, максимум или среднее за период).
+type Aggregator struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	gauges   map[string]*gaugeWindow
+	modes    map[string]string
+
+	defaultMode string
+	rules       []string
+	ruleModes   map[string]string
+}
+
+// NewAggregator принимает способ свёртки gauge по умолчанию и правила для отдельных метрик:
+// ключ — шаблон path.Match имени, при нескольких совпадениях побеждает самый длинный шаблон.
+func NewAggregator(defaultMode string, rules map[string]string) (*Aggregator, error) {
+	if defaultMode == "" {
+		defaultMode = GaugeLast
+	}
+	if err := checkGaugeMode(defaultMode); err != nil {
+		return nil, err
+	}
+
+	patterns := make([]string, 0, len(rules))
+	for pattern, mode := range rules {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad gauge aggregation pattern %q: %w", pattern, err)
+		}
+		if err := checkGaugeMode(mode); err != nil {
+			return nil, fmt.Errorf("gauge aggregation for %q: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	return &Aggregator{
+		counters:    make(map[string]int64),
+		gauges:      make(map[string]*gaugeWindow),
+		modes:       make(map[string]string),
+		defaultMode: defaultMode,
+		rules:       patterns,
+		ruleModes:   rules,
+	}, nil
+}
+
+func checkGaugeMode(mode string) error {
+	switch mode {
+	case GaugeLast, GaugeMin, GaugeMax, GaugeAvg:
+		return nil
+	default:
+		return fmt.Errorf("unknown gauge aggregation %q, expected last, min, max or avg", mode)
+	}
+}
+
+func (a *Aggregator) Add(metrics ...model.Metrics) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, metric := range metrics {
+		switch {
+		case metric.MType == model.Counter && metric.Delta != nil:
+			a.counters[metric.ID] += *metric.Delta
+		case metric.MType == model.Gauge && metric.Value != nil:
+			window, ok := a.gauges[metric.ID]
+			if !ok {
+				window = &gaugeWindow{}
+				a.gauges[metric.ID] = window
+			}
+			window.observe(*metric.Value)
+		}
+	}
+}
+
+// Snapshot забирает накопленное в отчёт: счётчики обнуляются, окна gauge начинаются заново.
+// Counter без прироста в отчёт не попадает и забывается до следующего значения;
+// gauge без новых значений отправляется с последним значением, пока не простоит gaugeIdleReports отчётов.
+func (a *Aggregator) Snapshot() Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sentCounters := make(map[string]int64, len(a.counters))
+	sentGauges := make(map[string]gaugeWindow, len(a.gauges))
+	metrics := make([]model.Metrics, 0, len(a.counters)+len(a.gauges))
+
+	for _, id := range sortedKeys(a.gauges) {
+		window := a.gauges[id]
+		if window.count == 0 {
+			window.idle++
+			if window.idle > gaugeIdleReports {
+				delete(a.gauges, id)
+				delete(a.modes, id)
+				continue
+			}
+		} else {
+			window.idle = 0
+		}
+		value := window.value(a.modeOf(id))
+		metrics = append(metrics, *model.NewGauge(id, &value))
+		sentGauges[id] = *window
+		window.sum, window.count = 0, 0
+	}
+	for _, id := range sortedKeys(a.counters) {
+		delta := a.counters[id]
+		delete(a.counters, id)
+		if delta == 0 {
+			continue
+		}
+		metrics = append(metrics, *model.NewCounter(id, &delta))
+		sentCounters[id] = delta
+	}
+
+	return Report{
+		Metrics: metrics,
+		done: func(err error) {
+			if err != nil {
+				a.restore(unsentOnly(UnsentMetrics(err, metrics), sentCounters, sentGauges))
+			}
+		},
+	}
+}
+
+// unsentOnly оставляет из отправленного только недоставленные метрики: доставленные приросты вернуть — значит учесть их дважды.
+func unsentOnly(unsent []model.Metrics, counters map[string]int64, gauges map[string]gaugeWindow) (map[string]int64, map[string]gaugeWindow) {
+	keptCounters := make(map[string]int64, len(unsent))
+	keptGauges := make(map[string]gaugeWindow, len(unsent))
+	for _, metric := range unsent {
+		switch metric.MType {
+		case model.Counter:
+			if delta, ok := counters[metric.ID]; ok {
+				keptCounters[metric.ID] = delta
+			}
+		case model.Gauge:
+			if window, ok := gauges[metric.ID]; ok {
+				keptGauges[metric.ID] = window
+			}
+		}
+	}
+
+	return keptCounters, keptGauges
+}
+
+func (a *Aggregator) restore(counters map[string]int64, gauges map[string]gaugeWindow) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for id, delta := range counters {
+		a.counters[id] += delta
+	}
+	for id, sent := range gauges {
+		window, ok := a.gauges[id]
+		if !ok {
+			window = &gaugeWindow{}
+			a.gauges[id] = window
+		}
+		window.merge(sent)
+	}
+}
+
+func (a *Aggregator) modeOf(id string) string {
+	if mode, ok := a.modes[id]; ok {
+		return mode
+	}
+
+	mode := a.defaultMode
+	for _, pattern := range a.rules {
+		if ok, _ := path.Match(pattern, id); ok {
+			mode = a.ruleModes[pattern]
+			break
+		}
+	}
+	a.modes[id] = mode
+
+	return mode
+}
+
+// FanOut раздаёт метрики опроса агрегаторам всех получателей:
+// у каждого свой учёт отправленного, и сбой одного не задерживает остальных.
+type FanOut []*Aggregator
+
+func (f FanOut) Add(metrics ...model.Metrics) {
+	for _, agg := range f {
+		agg.Add(metrics...)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
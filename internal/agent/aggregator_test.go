@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reportByID(report Report) map[string]model.Metrics {
+	byID := make(map[string]model.Metrics, len(report.Metrics))
+	for _, metric := range report.Metrics {
+		byID[metric.ID] = metric
+	}
+	return byID
+}
+
+func TestAggregator_SumsCountersAndFoldsGauges(t *testing.T) {
+	agg, err := NewAggregator(GaugeLast, map[string]string{"CPU*": GaugeMax, "CPUutilization1": GaugeAvg, "Free*": GaugeMin})
+	require.NoError(t, err)
+
+	for _, v := range []float64{10, 40, 20} {
+		agg.Add(counter("PollCount", 1), gauge("CPUutilization1", v), gauge("CPUutilization2", v), gauge("FreeMemory", v), gauge("Alloc", v))
+	}
+
+	byID := reportByID(agg.Snapshot())
+	assert.EqualValues(t, 3, *byID["PollCount"].Delta)
+	assert.EqualValues(t, 70.0/3, *byID["CPUutilization1"].Value, "exact name beats shorter pattern")
+	assert.EqualValues(t, 40, *byID["CPUutilization2"].Value)
+	assert.EqualValues(t, 10, *byID["FreeMemory"].Value)
+	assert.EqualValues(t, 20, *byID["Alloc"].Value)
+
+	byID = reportByID(agg.Snapshot())
+	assert.NotContains(t, byID, "PollCount", "sent deltas are not sent again")
+	assert.EqualValues(t, 20, *byID["CPUutilization2"].Value, "gauge without new samples keeps its last value")
+}
+
+func TestAggregator_RestoresFailedReport(t *testing.T) {
+	agg, err := NewAggregator(GaugeMax, nil)
+	require.NoError(t, err)
+
+	agg.Add(counter("jobs", 5), gauge("queue", 9))
+	failed := agg.Snapshot()
+
+	// Пока отчёт в пути, копятся новые значения — они не должны попасть в него повторно.
+	agg.Add(counter("jobs", 2), gauge("queue", 3))
+	failed.Done(errors.New("server is down"))
+
+	byID := reportByID(agg.Snapshot())
+	assert.EqualValues(t, 7, *byID["jobs"].Delta)
+	assert.EqualValues(t, 9, *byID["queue"].Value, "window of the failed report is merged back")
+
+	agg.Add(counter("jobs", 1))
+	sent := agg.Snapshot()
+	sent.Done(nil)
+	assert.NotContains(t, reportByID(agg.Snapshot()), "jobs")
+}
+
+func TestAggregator_RestoresOnlyUnsentMetrics(t *testing.T) {
+	agg, err := NewAggregator(GaugeMax, nil)
+	require.NoError(t, err)
+
+	agg.Add(counter("jobs", 5), counter("errors", 2), gauge("queue", 9))
+	failed := agg.Snapshot()
+
+	// Сервер принял jobs и queue, отправка оборвалась на errors.
+	failed.Done(&UnsentError{Unsent: []model.Metrics{counter("errors", 2)}, err: errors.New("server is down")})
+
+	byID := reportByID(agg.Snapshot())
+	assert.NotContains(t, byID, "jobs", "delivered increment is not sent twice")
+	assert.EqualValues(t, 2, *byID["errors"].Delta)
+}
+
+func TestAggregator_ForgetsIdleSeries(t *testing.T) {
+	agg, err := NewAggregator(GaugeLast, nil)
+	require.NoError(t, err)
+
+	agg.Add(counter("jobs", 1), gauge("queue", 4))
+	agg.Snapshot()
+
+	for i := 0; i < gaugeIdleReports; i++ {
+		byID := reportByID(agg.Snapshot())
+		assert.NotContains(t, byID, "jobs", "counter without increments is skipped")
+		assert.EqualValues(t, 4, *byID["queue"].Value, "idle gauge keeps its last value for a while")
+	}
+
+	assert.Empty(t, agg.Snapshot().Metrics, "gauge idle for too long is evicted")
+	assert.Empty(t, agg.counters)
+	assert.Empty(t, agg.gauges)
+
+	agg.Add(gauge("queue", 5))
+	assert.EqualValues(t, 5, *reportByID(agg.Snapshot())["queue"].Value, "evicted gauge comes back with new samples")
+}
+
+func TestAggregator_RejectsBadConfig(t *testing.T) {
+	_, err := NewAggregator("median", nil)
+	assert.ErrorContains(t, err, "unknown gauge aggregation")
+
+	_, err = NewAggregator(GaugeLast, map[string]string{"CPU[": GaugeMax})
+	assert.ErrorContains(t, err, "bad gauge aggregation pattern")
+}
@@ -4,18 +4,19 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/agent"
 	"github.com/GoLessons/go-musthave-metrics/internal/agent/reader"
-	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
 	"github.com/GoLessons/go-musthave-metrics/internal/model"
 )
 
 func CollectAllMetrics(
 	ctx context.Context,
-	stg storage.Storage[model.Metrics],
+	sink agent.MetricSink,
 	readers []agent.Reader,
 	simpleReader *reader.SimpleMetricsReader,
+	telemetry *agent.Telemetry,
 ) {
 	var wg sync.WaitGroup
 
@@ -23,14 +24,14 @@ func CollectAllMetrics(
 		wg.Add(1)
 		go func(rd agent.Reader) {
 			defer wg.Done()
-			collectFromReader(stg, rd)
+			collectFromReader(sink, rd, telemetry)
 		}(r)
 	}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		CollectFromSimpleReader(stg, simpleReader)
+		CollectFromSimpleReader(sink, simpleReader, telemetry)
 	}()
 
 	done := make(chan struct{})
@@ -45,54 +46,44 @@ func CollectAllMetrics(
 	}
 }
 
-func collectFromReader(stg storage.Storage[model.Metrics], rd agent.Reader) {
-	if err := rd.Refresh(); err != nil {
-		fmt.Printf("can't refresh reader: %v\n", err)
-		return
-	}
-
-	metrics, err := rd.Fetch()
+func collectFromReader(sink agent.MetricSink, rd agent.Reader, telemetry *agent.Telemetry) {
+	name := readerName(rd)
+	started := time.Now()
+	metrics, err := refreshAndFetch(rd)
+	telemetry.ObserveReader(name, time.Since(started), err)
 	if err != nil {
-		fmt.Printf("can't fetch metrics: %v\n", err)
+		fmt.Printf("can't collect metrics from %s: %v\n", name, err)
 		return
 	}
 
-	for _, metric := range metrics {
-		if err := stg.Set(metric.ID, metric); err != nil {
-			fmt.Printf("can't store metric %s: %v\n", metric.ID, err)
-		}
+	// Приросты забраны в агрегатор, читатель копит следующие с нуля.
+	sink.Add(metrics...)
+	if resetable, ok := rd.(agent.ResetableReader); ok {
+		resetable.Reset()
 	}
 }
 
-func CollectFromSimpleReader(stg storage.Storage[model.Metrics], simpleReader *reader.SimpleMetricsReader) {
-	if err := simpleReader.Refresh(); err != nil {
-		fmt.Printf("can't refresh simple reader: %v\n", err)
-		return
+func CollectFromSimpleReader(sink agent.MetricSink, simpleReader *reader.SimpleMetricsReader, telemetry *agent.Telemetry) {
+	collectFromReader(sink, simpleReader, telemetry)
+}
+
+func refreshAndFetch(rd agent.Reader) ([]model.Metrics, error) {
+	if err := rd.Refresh(); err != nil {
+		return nil, fmt.Errorf("can't refresh reader: %w", err)
 	}
 
-	metrics, err := simpleReader.Fetch()
+	metrics, err := rd.Fetch()
 	if err != nil {
-		fmt.Printf("can't fetch simple metrics: %v\n", err)
-		return
+		return nil, fmt.Errorf("can't fetch metrics: %w", err)
 	}
 
-	for _, metric := range metrics {
-		if err := stg.Set(metric.ID, metric); err != nil {
-			fmt.Printf("can't store simple metric %s: %v\n", metric.ID, err)
-		}
-	}
+	return metrics, nil
 }
 
-func FetchAllMetrics(stg storage.Storage[model.Metrics]) ([]model.Metrics, error) {
-	all, err := stg.GetAll()
-	if err != nil {
-		return nil, fmt.Errorf("can't get all metrics: %w", err)
-	}
-
-	metrics := make([]model.Metrics, 0, len(all))
-	for _, metric := range all {
-		metrics = append(metrics, metric)
+func readerName(rd agent.Reader) string {
+	if named, ok := rd.(interface{ Name() string }); ok {
+		return named.Name()
 	}
 
-	return metrics, nil
+	return fmt.Sprintf("%T", rd)
 }
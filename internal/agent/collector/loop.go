@@ -2,25 +2,39 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/agent"
 	"github.com/GoLessons/go-musthave-metrics/internal/agent/reader"
-	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
-	"github.com/GoLessons/go-musthave-metrics/internal/model"
 )
 
+// ErrDestinationBusy — получатель ещё не разобрал прошлые отчёты; накопленное дождётся следующего.
+var ErrDestinationBusy = errors.New("destination is busy with previous reports")
+
+// Destination связывает агрегатор получателя с очередью его отправителя.
+type Destination struct {
+	Name       string
+	Aggregator *agent.Aggregator
+	Out        chan<- agent.Report
+}
+
 func RunAgentLoop(
 	ctx context.Context,
 	pollTicker, dumpTicker *time.Ticker,
-	stg storage.Storage[model.Metrics],
+	destinations []Destination,
 	readers []agent.Reader,
 	simpleReader *reader.SimpleMetricsReader,
-	out chan<- []model.Metrics,
-	stopSender func(),
+	telemetry *agent.Telemetry,
+	stopSenders func(),
 ) {
-	defer stopSender()
+	defer stopSenders()
+
+	sink := make(agent.FanOut, 0, len(destinations))
+	for _, dst := range destinations {
+		sink = append(sink, dst.Aggregator)
+	}
 
 	for {
 		select {
@@ -28,15 +42,17 @@ func RunAgentLoop(
 			return
 
 		case <-pollTicker.C:
-			handlePollTick(ctx, stg, readers, simpleReader)
+			handlePollTick(ctx, sink, readers, simpleReader, telemetry)
 
 		case <-dumpTicker.C:
-			if err := HandleDumpTick(ctx, stg, simpleReader, out); err != nil {
-				// Если контекст отменён — выходим, иначе логируем и продолжаем
-				if ctx.Err() != nil {
-					return
+			for _, dst := range destinations {
+				if err := HandleDumpTick(ctx, dst.Aggregator, dst.Out); err != nil {
+					// Если контекст отменён — выходим, иначе логируем и продолжаем
+					if ctx.Err() != nil {
+						return
+					}
+					fmt.Printf("can't report metrics to %s: %v\n", dst.Name, err)
 				}
-				fmt.Printf("can't fetch metrics: %v\n", err)
 			}
 		}
 	}
@@ -44,29 +60,32 @@ func RunAgentLoop(
 
 func handlePollTick(
 	ctx context.Context,
-	stg storage.Storage[model.Metrics],
+	sink agent.MetricSink,
 	readers []agent.Reader,
 	simpleReader *reader.SimpleMetricsReader,
+	telemetry *agent.Telemetry,
 ) {
-	CollectAllMetrics(ctx, stg, readers, simpleReader)
+	CollectAllMetrics(ctx, sink, readers, simpleReader, telemetry)
 }
 
+// HandleDumpTick передаёт накопленное на отправку, не дожидаясь занятого получателя;
+// если отчёт не принят или отправка не удастся, агрегатор вернёт значения себе
+// и они уйдут со следующим отчётом.
 func HandleDumpTick(
 	ctx context.Context,
-	stg storage.Storage[model.Metrics],
-	simpleReader *reader.SimpleMetricsReader,
-	out chan<- []model.Metrics,
+	agg *agent.Aggregator,
+	out chan<- agent.Report,
 ) error {
-	metrics, err := FetchAllMetrics(stg)
-	if err != nil {
-		return err
-	}
+	report := agg.Snapshot()
 
 	select {
-	case out <- metrics:
-		simpleReader.Reset()
+	case out <- report:
 		return nil
 	case <-ctx.Done():
+		report.Done(ctx.Err())
 		return ctx.Err()
+	default:
+		report.Done(ErrDestinationBusy)
+		return ErrDestinationBusy
 	}
 }
@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/agent"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAggregator(t *testing.T) *agent.Aggregator {
+	t.Helper()
+	agg, err := agent.NewAggregator(agent.GaugeLast, nil)
+	require.NoError(t, err)
+	return agg
+}
+
+func TestHandleDumpTick_BusyDestinationDoesNotBlockOthers(t *testing.T) {
+	slow, fast := newAggregator(t), newAggregator(t)
+	delta := int64(3)
+	agent.FanOut{slow, fast}.Add(*model.NewCounter("jobs", &delta))
+
+	slowOut := make(chan agent.Report, 1)
+	slowOut <- agent.NewReport(nil) // очередь медленного получателя занята прошлым отчётом
+	fastOut := make(chan agent.Report, 1)
+
+	assert.ErrorIs(t, HandleDumpTick(context.Background(), slow, slowOut), ErrDestinationBusy)
+	require.NoError(t, HandleDumpTick(context.Background(), fast, fastOut))
+
+	report := <-fastOut
+	require.Len(t, report.Metrics, 1)
+	assert.EqualValues(t, 3, *report.Metrics[0].Delta)
+	report.Done(nil)
+
+	<-slowOut
+	require.NoError(t, HandleDumpTick(context.Background(), slow, slowOut))
+	report = <-slowOut
+	require.Len(t, report.Metrics, 1)
+	assert.EqualValues(t, 3, *report.Metrics[0].Delta, "busy destination gets its deltas with the next report")
+}
@@ -5,7 +5,6 @@ import (
 	"sync"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/agent"
-	"github.com/GoLessons/go-musthave-metrics/internal/model"
 )
 
 func StartSenderPipeline(
@@ -13,13 +12,15 @@ func StartSenderPipeline(
 	sender agent.Sender,
 	rateLimit int,
 	batch bool,
+	spool *agent.Spool,
+	stats *agent.SenderStats,
 	buffer int,
-) (chan<- []model.Metrics, func()) {
-	sendChan := make(chan []model.Metrics, buffer)
+) (chan<- agent.Report, func()) {
+	sendChan := make(chan agent.Report, buffer)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
-	go agent.SenderWorker(ctx, sendChan, sender, rateLimit, batch, &wg)
+	go agent.SenderWorker(ctx, sendChan, sender, rateLimit, batch, spool, stats, &wg)
 
 	stop := func() {
 		close(sendChan)
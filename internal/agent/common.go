@@ -2,6 +2,8 @@ package agent
 
 import "github.com/GoLessons/go-musthave-metrics/internal/model"
 
+const APIKeyHeader = "X-API-Key"
+
 type MetricReader[T any] interface {
 	Get(name string) (T, bool)
 }
@@ -24,3 +26,8 @@ type Reader interface {
 type ResetableReader interface {
 	Reset()
 }
+
+// MetricSink принимает метрики очередного опроса.
+type MetricSink interface {
+	Add(metrics ...model.Metrics)
+}
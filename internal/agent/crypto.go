@@ -11,16 +11,33 @@ import (
 	"encoding/pem"
 	"errors"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/GoLessons/go-musthave-metrics/internal/common/keyid"
 	"github.com/goccy/go-json"
 )
 
+// keyCheckInterval ограничивает частоту проверки файла ключа на изменение.
+const keyCheckInterval = time.Second
+
+// Encrypter шифрует тело открытым ключом сервера и перечитывает ключ при изменении файла,
+// чтобы агент переходил на новый ключ без перезапуска.
 type Encrypter struct {
-	pub *rsa.PublicKey
+	path string
+
+	mu        sync.Mutex
+	pub       *rsa.PublicKey
+	kid       string
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+	now       func() time.Time
 }
 
 type encryptedContainer struct {
 	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
 	K   string `json:"k"`
 	N   string `json:"n"`
 	D   string `json:"d"`
@@ -28,18 +45,62 @@ type encryptedContainer struct {
 }
 
 func NewEncrypterFromFile(path string) (*Encrypter, error) {
-	b, err := os.ReadFile(path)
-	if err != nil {
+	e := &Encrypter{path: path, now: time.Now}
+	if err := e.load(); err != nil {
 		return nil, err
 	}
+	return e, nil
+}
+
+func (e *Encrypter) load() error {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(e.path)
+	if err != nil {
+		return err
+	}
 	pub, err := parsePublicKey(b)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	kid, err := keyid.FromPublicKey(pub)
+	if err != nil {
+		return err
 	}
-	return &Encrypter{pub: pub}, nil
+
+	e.pub = pub
+	e.kid = kid
+	e.modTime = info.ModTime()
+	e.size = info.Size()
+	e.lastCheck = e.now()
+	return nil
+}
+
+// current возвращает актуальный ключ; если файл изменился, но не читается, остаётся прежний.
+func (e *Encrypter) current() (*rsa.PublicKey, string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if now := e.now(); now.Sub(e.lastCheck) >= keyCheckInterval {
+		e.lastCheck = now
+		if info, err := os.Stat(e.path); err == nil && (!info.ModTime().Equal(e.modTime) || info.Size() != e.size) {
+			_ = e.load()
+		}
+	}
+
+	return e.pub, e.kid
+}
+
+func (e *Encrypter) KeyID() string {
+	_, kid := e.current()
+	return kid
 }
 
 func (e *Encrypter) Encrypt(data []byte) ([]byte, map[string]string, error) {
+	pub, kid := e.current()
+
 	k := make([]byte, 32)
 	_, err := rand.Read(k)
 	if err != nil {
@@ -61,13 +122,14 @@ func (e *Encrypter) Encrypt(data []byte) ([]byte, map[string]string, error) {
 	}
 	c := g.Seal(nil, n, data, nil)
 
-	ek, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, e.pub, k, nil)
+	ek, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, k, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	cont := encryptedContainer{
 		Alg: "aes256gcm+rsa-oaep",
+		Kid: kid,
 		K:   base64.StdEncoding.EncodeToString(ek),
 		N:   base64.StdEncoding.EncodeToString(n),
 		D:   base64.StdEncoding.EncodeToString(c),
@@ -2,9 +2,15 @@ package agent
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/goccy/go-json"
 )
@@ -86,3 +92,41 @@ func TestEncrypter_Encrypt_ReturnsHeadersAndBody(t *testing.T) {
 		t.Fatalf("ciphertext must differ from plaintext")
 	}
 }
+
+func TestEncrypter_ReloadsKeyOnFileChange(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	writePub := func(path string, key *rsa.PrivateKey, mtime time.Time) {
+		der := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+			t.Fatalf("write key: %v", err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "server.pub")
+	now := time.Now()
+	writePub(path, first, now)
+
+	e, err := NewEncrypterFromFile(path)
+	if err != nil {
+		t.Fatalf("NewEncrypterFromFile error: %v", err)
+	}
+	e.now = func() time.Time { return now }
+	firstKid := e.KeyID()
+
+	writePub(path, second, now.Add(time.Hour))
+	now = now.Add(2 * keyCheckInterval)
+
+	if e.KeyID() == firstKid {
+		t.Fatalf("expected key id to change after key file update")
+	}
+}
@@ -2,15 +2,20 @@ package agent
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
 	"github.com/GoLessons/go-musthave-metrics/internal/model"
 	"github.com/GoLessons/go-musthave-metrics/internal/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 type grpcSender struct {
@@ -18,13 +23,18 @@ type grpcSender struct {
 	client  proto.MetricsClient
 	address string
 	realIP  string
+	apiKey  string
+	token   string
+	connErr error
 	timeout time.Duration
-}
 
-func NewGRPCSender(address string) Sender {
-	conn, _ := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	client := proto.NewMetricsClient(conn)
+	signer    *signature.Signer
+	keySigner *signature.KeySigner
+	encrypter *Encrypter
+	stats     *SenderStats
+}
 
+func NewGRPCSender(address string) *grpcSender {
 	ip := ""
 	if c, err := net.Dial("udp", address); err == nil {
 		if la, ok := c.LocalAddr().(*net.UDPAddr); ok && la.IP != nil {
@@ -36,13 +46,79 @@ func NewGRPCSender(address string) Sender {
 		ip = "127.0.0.1"
 	}
 
-	return &grpcSender{
-		conn:    conn,
-		client:  client,
+	s := &grpcSender{
 		address: address,
 		realIP:  ip,
 		timeout: 5 * time.Second,
 	}
+	conn, _ := s.dial(insecure.NewCredentials())
+	s.conn = conn
+	s.client = proto.NewMetricsClient(conn)
+
+	return s
+}
+
+func (s *grpcSender) dial(creds credentials.TransportCredentials) (*grpc.ClientConn, error) {
+	return grpc.NewClient(
+		s.address,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(s.signRequest, s.encryptRequest),
+	)
+}
+
+// WithAPIKey добавляет ключ арендатора в метаданные всех вызовов.
+func (s *grpcSender) WithAPIKey(apiKey string) *grpcSender {
+	s.apiKey = apiKey
+	return s
+}
+
+// WithTLS переподключает клиента с TLS вместо незащищённого канала.
+func (s *grpcSender) WithTLS(tlsConfig *tls.Config) *grpcSender {
+	if tlsConfig == nil {
+		return s
+	}
+
+	conn, err := s.dial(credentials.NewTLS(tlsConfig))
+	_ = s.conn.Close()
+	if err != nil {
+		// Незащищённое соединение не используем как запасное: отправка будет возвращать ошибку.
+		s.connErr = err
+		return s
+	}
+
+	s.conn = conn
+	s.client = proto.NewMetricsClient(conn)
+	return s
+}
+
+// WithBearerToken добавляет JWT в метаданные authorization всех вызовов.
+func (s *grpcSender) WithBearerToken(token string) *grpcSender {
+	s.token = token
+	return s
+}
+
+// WithSigner подписывает каждый запрос HMAC-SHA256 в метаданных hashsha256.
+func (s *grpcSender) WithSigner(signer *signature.Signer) *grpcSender {
+	s.signer = signer
+	return s
+}
+
+// WithKeySigner подписывает каждый запрос ключом агента.
+func (s *grpcSender) WithKeySigner(keySigner *signature.KeySigner) *grpcSender {
+	s.keySigner = keySigner
+	return s
+}
+
+// WithEncrypter шифрует сериализованный запрос в тот же конверт, что и тело HTTP-запроса.
+func (s *grpcSender) WithEncrypter(encrypter *Encrypter) *grpcSender {
+	s.encrypter = encrypter
+	return s
+}
+
+// WithStats учитывает размер запросов по сериализованному сообщению protobuf.
+func (s *grpcSender) WithStats(stats *SenderStats) *grpcSender {
+	s.stats = stats
+	return s
 }
 
 func (s *grpcSender) Send(metric model.Metrics) error {
@@ -70,14 +146,89 @@ func (s *grpcSender) Close() {
 }
 
 func (s *grpcSender) sendUpdate(list []*proto.Metric) error {
+	if s.connErr != nil {
+		return s.connErr
+	}
 	md := metadata.Pairs("x-real-ip", s.realIP)
+	if s.apiKey != "" {
+		md.Set(strings.ToLower(APIKeyHeader), s.apiKey)
+	}
+	if s.token != "" {
+		md.Set("authorization", "Bearer "+s.token)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 	defer cancel()
 	ctx = metadata.NewOutgoingContext(ctx, md)
-	_, err := s.client.UpdateMetrics(ctx, &proto.UpdateMetricsRequest{Metrics: list})
+	request := &proto.UpdateMetricsRequest{Metrics: list}
+	size := protobuf.Size(request)
+	s.stats.Bytes(size, size)
+	_, err := s.client.UpdateMetrics(ctx, request)
 	return err
 }
 
+// signRequest подписывает детерминированную сериализацию запроса до шифрования,
+// поэтому сервер проверяет подпись по уже расшифрованному сообщению.
+func (s *grpcSender) signRequest(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	message, ok := req.(protobuf.Message)
+	if (s.signer == nil && s.keySigner == nil) || !ok {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	payload, err := proto.SigningBytes(message)
+	if err != nil {
+		return fmt.Errorf("failed to serialize request: %w", err)
+	}
+	signHeaders, err := signatureHeaders(s.signer, s.keySigner, payload)
+	if err != nil {
+		return err
+	}
+	pairs := make([]string, 0, 2*len(signHeaders))
+	for k, v := range signHeaders {
+		pairs = append(pairs, strings.ToLower(k), v)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (s *grpcSender) encryptRequest(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if s.encrypter == nil {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	return invoker(ctx, method, req, reply, cc, append(opts, grpc.ForceCodec(&envelopeCodec{encrypter: s.encrypter}))...)
+}
+
+// envelopeCodec шифрует исходящие сообщения; ответы сервера приходят обычным protobuf.
+type envelopeCodec struct {
+	encrypter *Encrypter
+}
+
+func (c *envelopeCodec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(protobuf.Message)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal, message is %T, want proto.Message", v)
+	}
+	data, err := protobuf.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := c.encrypter.Encrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return body, nil
+}
+
+func (c *envelopeCodec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(protobuf.Message)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal, message is %T, want proto.Message", v)
+	}
+	return protobuf.Unmarshal(data, message)
+}
+
+func (c *envelopeCodec) Name() string {
+	return "proto"
+}
+
 func (s *grpcSender) modelToProto(m model.Metrics) (*proto.Metric, error) {
 	pm := &proto.Metric{Id: m.ID}
 	switch m.MType {
@@ -3,9 +3,11 @@ package agent
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
 	"github.com/GoLessons/go-musthave-metrics/internal/model"
@@ -17,7 +19,9 @@ type jsonSender struct {
 	client     *resty.Client
 	enableGzip bool
 	signer     *signature.Signer
+	keySigner  *signature.KeySigner
 	encrypter  *Encrypter
+	stats      *SenderStats
 }
 
 func NewJSONSender(address string, enableGzip bool, signer *signature.Signer, encrypter *Encrypter) *jsonSender {
@@ -48,6 +52,46 @@ func NewJSONSender(address string, enableGzip bool, signer *signature.Signer, en
 	}
 }
 
+// WithKeySigner подписывает запросы ключом агента для проверки по его открытому ключу на сервере.
+func (sender *jsonSender) WithKeySigner(keySigner *signature.KeySigner) *jsonSender {
+	sender.keySigner = keySigner
+	return sender
+}
+
+// WithAPIKey добавляет ключ арендатора ко всем запросам.
+func (sender *jsonSender) WithAPIKey(apiKey string) *jsonSender {
+	if apiKey != "" {
+		sender.client.SetHeader(APIKeyHeader, apiKey)
+	}
+
+	return sender
+}
+
+// WithTLS переключает отправку на HTTPS с заданной TLS-конфигурацией.
+func (sender *jsonSender) WithTLS(tlsConfig *tls.Config) *jsonSender {
+	if tlsConfig != nil {
+		sender.client.SetTLSClientConfig(tlsConfig).
+			SetBaseURL("https://" + strings.TrimPrefix(sender.client.BaseURL(), "http://"))
+	}
+
+	return sender
+}
+
+// WithBearerToken добавляет JWT в заголовок Authorization всех запросов.
+func (sender *jsonSender) WithBearerToken(token string) *jsonSender {
+	if token != "" {
+		sender.client.SetAuthToken(token)
+	}
+
+	return sender
+}
+
+// WithStats учитывает размер запросов до сжатия и шифрования и после них.
+func (sender *jsonSender) WithStats(stats *SenderStats) *jsonSender {
+	sender.stats = stats
+	return sender
+}
+
 func (sender *jsonSender) Send(metric model.Metrics) error {
 	switch metric.MType {
 	case model.Counter:
@@ -86,6 +130,7 @@ func (sender *jsonSender) send(endpoint string, data interface{}) error {
 	if err != nil {
 		return err
 	}
+	rawSize := len(body)
 
 	headers := map[string]string{}
 
@@ -122,14 +167,15 @@ func (sender *jsonSender) send(endpoint string, data interface{}) error {
 		request.SetHeader(k, v)
 	}
 
-	if sender.signer != nil {
-		hash, err := sender.signer.Hash(body)
-		if err != nil {
-			return fmt.Errorf("failed to calculate hash: %w", err)
-		}
-		request.SetHeader("HashSHA256", hash)
+	signHeaders, err := signatureHeaders(sender.signer, sender.keySigner, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range signHeaders {
+		request.SetHeader(k, v)
 	}
 
+	sender.stats.Bytes(rawSize, len(body))
 	resp, err := request.Post(endpoint)
 	if err != nil {
 		return WrapSendError(0, fmt.Sprintf("can't send data to %s", endpoint), err)
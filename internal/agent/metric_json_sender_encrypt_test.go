@@ -58,7 +58,12 @@ func TestJSONSender_EncryptsAndSigns_WithGzip(t *testing.T) {
 	if sig == "" {
 		t.Fatalf("missing HashSHA256 header")
 	}
-	expected, err := signer.Hash(capturedBody)
+	timestamp := capturedHeaders.Get(signature.TimestampHeader)
+	nonce := capturedHeaders.Get(signature.NonceHeader)
+	if timestamp == "" || nonce == "" {
+		t.Fatalf("missing signature timestamp or nonce")
+	}
+	expected, err := signer.Hash(signature.SignedPayload(timestamp, nonce, capturedBody))
 	if err != nil {
 		t.Fatalf("hash error: %v", err)
 	}
@@ -125,7 +130,12 @@ func TestJSONSender_EncryptsAndSigns_NoGzip(t *testing.T) {
 	if sig == "" {
 		t.Fatalf("missing HashSHA256 header")
 	}
-	expected, err := signer.Hash(capturedBody)
+	timestamp := capturedHeaders.Get(signature.TimestampHeader)
+	nonce := capturedHeaders.Get(signature.NonceHeader)
+	if timestamp == "" || nonce == "" {
+		t.Fatalf("missing signature timestamp or nonce")
+	}
+	expected, err := signer.Hash(signature.SignedPayload(timestamp, nonce, capturedBody))
 	if err != nil {
 		t.Fatalf("hash error: %v", err)
 	}
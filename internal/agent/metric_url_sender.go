@@ -1,15 +1,20 @@
 package agent
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
+	"strings"
 
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
 	"github.com/GoLessons/go-musthave-metrics/internal/model"
 	"resty.dev/v3"
 )
 
 type urPathSender struct {
-	client *resty.Client
+	client    *resty.Client
+	keySigner *signature.KeySigner
+	stats     *SenderStats
 }
 
 func NewMetricURLSender(address string) *urPathSender {
@@ -35,12 +40,52 @@ func NewMetricURLSender(address string) *urPathSender {
 	}
 }
 
+// WithKeySigner подписывает запросы ключом агента; у запроса нет тела, поэтому подписывается путь.
+func (sender *urPathSender) WithKeySigner(keySigner *signature.KeySigner) *urPathSender {
+	sender.keySigner = keySigner
+	return sender
+}
+
+// WithAPIKey добавляет ключ арендатора ко всем запросам.
+func (sender *urPathSender) WithAPIKey(apiKey string) *urPathSender {
+	if apiKey != "" {
+		sender.client.SetHeader(APIKeyHeader, apiKey)
+	}
+
+	return sender
+}
+
+// WithTLS переключает отправку на HTTPS с заданной TLS-конфигурацией.
+func (sender *urPathSender) WithTLS(tlsConfig *tls.Config) *urPathSender {
+	if tlsConfig != nil {
+		sender.client.SetTLSClientConfig(tlsConfig).
+			SetBaseURL("https://" + strings.TrimPrefix(sender.client.BaseURL(), "http://"))
+	}
+
+	return sender
+}
+
+// WithBearerToken добавляет JWT в заголовок Authorization всех запросов.
+func (sender *urPathSender) WithBearerToken(token string) *urPathSender {
+	if token != "" {
+		sender.client.SetAuthToken(token)
+	}
+
+	return sender
+}
+
 type metricData struct {
 	name       string
 	metricType string
 	value      string
 }
 
+// WithStats учитывает размер запросов; тела нет, поэтому считается длина пути.
+func (sender *urPathSender) WithStats(stats *SenderStats) *urPathSender {
+	sender.stats = stats
+	return sender
+}
+
 func (sender *urPathSender) Close() {
 	defer sender.client.Close()
 }
@@ -53,7 +98,15 @@ func (sender *urPathSender) Send(metric model.Metrics) (err error) {
 		return err
 	}
 
+	path := "/update/" + metricData.metricType + "/" + metricData.name + "/" + metricData.value
+	signHeaders, err := signatureHeaders(nil, sender.keySigner, []byte(path))
+	if err != nil {
+		return err
+	}
+
+	sender.stats.Bytes(len(path), len(path))
 	resp, err := client.R().
+		SetHeaders(signHeaders).
 		SetPathParam("metricName", metricData.name).
 		SetPathParam("metricType", metricData.metricType).
 		SetPathParam("metricVal", metricData.value).
@@ -0,0 +1,148 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/agent/reader"
+	"github.com/GoLessons/go-musthave-metrics/internal/common"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/goccy/go-json"
+)
+
+const (
+	// maxBodyBytes ограничивает размер одного запроса приложения.
+	maxBodyBytes    = 1 << 20
+	shutdownTimeout = 5 * time.Second
+)
+
+// NewHandler принимает /update (одна метрика) и /updates (массив) в том же JSON, что и сервер,
+// и складывает метрики в читатель, откуда они уходят на сервер вместе с остальными.
+func NewHandler(target *reader.PushMetricsReader) http.Handler {
+	mux := http.NewServeMux()
+
+	single := func(w http.ResponseWriter, r *http.Request) {
+		var metric model.Metrics
+		if !decodeBody(w, r, &metric) {
+			return
+		}
+		if err := target.Push([]model.Metrics{metric}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, metric)
+	}
+	batch := func(w http.ResponseWriter, r *http.Request) {
+		var metrics []model.Metrics
+		if !decodeBody(w, r, &metrics) {
+			return
+		}
+		if err := target.Push(metrics); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, metrics)
+	}
+
+	mux.HandleFunc("POST /update", single)
+	mux.HandleFunc("POST /update/", single)
+	mux.HandleFunc("POST /updates", batch)
+	mux.HandleFunc("POST /updates/", batch)
+
+	return mux
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
+		cr, err := common.NewCompressReader(io.NopCloser(body))
+		if err != nil {
+			http.Error(w, "failed to read gzip body", http.StatusBadRequest)
+			return false
+		}
+		defer cr.Close()
+		body = io.LimitReader(cr, maxBodyBytes)
+	}
+
+	if err := json.NewDecoder(body).Decode(dst); err != nil {
+		http.Error(w, fmt.Sprintf("bad json: %v", err), http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Listen открывает TCP-адрес и/или Unix-сокет; пустое значение — не слушать.
+// Файл сокета, оставшийся от прошлого запуска, удаляется.
+func Listen(address string, socket string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	if address != "" {
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("push listener: %w", err)
+		}
+		listeners = append(listeners, l)
+	}
+	if socket != "" {
+		if err := os.Remove(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			closeListeners(listeners)
+			return nil, fmt.Errorf("push socket: %w", err)
+		}
+		l, err := net.Listen("unix", socket)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, fmt.Errorf("push socket: %w", err)
+		}
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		_ = l.Close()
+	}
+}
+
+// Serve обслуживает запросы на всех слушателях до отмены ctx и корректно их закрывает.
+func Serve(ctx context.Context, handler http.Handler, listeners []net.Listener) error {
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	errCh := make(chan error, len(listeners))
+	var wg sync.WaitGroup
+	for _, l := range listeners {
+		wg.Add(1)
+		go func(l net.Listener) {
+			defer wg.Done()
+			if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}(l)
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-errCh:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	wg.Wait()
+
+	return errors.Join(serveErr, err)
+}
@@ -0,0 +1,118 @@
+package push
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/agent/reader"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fetchByID(t *testing.T, r *reader.PushMetricsReader) map[string]model.Metrics {
+	t.Helper()
+	metrics, err := r.Fetch()
+	require.NoError(t, err)
+	byID := make(map[string]model.Metrics, len(metrics))
+	for _, metric := range metrics {
+		byID[metric.ID] = metric
+	}
+	return byID
+}
+
+func post(t *testing.T, handler http.Handler, path string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestHandler_AcceptsUpdateAndUpdates(t *testing.T) {
+	pushReader := reader.NewPushMetricsReader()
+	handler := NewHandler(pushReader)
+
+	require.Equal(t, http.StatusOK, post(t, handler, "/update", `{"id":"jobs","type":"counter","delta":2}`).Code)
+	require.Equal(t, http.StatusOK, post(t, handler, "/updates/", `[{"id":"jobs","type":"counter","delta":3},{"id":"queue","type":"gauge","value":1.5}]`).Code)
+
+	byID := fetchByID(t, pushReader)
+	assert.Equal(t, int64(5), *byID["jobs"].Delta)
+	assert.Equal(t, 1.5, *byID["queue"].Value)
+}
+
+func TestHandler_RejectsBadMetrics(t *testing.T) {
+	pushReader := reader.NewPushMetricsReader()
+	handler := NewHandler(pushReader)
+
+	assert.Equal(t, http.StatusBadRequest, post(t, handler, "/update", `{"id":"x","type":"gauge"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(t, handler, "/updates", `[{"id":"a","type":"gauge","value":1},{"id":"b","type":"histogram"}]`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(t, handler, "/updates", `not json`).Code)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/update", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	assert.Empty(t, fetchByID(t, pushReader), "rejected batch must not be applied partially")
+}
+
+func TestHandler_GzipBody(t *testing.T) {
+	pushReader := reader.NewPushMetricsReader()
+	handler := NewHandler(pushReader)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(`[{"id":"queue","type":"gauge","value":7}]`))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/updates", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 7.0, *fetchByID(t, pushReader)["queue"].Value)
+}
+
+func TestPushReader_KeepsDeltasArrivedAfterFetch(t *testing.T) {
+	pushReader := reader.NewPushMetricsReader()
+	one := int64(1)
+	require.NoError(t, pushReader.Push([]model.Metrics{*model.NewCounter("jobs", &one)}))
+	assert.Equal(t, int64(1), *fetchByID(t, pushReader)["jobs"].Delta)
+
+	// Прирост пришёл между Fetch и Reset — после сброса он должен остаться.
+	require.NoError(t, pushReader.Push([]model.Metrics{*model.NewCounter("jobs", &one)}))
+	pushReader.Reset()
+	assert.Equal(t, int64(1), *fetchByID(t, pushReader)["jobs"].Delta)
+}
+
+func TestServe_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "push.sock")
+	listeners, err := Listen("", socket)
+	require.NoError(t, err)
+
+	pushReader := reader.NewPushMetricsReader()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, NewHandler(pushReader), listeners) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Post("http://agent/update", "application/json", strings.NewReader(`{"id":"queue","type":"gauge","value":2}`))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2.0, *fetchByID(t, pushReader)["queue"].Value)
+
+	cancel()
+	require.NoError(t, <-done)
+}
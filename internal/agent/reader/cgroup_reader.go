@@ -0,0 +1,264 @@
+package reader
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+)
+
+// cgroupUnlimited — значения от этой границы cgroup v1 использует как «без лимита».
+const cgroupUnlimited = uint64(1) << 62
+
+type CgroupOptions struct {
+	// Root — точка монтирования cgroupfs, по умолчанию /sys/fs/cgroup.
+	Root string `json:"root"`
+	// SelfCgroup — файл с cgroup агента, по умолчанию /proc/self/cgroup.
+	SelfCgroup string `json:"self_cgroup"`
+}
+
+// CgroupMetricsReader сообщает лимиты и потребление cgroup, в которой работает агент (v1 и v2):
+// память и её лимит, лимит CPU в ядрах (gauge), процессорное время, троттлинг и OOM (counter).
+type CgroupMetricsReader struct {
+	root       string
+	selfCgroup string
+
+	mu       sync.RWMutex
+	gauges   []model.Metrics
+	counters *counterSet
+}
+
+func NewCgroupMetricsReader(options CgroupOptions) *CgroupMetricsReader {
+	if options.Root == "" {
+		options.Root = "/sys/fs/cgroup"
+	}
+	if options.SelfCgroup == "" {
+		options.SelfCgroup = "/proc/self/cgroup"
+	}
+
+	return &CgroupMetricsReader{root: options.Root, selfCgroup: options.SelfCgroup, counters: newCounterSet()}
+}
+
+func newCgroupReaderFromConfig(cfg Config) (*CgroupMetricsReader, error) {
+	var options CgroupOptions
+	if err := decodeOptions(cfg, &options); err != nil {
+		return nil, err
+	}
+
+	return NewCgroupMetricsReader(options), nil
+}
+
+func (r *CgroupMetricsReader) Refresh() error {
+	v1, v2Path, err := parseSelfCgroup(r.selfCgroup)
+	if err != nil {
+		return fmt.Errorf("failed to read cgroup of agent: %w", err)
+	}
+
+	var gauges []model.Metrics
+	counters := map[string]uint64{}
+	gauge := func(name string, value float64) {
+		gauges = append(gauges, *model.NewGauge(name, &value))
+	}
+
+	_, hasMemory := v1["memory"]
+	_, hasCPU := v1["cpu"]
+	if hasMemory || hasCPU {
+		r.readV1(v1, gauge, counters)
+	} else if v2Path != "" {
+		r.readV2(v2Path, gauge, counters)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges = gauges
+	r.counters.observe(counters)
+
+	return nil
+}
+
+func (r *CgroupMetricsReader) Fetch() ([]model.Metrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append(append([]model.Metrics{}, r.gauges...), r.counters.metrics()...), nil
+}
+
+func (r *CgroupMetricsReader) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters.reset()
+}
+
+func (r *CgroupMetricsReader) readV2(cgroupPath string, gauge func(string, float64), counters map[string]uint64) {
+	dir := r.resolve("", cgroupPath, "cgroup.controllers")
+
+	if usage, ok := readCgroupUint(filepath.Join(dir, "memory.current")); ok {
+		gauge("CgroupMemoryUsage", float64(usage))
+	}
+	if limit, ok := readCgroupUint(filepath.Join(dir, "memory.max")); ok {
+		gauge("CgroupMemoryLimit", float64(limit))
+	}
+	if events := readCgroupStat(filepath.Join(dir, "memory.events")); events != nil {
+		if kills, ok := events["oom_kill"]; ok {
+			counters["CgroupOOMKills"] = kills
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, errQuota := strconv.ParseFloat(fields[0], 64)
+			period, errPeriod := strconv.ParseFloat(fields[1], 64)
+			if errQuota == nil && errPeriod == nil && period > 0 {
+				gauge("CgroupCPULimit", quota/period)
+			}
+		}
+	}
+	if stat := readCgroupStat(filepath.Join(dir, "cpu.stat")); stat != nil {
+		setCounter(counters, "CgroupCPUUsageMs", stat, "usage_usec", 1000)
+		setCounter(counters, "CgroupCPUPeriods", stat, "nr_periods", 1)
+		setCounter(counters, "CgroupCPUThrottledPeriods", stat, "nr_throttled", 1)
+		setCounter(counters, "CgroupCPUThrottledMs", stat, "throttled_usec", 1000)
+	}
+}
+
+func (r *CgroupMetricsReader) readV1(paths map[string]cgroupV1Entry, gauge func(string, float64), counters map[string]uint64) {
+	if entry, ok := paths["memory"]; ok {
+		dir := r.resolve(entry.hierarchy, entry.path, "memory.usage_in_bytes")
+		if usage, ok := readCgroupUint(filepath.Join(dir, "memory.usage_in_bytes")); ok {
+			gauge("CgroupMemoryUsage", float64(usage))
+		}
+		if limit, ok := readCgroupUint(filepath.Join(dir, "memory.limit_in_bytes")); ok && limit < cgroupUnlimited {
+			gauge("CgroupMemoryLimit", float64(limit))
+		}
+		if control := readCgroupStat(filepath.Join(dir, "memory.oom_control")); control != nil {
+			if kills, ok := control["oom_kill"]; ok {
+				counters["CgroupOOMKills"] = kills
+			}
+		}
+	}
+
+	if entry, ok := paths["cpu"]; ok {
+		dir := r.resolve(entry.hierarchy, entry.path, "cpu.cfs_period_us")
+		quota, errQuota := readCgroupInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+		period, errPeriod := readCgroupInt(filepath.Join(dir, "cpu.cfs_period_us"))
+		if errQuota == nil && errPeriod == nil && quota > 0 && period > 0 {
+			gauge("CgroupCPULimit", float64(quota)/float64(period))
+		}
+		if stat := readCgroupStat(filepath.Join(dir, "cpu.stat")); stat != nil {
+			setCounter(counters, "CgroupCPUPeriods", stat, "nr_periods", 1)
+			setCounter(counters, "CgroupCPUThrottledPeriods", stat, "nr_throttled", 1)
+			setCounter(counters, "CgroupCPUThrottledMs", stat, "throttled_time", 1000000)
+		}
+	}
+
+	if entry, ok := paths["cpuacct"]; ok {
+		dir := r.resolve(entry.hierarchy, entry.path, "cpuacct.usage")
+		if usage, ok := readCgroupUint(filepath.Join(dir, "cpuacct.usage")); ok {
+			counters["CgroupCPUUsageMs"] = usage / 1000000
+		}
+	}
+}
+
+// resolve ищет каталог cgroup: на хосте это root/<иерархия>/<путь>, а внутри контейнера
+// со своим cgroup namespace путь уже относителен и каталогом служит сама точка монтирования.
+func (r *CgroupMetricsReader) resolve(hierarchy string, cgroupPath string, probe string) string {
+	base := filepath.Join(r.root, hierarchy)
+	full := filepath.Join(base, cgroupPath)
+	if _, err := os.Stat(filepath.Join(full, probe)); err == nil {
+		return full
+	}
+
+	return base
+}
+
+type cgroupV1Entry struct {
+	hierarchy string
+	path      string
+}
+
+// parseSelfCgroup разбирает строки вида "4:memory:/docker/abc" (v1) и "0::/system.slice/agent" (v2).
+func parseSelfCgroup(path string) (map[string]cgroupV1Entry, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	v1 := map[string]cgroupV1Entry{}
+	v2 := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "" {
+			if parts[0] == "0" {
+				v2 = parts[2]
+			}
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			v1[controller] = cgroupV1Entry{hierarchy: parts[1], path: parts[2]}
+		}
+	}
+
+	return v1, v2, scanner.Err()
+}
+
+func readCgroupUint(path string) (uint64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		// Например, "max" — лимит не задан.
+		return 0, false
+	}
+
+	return value, true
+}
+
+func readCgroupInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readCgroupStat разбирает файлы вида "ключ значение" (cpu.stat, memory.events).
+func readCgroupStat(path string) map[string]uint64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	stat := map[string]uint64{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			stat[fields[0]] = value
+		}
+	}
+
+	return stat
+}
+
+func setCounter(counters map[string]uint64, name string, stat map[string]uint64, key string, divisor uint64) {
+	if value, ok := stat[key]; ok {
+		counters[name] = value / divisor
+	}
+}
@@ -0,0 +1,90 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCgroupFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+}
+
+func metricsByID(metrics []model.Metrics) map[string]model.Metrics {
+	result := make(map[string]model.Metrics, len(metrics))
+	for _, metric := range metrics {
+		result[metric.ID] = metric
+	}
+	return result
+}
+
+func TestCgroupReader_V2(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"self": "0::/system.slice/agent.service\n",
+		"system.slice/agent.service/cgroup.controllers": "cpu memory\n",
+		"system.slice/agent.service/memory.current":     "104857600\n",
+		"system.slice/agent.service/memory.max":         "268435456\n",
+		"system.slice/agent.service/memory.events":      "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n",
+		"system.slice/agent.service/cpu.max":            "150000 100000\n",
+		"system.slice/agent.service/cpu.stat":           "usage_usec 5000000\nnr_periods 40\nnr_throttled 4\nthrottled_usec 200000\n",
+	})
+
+	reader := NewCgroupMetricsReader(CgroupOptions{Root: root, SelfCgroup: filepath.Join(root, "self")})
+	require.NoError(t, reader.Refresh())
+
+	writeCgroupFiles(t, root, map[string]string{
+		"system.slice/agent.service/cpu.stat":      "usage_usec 7500000\nnr_periods 50\nnr_throttled 9\nthrottled_usec 700000\n",
+		"system.slice/agent.service/memory.events": "oom_kill 2\n",
+	})
+	require.NoError(t, reader.Refresh())
+
+	metrics, err := reader.Fetch()
+	require.NoError(t, err)
+	byID := metricsByID(metrics)
+
+	assert.EqualValues(t, 104857600, *byID["CgroupMemoryUsage"].Value)
+	assert.EqualValues(t, 268435456, *byID["CgroupMemoryLimit"].Value)
+	assert.InDelta(t, 1.5, *byID["CgroupCPULimit"].Value, 1e-9)
+	assert.EqualValues(t, 2500, *byID["CgroupCPUUsageMs"].Delta)
+	assert.EqualValues(t, 10, *byID["CgroupCPUPeriods"].Delta)
+	assert.EqualValues(t, 5, *byID["CgroupCPUThrottledPeriods"].Delta)
+	assert.EqualValues(t, 500, *byID["CgroupCPUThrottledMs"].Delta)
+	assert.EqualValues(t, 1, *byID["CgroupOOMKills"].Delta)
+}
+
+func TestCgroupReader_V1InsideContainer(t *testing.T) {
+	root := t.TempDir()
+	// В контейнере с cgroup namespace путь из /proc/self/cgroup не существует под точкой монтирования.
+	writeCgroupFiles(t, root, map[string]string{
+		"self":                          "5:memory:/docker/abc\n3:cpu,cpuacct:/docker/abc\n0::/\n",
+		"memory/memory.usage_in_bytes":  "1048576\n",
+		"memory/memory.limit_in_bytes":  "9223372036854771712\n",
+		"cpu,cpuacct/cpu.cfs_quota_us":  "50000\n",
+		"cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+		"cpu,cpuacct/cpu.stat":          "nr_periods 10\nnr_throttled 2\nthrottled_time 3000000\n",
+		"cpu,cpuacct/cpuacct.usage":     "2000000000\n",
+	})
+
+	reader := NewCgroupMetricsReader(CgroupOptions{Root: root, SelfCgroup: filepath.Join(root, "self")})
+	require.NoError(t, reader.Refresh())
+
+	metrics, err := reader.Fetch()
+	require.NoError(t, err)
+	byID := metricsByID(metrics)
+
+	assert.EqualValues(t, 1048576, *byID["CgroupMemoryUsage"].Value)
+	assert.NotContains(t, byID, "CgroupMemoryLimit", "unlimited memory is not reported")
+	assert.InDelta(t, 0.5, *byID["CgroupCPULimit"].Value, 1e-9)
+	assert.Contains(t, byID, "CgroupCPUUsageMs")
+	assert.Contains(t, byID, "CgroupCPUThrottledMs")
+}
@@ -0,0 +1,81 @@
+package reader
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+)
+
+// counterSet превращает накопительные счётчики ОС в дельты с момента последнего сброса:
+// сервер суммирует дельты counter, поэтому отправлять абсолютные значения нельзя.
+// Первое наблюдение счётчика служит точкой отсчёта и даёт нулевую дельту.
+type counterSet struct {
+	current  map[string]uint64
+	baseline map[string]uint64
+}
+
+func newCounterSet() *counterSet {
+	return &counterSet{current: map[string]uint64{}, baseline: map[string]uint64{}}
+}
+
+// observe запоминает свежие значения; пропавшие счётчики (например, отключённый интерфейс) забываются.
+func (c *counterSet) observe(values map[string]uint64) {
+	for name, value := range values {
+		if _, known := c.baseline[name]; !known {
+			c.baseline[name] = value
+		}
+	}
+	for name := range c.baseline {
+		if _, ok := values[name]; !ok {
+			delete(c.baseline, name)
+		}
+	}
+	c.current = values
+}
+
+func (c *counterSet) metrics() []model.Metrics {
+	names := make([]string, 0, len(c.current))
+	for name := range c.current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]model.Metrics, 0, len(names))
+	for _, name := range names {
+		value, base := c.current[name], c.baseline[name]
+		// Значение меньше точки отсчёта означает сброс счётчика (перезагрузка устройства).
+		delta := int64(value)
+		if value >= base {
+			delta = int64(value - base)
+		}
+		metrics = append(metrics, *model.NewCounter(name, &delta))
+	}
+
+	return metrics
+}
+
+func (c *counterSet) reset() {
+	for name, value := range c.current {
+		c.baseline[name] = value
+	}
+}
+
+// labelSuffix делает из имени устройства или точки монтирования допустимую часть имени метрики.
+func labelSuffix(label string) string {
+	var b strings.Builder
+	for _, r := range label {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+
+	suffix := strings.Trim(b.String(), "_")
+	if suffix == "" {
+		return "root"
+	}
+
+	return suffix
+}
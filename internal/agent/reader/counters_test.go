@@ -0,0 +1,89 @@
+package reader
+
+import (
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deltas(metrics []model.Metrics) map[string]int64 {
+	result := make(map[string]int64, len(metrics))
+	for _, metric := range metrics {
+		result[metric.ID] = *metric.Delta
+	}
+	return result
+}
+
+func TestCounterSet_DeltasSinceReset(t *testing.T) {
+	counters := newCounterSet()
+
+	counters.observe(map[string]uint64{"NetBytesRecv_eth0": 1000})
+	assert.Equal(t, map[string]int64{"NetBytesRecv_eth0": 0}, deltas(counters.metrics()), "first observation is a baseline")
+
+	counters.observe(map[string]uint64{"NetBytesRecv_eth0": 1500})
+	counters.observe(map[string]uint64{"NetBytesRecv_eth0": 1700})
+	assert.Equal(t, map[string]int64{"NetBytesRecv_eth0": 700}, deltas(counters.metrics()), "polls between reports accumulate")
+
+	counters.reset()
+	assert.Equal(t, map[string]int64{"NetBytesRecv_eth0": 0}, deltas(counters.metrics()))
+
+	counters.observe(map[string]uint64{"NetBytesRecv_eth0": 200})
+	assert.Equal(t, map[string]int64{"NetBytesRecv_eth0": 200}, deltas(counters.metrics()), "counter reset on device restart")
+}
+
+func TestCounterSet_ForgetsVanishedCounters(t *testing.T) {
+	counters := newCounterSet()
+	counters.observe(map[string]uint64{"NetBytesRecv_eth0": 10, "NetBytesRecv_wlan0": 20})
+	counters.observe(map[string]uint64{"NetBytesRecv_eth0": 15})
+
+	assert.Equal(t, map[string]int64{"NetBytesRecv_eth0": 5}, deltas(counters.metrics()))
+
+	counters.observe(map[string]uint64{"NetBytesRecv_eth0": 15, "NetBytesRecv_wlan0": 50})
+	assert.Equal(t, int64(0), deltas(counters.metrics())["NetBytesRecv_wlan0"], "returning counter starts from a new baseline")
+}
+
+func TestLabelSuffix(t *testing.T) {
+	assert.Equal(t, "root", labelSuffix("/"))
+	assert.Equal(t, "var_lib_docker", labelSuffix("/var/lib/docker"))
+	assert.Equal(t, "C", labelSuffix("C:\\"))
+	assert.Equal(t, "eth0", labelSuffix("eth0"))
+}
+
+func TestOperatingSystemReaders(t *testing.T) {
+	fdReader, err := NewFileDescriptorMetricsReader()
+	require.NoError(t, err)
+
+	readers := map[string]interface {
+		Refresh() error
+		Fetch() ([]model.Metrics, error)
+	}{
+		"disk":    NewDiskMetricsReader(),
+		"network": NewNetworkMetricsReader(),
+		"load":    NewLoadMetricsReader(),
+		"swap":    NewSwapMetricsReader(),
+		"fd":      fdReader,
+	}
+
+	for name, rd := range readers {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, rd.Refresh())
+			metrics, err := rd.Fetch()
+			require.NoError(t, err)
+
+			for _, metric := range metrics {
+				assert.NotEmpty(t, metric.ID)
+				switch metric.MType {
+				case model.Gauge:
+					assert.NotNil(t, metric.Value)
+				case model.Counter:
+					assert.NotNil(t, metric.Delta)
+					assert.GreaterOrEqual(t, *metric.Delta, int64(0))
+				default:
+					t.Fatalf("unexpected metric type %q", metric.MType)
+				}
+			}
+		})
+	}
+}
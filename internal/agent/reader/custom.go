@@ -0,0 +1,186 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/goccy/go-json"
+)
+
+// Форматы вывода пользовательских читателей.
+const (
+	FormatAuto  = ""
+	FormatLines = "lines"
+	FormatJSON  = "json"
+)
+
+// parseCustomMetrics разбирает строки "имя тип значение" или JSON-массив model.Metrics.
+// В строчном формате пустые строки и строки с # пропускаются. Значение counter — прирост.
+func parseCustomMetrics(data []byte, format string) ([]model.Metrics, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	if format == FormatJSON || (format == FormatAuto && trimmed[0] == '[') {
+		var metrics []model.Metrics
+		if err := json.Unmarshal(trimmed, &metrics); err != nil {
+			return nil, fmt.Errorf("bad json metrics: %w", err)
+		}
+		for _, metric := range metrics {
+			if err := validateCustomMetric(metric); err != nil {
+				return nil, err
+			}
+		}
+		return metrics, nil
+	}
+
+	var metrics []model.Metrics
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	for line := 1; scanner.Scan(); line++ {
+		metric, ok, err := parseCustomLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if ok {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	return metrics, scanner.Err()
+}
+
+func parseCustomLine(line string) (model.Metrics, bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return model.Metrics{}, false, nil
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return model.Metrics{}, false, fmt.Errorf("expected \"name type value\", got %q", line)
+	}
+
+	switch fields[1] {
+	case model.Gauge:
+		value, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return model.Metrics{}, false, fmt.Errorf("bad gauge value %q", fields[2])
+		}
+		return *model.NewGauge(fields[0], &value), true, nil
+	case model.Counter:
+		delta, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return model.Metrics{}, false, fmt.Errorf("bad counter value %q", fields[2])
+		}
+		return *model.NewCounter(fields[0], &delta), true, nil
+	default:
+		return model.Metrics{}, false, fmt.Errorf("unknown metric type %q", fields[1])
+	}
+}
+
+func validateCustomMetric(metric model.Metrics) error {
+	switch {
+	case metric.ID == "":
+		return fmt.Errorf("metric without id")
+	case metric.MType == model.Gauge && metric.Value == nil:
+		return fmt.Errorf("gauge %s without value", metric.ID)
+	case metric.MType == model.Counter && metric.Delta == nil:
+		return fmt.Errorf("counter %s without delta", metric.ID)
+	case metric.MType != model.Gauge && metric.MType != model.Counter:
+		return fmt.Errorf("metric %s has unknown type %q", metric.ID, metric.MType)
+	}
+
+	return nil
+}
+
+// customMetrics копит результаты пользовательского читателя между сбросами:
+// gauge хранят последнее значение, приросты counter суммируются до Reset.
+// Reset вычитает только то, что было выдано последним fetch, поэтому приросты,
+// пришедшие между Fetch и Reset, не теряются.
+type customMetrics struct {
+	mu       sync.Mutex
+	order    []string
+	gauges   map[string]float64
+	counters map[string]int64
+	fetched  map[string]int64
+}
+
+func newCustomMetrics() *customMetrics {
+	return &customMetrics{gauges: map[string]float64{}, counters: map[string]int64{}, fetched: map[string]int64{}}
+}
+
+func (c *customMetrics) add(metrics []model.Metrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, metric := range metrics {
+		key := metric.MType + ":" + metric.ID
+		_, isGauge := c.gauges[key]
+		_, isCounter := c.counters[key]
+		if !isGauge && !isCounter {
+			c.order = append(c.order, key)
+		}
+
+		if metric.MType == model.Gauge {
+			c.gauges[key] = *metric.Value
+		} else {
+			c.counters[key] += *metric.Delta
+		}
+	}
+}
+
+func (c *customMetrics) fetch() []model.Metrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	metrics := make([]model.Metrics, 0, len(c.order))
+	for _, key := range c.order {
+		mType, id, _ := strings.Cut(key, ":")
+		if mType == model.Gauge {
+			value := c.gauges[key]
+			metrics = append(metrics, *model.NewGauge(id, &value))
+		} else {
+			delta := c.counters[key]
+			c.fetched[key] = delta
+			metrics = append(metrics, *model.NewCounter(id, &delta))
+		}
+	}
+
+	return metrics
+}
+
+func (c *customMetrics) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, delta := range c.fetched {
+		c.counters[key] -= delta
+	}
+	clear(c.fetched)
+}
+
+func decodeOptions(cfg Config, options any) error {
+	if len(cfg.Options) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(cfg.Options, options); err != nil {
+		return fmt.Errorf("bad options: %w", err)
+	}
+
+	return nil
+}
+
+func checkFormat(format string) error {
+	switch format {
+	case FormatAuto, FormatLines, FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,151 @@
+package reader
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCustomMetrics(t *testing.T) {
+	metrics, err := parseCustomMetrics([]byte("# orders\norders_total counter 5\n\nqueue_len gauge 3.5\n"), FormatAuto)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "orders_total", metrics[0].ID)
+	assert.EqualValues(t, 5, *metrics[0].Delta)
+	assert.EqualValues(t, 3.5, *metrics[1].Value)
+
+	metrics, err = parseCustomMetrics([]byte(`[{"id":"queue_len","type":"gauge","value":2}]`), FormatAuto)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.EqualValues(t, 2, *metrics[0].Value)
+
+	_, err = parseCustomMetrics([]byte("orders_total counter 1.5\n"), FormatLines)
+	assert.ErrorContains(t, err, "line 1")
+
+	_, err = parseCustomMetrics([]byte(`[{"id":"x","type":"histogram"}]`), FormatJSON)
+	assert.Error(t, err)
+}
+
+func TestExecReader_AccumulatesCountersUntilReset(t *testing.T) {
+	reader, err := NewExecMetricsReader(ExecOptions{Command: []string{"sh", "-c", "echo 'orders counter 2'; echo 'queue gauge 7'"}})
+	require.NoError(t, err)
+
+	require.NoError(t, reader.Refresh())
+	require.NoError(t, reader.Refresh())
+	byID := metricsByID(mustFetch(t, reader))
+	assert.EqualValues(t, 4, *byID["orders"].Delta)
+	assert.EqualValues(t, 7, *byID["queue"].Value)
+
+	reader.Reset()
+	byID = metricsByID(mustFetch(t, reader))
+	assert.EqualValues(t, 0, *byID["orders"].Delta)
+	assert.EqualValues(t, 7, *byID["queue"].Value, "gauges survive reset")
+}
+
+func TestExecReader_ReportsFailures(t *testing.T) {
+	reader, err := NewExecMetricsReader(ExecOptions{Command: []string{"sh", "-c", "echo boom >&2; exit 3"}})
+	require.NoError(t, err)
+	assert.ErrorContains(t, reader.Refresh(), "boom")
+
+	reader, err = NewExecMetricsReader(ExecOptions{Command: []string{"sleep", "5"}, Timeout: 1})
+	require.NoError(t, err)
+	started := time.Now()
+	assert.Error(t, reader.Refresh())
+	assert.Less(t, time.Since(started), 3*time.Second)
+}
+
+func TestFileReader_TailsAppendedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log")
+	require.NoError(t, os.WriteFile(path, []byte("old counter 100\n"), 0o600))
+
+	reader, err := NewFileMetricsReader(FileOptions{Path: path})
+	require.NoError(t, err)
+
+	appendLine := func(line string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = f.WriteString(line)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	appendLine("orders counter 1\norders counter 2\nqueue gauge 4\norders coun")
+	require.NoError(t, reader.Refresh())
+	byID := metricsByID(mustFetch(t, reader))
+	assert.NotContains(t, byID, "old", "lines written before start are skipped")
+	assert.EqualValues(t, 3, *byID["orders"].Delta)
+
+	appendLine("ter 4\n")
+	require.NoError(t, reader.Refresh())
+	assert.EqualValues(t, 7, *metricsByID(mustFetch(t, reader))["orders"].Delta, "partial line is completed on next poll")
+
+	require.NoError(t, os.WriteFile(path, []byte("queue gauge 1\n"), 0o600))
+	require.NoError(t, reader.Refresh())
+	assert.EqualValues(t, 1, *metricsByID(mustFetch(t, reader))["queue"].Value, "truncated file is read from start")
+}
+
+func TestSocketReader(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 16)
+			n, _ := conn.Read(buf)
+			if string(buf[:n]) == "stats\n" {
+				_, _ = conn.Write([]byte(`[{"id":"sessions","type":"gauge","value":12}]`))
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	reader, err := NewSocketMetricsReader(SocketOptions{Socket: socket, Request: "stats\n"})
+	require.NoError(t, err)
+	require.NoError(t, reader.Refresh())
+	assert.EqualValues(t, 12, *metricsByID(mustFetch(t, reader))["sessions"].Value)
+}
+
+func TestRegistry_CustomReaders(t *testing.T) {
+	readers, err := DefaultRegistry().Build(map[string]Config{
+		"runtime": {Enabled: boolPtr(false)},
+		"system":  {Enabled: boolPtr(false)},
+		"orders":  {Type: "exec", Prefix: "shop_", Options: []byte(`{"command":["echo","total counter 1"]}`)},
+	}, time.Second)
+	require.NoError(t, err)
+	require.Len(t, readers, 1)
+
+	require.NoError(t, readers[0].Refresh())
+	metrics := mustFetch(t, readers[0])
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "shop_total", metrics[0].ID)
+
+	_, err = DefaultRegistry().Build(map[string]Config{"x": {Type: "ftp"}}, time.Second)
+	assert.ErrorContains(t, err, "unknown type")
+
+	_, err = DefaultRegistry().Build(map[string]Config{"runtime": {Type: "exec"}}, time.Second)
+	assert.ErrorContains(t, err, "built-in")
+
+	_, err = DefaultRegistry().Build(map[string]Config{"x": {Type: "exec"}}, time.Second)
+	assert.ErrorContains(t, err, "command is required")
+}
+
+func mustFetch(t *testing.T, reader interface {
+	Fetch() ([]model.Metrics, error)
+}) []model.Metrics {
+	t.Helper()
+	metrics, err := reader.Fetch()
+	require.NoError(t, err)
+	return metrics
+}
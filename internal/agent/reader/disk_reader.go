@@ -0,0 +1,81 @@
+package reader
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// DiskMetricsReader сообщает заполненность каждой точки монтирования (gauge)
+// и счётчики ввода-вывода каждого блочного устройства (counter).
+type DiskMetricsReader struct {
+	mu       sync.RWMutex
+	usage    []model.Metrics
+	counters *counterSet
+}
+
+func NewDiskMetricsReader() *DiskMetricsReader {
+	return &DiskMetricsReader{counters: newCounterSet()}
+}
+
+func (r *DiskMetricsReader) Refresh() error {
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return fmt.Errorf("failed to get partitions: %w", err)
+	}
+
+	usage := make([]model.Metrics, 0, 4*len(partitions))
+	for _, partition := range partitions {
+		stat, err := disk.Usage(partition.Mountpoint)
+		if err != nil {
+			// Недоступная точка монтирования (например, отключённый сетевой диск) не мешает остальным.
+			continue
+		}
+		suffix := "_" + labelSuffix(partition.Mountpoint)
+		total, free, used, percent := float64(stat.Total), float64(stat.Free), float64(stat.Used), stat.UsedPercent
+		usage = append(usage,
+			*model.NewGauge("DiskTotal"+suffix, &total),
+			*model.NewGauge("DiskFree"+suffix, &free),
+			*model.NewGauge("DiskUsed"+suffix, &used),
+			*model.NewGauge("DiskUsedPercent"+suffix, &percent),
+		)
+	}
+
+	io, err := disk.IOCounters()
+	if err != nil {
+		return fmt.Errorf("failed to get disk io counters: %w", err)
+	}
+
+	values := make(map[string]uint64, 5*len(io))
+	for name, stat := range io {
+		suffix := "_" + labelSuffix(name)
+		values["DiskReads"+suffix] = stat.ReadCount
+		values["DiskWrites"+suffix] = stat.WriteCount
+		values["DiskReadBytes"+suffix] = stat.ReadBytes
+		values["DiskWriteBytes"+suffix] = stat.WriteBytes
+		values["DiskIoTimeMs"+suffix] = stat.IoTime
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.usage = usage
+	r.counters.observe(values)
+
+	return nil
+}
+
+func (r *DiskMetricsReader) Fetch() ([]model.Metrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append(append([]model.Metrics{}, r.usage...), r.counters.metrics()...), nil
+}
+
+func (r *DiskMetricsReader) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters.reset()
+}
@@ -0,0 +1,90 @@
+package reader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+)
+
+const defaultExecTimeout = 10 * time.Second
+
+type ExecOptions struct {
+	// Command запускается без оболочки; для конвейеров используйте ["sh", "-c", "..."].
+	Command []string `json:"command"`
+	// Timeout в секундах, по умолчанию 10.
+	Timeout int    `json:"timeout"`
+	Format  string `json:"format"`
+}
+
+// ExecMetricsReader запускает команду при каждом опросе и разбирает её stdout.
+type ExecMetricsReader struct {
+	command []string
+	timeout time.Duration
+	format  string
+	metrics *customMetrics
+}
+
+func NewExecMetricsReader(options ExecOptions) (*ExecMetricsReader, error) {
+	if len(options.Command) == 0 || options.Command[0] == "" {
+		return nil, errors.New("command is required")
+	}
+	if err := checkFormat(options.Format); err != nil {
+		return nil, err
+	}
+
+	timeout := defaultExecTimeout
+	if options.Timeout > 0 {
+		timeout = time.Duration(options.Timeout) * time.Second
+	}
+
+	return &ExecMetricsReader{
+		command: options.Command,
+		timeout: timeout,
+		format:  options.Format,
+		metrics: newCustomMetrics(),
+	}, nil
+}
+
+func newExecReaderFromConfig(cfg Config) (*ExecMetricsReader, error) {
+	var options ExecOptions
+	if err := decodeOptions(cfg, &options); err != nil {
+		return nil, err
+	}
+
+	return NewExecMetricsReader(options)
+}
+
+func (r *ExecMetricsReader) Refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.command[0], r.command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command %s failed: %w: %s", r.command[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	metrics, err := parseCustomMetrics(stdout.Bytes(), r.format)
+	if err != nil {
+		return fmt.Errorf("command %s: %w", r.command[0], err)
+	}
+	r.metrics.add(metrics)
+
+	return nil
+}
+
+func (r *ExecMetricsReader) Fetch() ([]model.Metrics, error) {
+	return r.metrics.fetch(), nil
+}
+
+func (r *ExecMetricsReader) Reset() {
+	r.metrics.reset()
+}
@@ -0,0 +1,99 @@
+package reader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// FileDescriptorMetricsReader сообщает число открытых дескрипторов агента и его лимит,
+// а на Linux — также занятые и максимальные дескрипторы всей системы.
+type FileDescriptorMetricsReader struct {
+	mu      sync.RWMutex
+	process *process.Process
+	metrics []model.Metrics
+}
+
+func NewFileDescriptorMetricsReader() (*FileDescriptorMetricsReader, error) {
+	p, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent process: %w", err)
+	}
+
+	return &FileDescriptorMetricsReader{process: p}, nil
+}
+
+func (r *FileDescriptorMetricsReader) Refresh() error {
+	open, err := r.process.NumFDs()
+	if err != nil {
+		return fmt.Errorf("failed to count open files: %w", err)
+	}
+
+	openFiles := float64(open)
+	metrics := []model.Metrics{*model.NewGauge("AgentOpenFiles", &openFiles)}
+
+	if limits, err := r.process.Rlimit(); err == nil {
+		for _, limit := range limits {
+			if limit.Resource == process.RLIMIT_NOFILE {
+				maxFiles := float64(limit.Soft)
+				metrics = append(metrics, *model.NewGauge("AgentMaxOpenFiles", &maxFiles))
+			}
+		}
+	}
+
+	// gopsutil не даёт системную статистику дескрипторов, на Linux её отдаёт /proc/sys/fs/file-nr.
+	if allocated, maxFiles, ok := systemFileDescriptors(); ok {
+		metrics = append(metrics,
+			*model.NewGauge("FileDescriptorsAllocated", &allocated),
+			*model.NewGauge("FileDescriptorsMax", &maxFiles),
+		)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = metrics
+
+	return nil
+}
+
+func (r *FileDescriptorMetricsReader) Fetch() ([]model.Metrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]model.Metrics{}, r.metrics...), nil
+}
+
+func systemFileDescriptors() (allocated float64, maxFiles float64, ok bool) {
+	procRoot := os.Getenv("HOST_PROC")
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+
+	data, err := os.ReadFile(filepath.Join(procRoot, "sys", "fs", "file-nr"))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	// Формат: <выделено> <выделено, но свободно> <максимум>.
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return 0, 0, false
+	}
+
+	values := make([]float64, 0, 3)
+	for _, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		values = append(values, v)
+	}
+
+	return values[0] - values[1], values[2], true
+}
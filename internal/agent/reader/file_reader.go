@@ -0,0 +1,123 @@
+package reader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+)
+
+type FileOptions struct {
+	Path string `json:"path"`
+	// FromStart — при запуске прочитать уже записанные строки, иначе читать только новые.
+	FromStart bool `json:"from_start"`
+}
+
+// FileMetricsReader читает строки "имя тип значение", дописанные в файл с прошлого опроса (как tail -f).
+// Если файл стал короче или был заменён при ротации, чтение начинается сначала.
+type FileMetricsReader struct {
+	path    string
+	metrics *customMetrics
+
+	mu      sync.Mutex
+	offset  int64
+	file    os.FileInfo
+	partial string
+}
+
+func NewFileMetricsReader(options FileOptions) (*FileMetricsReader, error) {
+	if options.Path == "" {
+		return nil, errors.New("path is required")
+	}
+
+	r := &FileMetricsReader{path: options.Path, metrics: newCustomMetrics()}
+	if !options.FromStart {
+		if info, err := os.Stat(options.Path); err == nil {
+			r.offset = info.Size()
+			r.file = info
+		}
+	}
+
+	return r, nil
+}
+
+func newFileReaderFromConfig(cfg Config) (*FileMetricsReader, error) {
+	var options FileOptions
+	if err := decodeOptions(cfg, &options); err != nil {
+		return nil, err
+	}
+
+	return NewFileMetricsReader(options)
+}
+
+func (r *FileMetricsReader) Refresh() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := os.Open(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Файл ещё не создан или удалён при ротации — новый будет прочитан целиком.
+		r.offset, r.file, r.partial = 0, nil, ""
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", r.path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", r.path, err)
+	}
+	if info.Size() < r.offset || (r.file != nil && !os.SameFile(r.file, info)) {
+		r.offset, r.partial = 0, ""
+	}
+	r.file = info
+
+	if _, err := file.Seek(r.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek %s: %w", r.path, err)
+	}
+
+	var metrics []model.Metrics
+	var parseErr error
+	reader := bufio.NewReader(file)
+	for {
+		chunk, err := reader.ReadString('\n')
+		r.offset += int64(len(chunk))
+		if err != nil {
+			// Незавершённая строка дочитывается при следующем опросе.
+			r.partial += chunk
+			break
+		}
+
+		line := r.partial + chunk
+		r.partial = ""
+		metric, ok, lineErr := parseCustomLine(line)
+		if lineErr != nil {
+			parseErr = errors.Join(parseErr, lineErr)
+			continue
+		}
+		if ok {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	r.metrics.add(metrics)
+	if parseErr != nil {
+		return fmt.Errorf("%s: %w", r.path, parseErr)
+	}
+
+	return nil
+}
+
+func (r *FileMetricsReader) Fetch() ([]model.Metrics, error) {
+	return r.metrics.fetch(), nil
+}
+
+func (r *FileMetricsReader) Reset() {
+	r.metrics.reset()
+}
@@ -0,0 +1,44 @@
+package reader
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+type LoadMetricsReader struct {
+	mu  sync.RWMutex
+	avg load.AvgStat
+}
+
+func NewLoadMetricsReader() *LoadMetricsReader {
+	return &LoadMetricsReader{}
+}
+
+func (r *LoadMetricsReader) Refresh() error {
+	avg, err := load.Avg()
+	if err != nil {
+		return fmt.Errorf("failed to get load average: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.avg = *avg
+
+	return nil
+}
+
+func (r *LoadMetricsReader) Fetch() ([]model.Metrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	load1, load5, load15 := r.avg.Load1, r.avg.Load5, r.avg.Load15
+
+	return []model.Metrics{
+		*model.NewGauge("LoadAverage1", &load1),
+		*model.NewGauge("LoadAverage5", &load5),
+		*model.NewGauge("LoadAverage15", &load15),
+	}, nil
+}
@@ -0,0 +1,59 @@
+package reader
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// NetworkMetricsReader сообщает байты, пакеты, ошибки и отброшенные пакеты каждого интерфейса как counter.
+type NetworkMetricsReader struct {
+	mu       sync.RWMutex
+	counters *counterSet
+}
+
+func NewNetworkMetricsReader() *NetworkMetricsReader {
+	return &NetworkMetricsReader{counters: newCounterSet()}
+}
+
+func (r *NetworkMetricsReader) Refresh() error {
+	stats, err := net.IOCounters(true)
+	if err != nil {
+		return fmt.Errorf("failed to get network counters: %w", err)
+	}
+
+	values := make(map[string]uint64, 8*len(stats))
+	for _, stat := range stats {
+		suffix := "_" + labelSuffix(stat.Name)
+		values["NetBytesRecv"+suffix] = stat.BytesRecv
+		values["NetBytesSent"+suffix] = stat.BytesSent
+		values["NetPacketsRecv"+suffix] = stat.PacketsRecv
+		values["NetPacketsSent"+suffix] = stat.PacketsSent
+		values["NetErrorsIn"+suffix] = stat.Errin
+		values["NetErrorsOut"+suffix] = stat.Errout
+		values["NetDropsIn"+suffix] = stat.Dropin
+		values["NetDropsOut"+suffix] = stat.Dropout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters.observe(values)
+
+	return nil
+}
+
+func (r *NetworkMetricsReader) Fetch() ([]model.Metrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.counters.metrics(), nil
+}
+
+func (r *NetworkMetricsReader) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters.reset()
+}
@@ -0,0 +1,236 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessTarget описывает группу наблюдаемых процессов; задаётся ровно один способ поиска.
+type ProcessTarget struct {
+	// Name попадает в имена метрик: ProcessRSS_<name>.
+	Name        string `json:"name"`
+	PIDFile     string `json:"pid_file"`
+	ProcessName string `json:"process_name"`
+	Cmdline     string `json:"cmdline"`
+}
+
+type processKey struct {
+	target string
+	pid    int32
+}
+
+type ProcessOptions struct {
+	Targets []ProcessTarget `json:"targets"`
+}
+
+type processMatcher struct {
+	suffix  string
+	pidFile string
+	process string
+	cmdline *regexp.Regexp
+}
+
+// ProcessMetricsReader суммирует по каждой группе процессов RSS, число потоков и открытых файлов (gauge)
+// и процессорное время (counter, миллисекунды).
+type ProcessMetricsReader struct {
+	mu       sync.RWMutex
+	targets  []processMatcher
+	scan     bool
+	gauges   []model.Metrics
+	counters *counterSet
+
+	// cpuByPID — последнее процессорное время каждого процесса группы; cpuTotal — накопленный
+	// по группе монотонный итог, который не уменьшается при завершении процессов.
+	cpuByPID map[processKey]float64
+	cpuTotal map[string]float64
+	primed   bool
+}
+
+func NewProcessMetricsReader(options ProcessOptions) (*ProcessMetricsReader, error) {
+	if len(options.Targets) == 0 {
+		return nil, errors.New("at least one process target is required")
+	}
+
+	seen := make(map[string]struct{}, len(options.Targets))
+	targets := make([]processMatcher, 0, len(options.Targets))
+	for _, target := range options.Targets {
+		if target.Name == "" {
+			return nil, errors.New("process target name is required")
+		}
+		suffix := labelSuffix(target.Name)
+		if _, dup := seen[suffix]; dup {
+			return nil, fmt.Errorf("duplicate process target %q", target.Name)
+		}
+		seen[suffix] = struct{}{}
+
+		selectors := 0
+		for _, v := range []string{target.PIDFile, target.ProcessName, target.Cmdline} {
+			if v != "" {
+				selectors++
+			}
+		}
+		if selectors != 1 {
+			return nil, fmt.Errorf("process target %q: exactly one of pid_file, process_name, cmdline is required", target.Name)
+		}
+
+		matcher := processMatcher{suffix: suffix, pidFile: target.PIDFile, process: target.ProcessName}
+		if target.Cmdline != "" {
+			re, err := regexp.Compile(target.Cmdline)
+			if err != nil {
+				return nil, fmt.Errorf("process target %q: bad cmdline regexp: %w", target.Name, err)
+			}
+			matcher.cmdline = re
+		}
+		targets = append(targets, matcher)
+	}
+
+	scan := false
+	for _, target := range targets {
+		scan = scan || target.pidFile == ""
+	}
+
+	return &ProcessMetricsReader{
+		targets:  targets,
+		scan:     scan,
+		counters: newCounterSet(),
+		cpuByPID: make(map[processKey]float64),
+		cpuTotal: make(map[string]float64),
+	}, nil
+}
+
+func newProcessReaderFromConfig(cfg Config) (*ProcessMetricsReader, error) {
+	var options ProcessOptions
+	if err := decodeOptions(cfg, &options); err != nil {
+		return nil, err
+	}
+
+	return NewProcessMetricsReader(options)
+}
+
+func (r *ProcessMetricsReader) Refresh() error {
+	// Полный список процессов нужен, только если есть цели без PID-файла.
+	var all []*process.Process
+	if r.scan {
+		list, err := process.Processes()
+		if err != nil {
+			return fmt.Errorf("failed to list processes: %w", err)
+		}
+		all = list
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	gauges := make([]model.Metrics, 0, 4*len(r.targets))
+	alive := make(map[processKey]struct{})
+	values := make(map[string]uint64, len(r.targets))
+
+	for _, target := range r.targets {
+		matched := target.match(all)
+
+		var rss, threads, files float64
+		for _, p := range matched {
+			key := processKey{target: target.suffix, pid: p.Pid}
+			alive[key] = struct{}{}
+
+			// Процесс мог завершиться между поиском и чтением — такие значения просто пропускаются.
+			if mem, err := p.MemoryInfo(); err == nil {
+				rss += float64(mem.RSS)
+			}
+			if n, err := p.NumThreads(); err == nil {
+				threads += float64(n)
+			}
+			if n, err := p.NumFDs(); err == nil {
+				files += float64(n)
+			}
+			if times, err := p.Times(); err == nil {
+				cpu := (times.User + times.System) * 1000
+				prev, known := r.cpuByPID[key]
+				switch {
+				case known && cpu >= prev:
+					r.cpuTotal[target.suffix] += cpu - prev
+				case !known && r.primed:
+					// Процесс появился после прошлого опроса — его время целиком новое.
+					r.cpuTotal[target.suffix] += cpu
+				}
+				r.cpuByPID[key] = cpu
+			}
+		}
+
+		count := float64(len(matched))
+		gauges = append(gauges,
+			*model.NewGauge("ProcessCount_"+target.suffix, &count),
+			*model.NewGauge("ProcessRSS_"+target.suffix, &rss),
+			*model.NewGauge("ProcessThreads_"+target.suffix, &threads),
+			*model.NewGauge("ProcessOpenFiles_"+target.suffix, &files),
+		)
+		values["ProcessCPUTimeMs_"+target.suffix] = uint64(r.cpuTotal[target.suffix])
+	}
+
+	for key := range r.cpuByPID {
+		if _, ok := alive[key]; !ok {
+			delete(r.cpuByPID, key)
+		}
+	}
+
+	r.primed = true
+	r.gauges = gauges
+	r.counters.observe(values)
+
+	return nil
+}
+
+func (r *ProcessMetricsReader) Fetch() ([]model.Metrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append(append([]model.Metrics{}, r.gauges...), r.counters.metrics()...), nil
+}
+
+func (r *ProcessMetricsReader) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters.reset()
+}
+
+func (m processMatcher) match(all []*process.Process) []*process.Process {
+	if m.pidFile != "" {
+		data, err := os.ReadFile(m.pidFile)
+		if err != nil {
+			return nil
+		}
+		pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+		if err != nil {
+			return nil
+		}
+		p, err := process.NewProcess(int32(pid))
+		if err != nil {
+			return nil
+		}
+		return []*process.Process{p}
+	}
+
+	var matched []*process.Process
+	for _, p := range all {
+		if m.process != "" {
+			if name, err := p.Name(); err == nil && name == m.process {
+				matched = append(matched, p)
+			}
+			continue
+		}
+		if cmdline, err := p.Cmdline(); err == nil && m.cmdline.MatchString(cmdline) {
+			matched = append(matched, p)
+		}
+	}
+
+	return matched
+}
@@ -0,0 +1,57 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessReader_ValidatesTargets(t *testing.T) {
+	_, err := NewProcessMetricsReader(ProcessOptions{})
+	assert.Error(t, err)
+
+	_, err = NewProcessMetricsReader(ProcessOptions{Targets: []ProcessTarget{{Name: "api", ProcessName: "api", Cmdline: "api"}}})
+	assert.ErrorContains(t, err, "exactly one")
+
+	_, err = NewProcessMetricsReader(ProcessOptions{Targets: []ProcessTarget{{Name: "api", Cmdline: "("}}})
+	assert.ErrorContains(t, err, "bad cmdline regexp")
+
+	_, err = NewProcessMetricsReader(ProcessOptions{Targets: []ProcessTarget{
+		{Name: "api", ProcessName: "api"},
+		{Name: "api", ProcessName: "api2"},
+	}})
+	assert.ErrorContains(t, err, "duplicate")
+}
+
+func TestProcessReader_WatchesOwnProcess(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "agent.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o600))
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	reader, err := NewProcessMetricsReader(ProcessOptions{Targets: []ProcessTarget{
+		{Name: "by-pid", PIDFile: pidFile},
+		{Name: "by-cmdline", Cmdline: regexp.QuoteMeta(filepath.Base(executable))},
+		{Name: "missing", PIDFile: filepath.Join(t.TempDir(), "none.pid")},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, reader.Refresh())
+
+	metrics, err := reader.Fetch()
+	require.NoError(t, err)
+	byID := metricsByID(metrics)
+
+	assert.EqualValues(t, 1, *byID["ProcessCount_by_pid"].Value)
+	assert.Greater(t, *byID["ProcessRSS_by_pid"].Value, 0.0)
+	assert.GreaterOrEqual(t, *byID["ProcessThreads_by_pid"].Value, 1.0)
+	assert.Greater(t, *byID["ProcessOpenFiles_by_pid"].Value, 0.0)
+	assert.GreaterOrEqual(t, *byID["ProcessCount_by_cmdline"].Value, 1.0)
+	assert.EqualValues(t, 0, *byID["ProcessCount_missing"].Value)
+	assert.EqualValues(t, 0, *byID["ProcessCPUTimeMs_by_pid"].Delta, "first poll is a baseline")
+}
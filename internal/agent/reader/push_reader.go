@@ -0,0 +1,42 @@
+package reader
+
+import (
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+)
+
+// PushMetricsReader отдаёт метрики, присланные приложениями на локальный endpoint агента.
+type PushMetricsReader struct {
+	metrics *customMetrics
+}
+
+func NewPushMetricsReader() *PushMetricsReader {
+	return &PushMetricsReader{metrics: newCustomMetrics()}
+}
+
+// Push принимает пачку целиком или отклоняет её при первой некорректной метрике.
+func (r *PushMetricsReader) Push(metrics []model.Metrics) error {
+	for _, metric := range metrics {
+		if err := validateCustomMetric(metric); err != nil {
+			return err
+		}
+	}
+	r.metrics.add(metrics)
+
+	return nil
+}
+
+func (r *PushMetricsReader) Name() string {
+	return "push"
+}
+
+func (r *PushMetricsReader) Refresh() error {
+	return nil
+}
+
+func (r *PushMetricsReader) Fetch() ([]model.Metrics, error) {
+	return r.metrics.fetch(), nil
+}
+
+func (r *PushMetricsReader) Reset() {
+	r.metrics.reset()
+}
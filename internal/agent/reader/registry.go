@@ -0,0 +1,278 @@
+package reader
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/agent"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/goccy/go-json"
+)
+
+// Config — настройки читателя из секции readers файла конфигурации агента.
+type Config struct {
+	// Type задаёт вид пользовательского читателя (exec, file, socket, scrape); имя записи тогда выбирается свободно.
+	// Для встроенных читателей не указывается.
+	Type string `json:"type"`
+	// Enabled включает или выключает читатель; если не задан, действует значение по умолчанию из реестра.
+	Enabled *bool `json:"enabled"`
+	// PollInterval — интервал опроса в секундах, не меньше общего POLL_INTERVAL; 0 — общий интервал.
+	PollInterval int `json:"poll_interval"`
+	// Prefix добавляется к именам всех метрик читателя.
+	Prefix string `json:"prefix"`
+	// Include и Exclude — шаблоны path.Match для исходных имён метрик. Exclude применяется после Include.
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	// Options — собственные настройки читателя, их разбирает фабрика.
+	Options json.RawMessage `json:"options"`
+}
+
+type Factory func(cfg Config) (agent.Reader, error)
+
+type registration struct {
+	factory          Factory
+	enabledByDefault bool
+}
+
+// Registry сопоставляет имена читателей из конфигурации с их фабриками.
+// Встроенные читатели существуют в единственном экземпляре, а пользовательских
+// каждого вида (kind) может быть сколько угодно под разными именами.
+type Registry struct {
+	readers map[string]registration
+	kinds   map[string]Factory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{readers: make(map[string]registration), kinds: make(map[string]Factory)}
+}
+
+// DefaultRegistry содержит все встроенные читатели; runtime и system включены по умолчанию,
+// остальные включаются в конфигурации.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register("runtime", true, func(Config) (agent.Reader, error) { return NewRuntimeMetricsReader(), nil })
+	r.Register("system", true, func(Config) (agent.Reader, error) { return NewSystemMetricsReader(), nil })
+	r.Register("disk", false, func(Config) (agent.Reader, error) { return NewDiskMetricsReader(), nil })
+	r.Register("network", false, func(Config) (agent.Reader, error) { return NewNetworkMetricsReader(), nil })
+	r.Register("load", false, func(Config) (agent.Reader, error) { return NewLoadMetricsReader(), nil })
+	r.Register("swap", false, func(Config) (agent.Reader, error) { return NewSwapMetricsReader(), nil })
+	r.Register("fd", false, func(Config) (agent.Reader, error) { return NewFileDescriptorMetricsReader() })
+	r.Register("process", false, func(cfg Config) (agent.Reader, error) { return newProcessReaderFromConfig(cfg) })
+	r.Register("cgroup", false, func(cfg Config) (agent.Reader, error) { return newCgroupReaderFromConfig(cfg) })
+	r.RegisterKind("exec", func(cfg Config) (agent.Reader, error) { return newExecReaderFromConfig(cfg) })
+	r.RegisterKind("file", func(cfg Config) (agent.Reader, error) { return newFileReaderFromConfig(cfg) })
+	r.RegisterKind("socket", func(cfg Config) (agent.Reader, error) { return newSocketReaderFromConfig(cfg) })
+	r.RegisterKind("scrape", func(cfg Config) (agent.Reader, error) { return newScrapeReaderFromConfig(cfg) })
+
+	return r
+}
+
+func (r *Registry) RegisterKind(kind string, factory Factory) {
+	r.kinds[kind] = factory
+}
+
+func (r *Registry) Register(name string, enabledByDefault bool, factory Factory) {
+	r.readers[name] = registration{factory: factory, enabledByDefault: enabledByDefault}
+}
+
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.readers))
+	for name := range r.readers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Build создаёт включённые встроенные читатели в порядке имён, затем пользовательские.
+// Неизвестное имя или вид в конфигурации — ошибка, чтобы опечатка не выключала сбор молча.
+// Пользовательские читатели включены, если явно не выключены.
+func (r *Registry) Build(configs map[string]Config, pollInterval time.Duration) ([]agent.Reader, error) {
+	for name, cfg := range configs {
+		if cfg.Type != "" {
+			if _, builtin := r.readers[name]; builtin {
+				return nil, fmt.Errorf("reader %q: name is taken by a built-in reader", name)
+			}
+			if _, ok := r.kinds[cfg.Type]; !ok {
+				return nil, fmt.Errorf("reader %q: unknown type %q", name, cfg.Type)
+			}
+			continue
+		}
+		if _, ok := r.readers[name]; !ok {
+			return nil, fmt.Errorf("unknown reader %q, available: %v", name, r.Names())
+		}
+	}
+
+	names := r.Names()
+	custom := make([]string, 0, len(configs))
+	for name, cfg := range configs {
+		if cfg.Type != "" {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(custom)
+
+	readers := make([]agent.Reader, 0, len(names)+len(custom))
+	for _, name := range append(names, custom...) {
+		cfg := configs[name]
+
+		factory, enabled := r.kinds[cfg.Type], true
+		if cfg.Type == "" {
+			reg := r.readers[name]
+			factory, enabled = reg.factory, reg.enabledByDefault
+		}
+		if cfg.Enabled != nil {
+			enabled = *cfg.Enabled
+		}
+		if !enabled {
+			continue
+		}
+
+		interval := pollInterval
+		if cfg.PollInterval != 0 {
+			interval = time.Duration(cfg.PollInterval) * time.Second
+			if interval < pollInterval {
+				return nil, fmt.Errorf("reader %s: poll interval %s is shorter than agent poll interval %s", name, interval, pollInterval)
+			}
+		}
+
+		for _, pattern := range append(append([]string{}, cfg.Include...), cfg.Exclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("reader %s: bad filter %q: %w", name, pattern, err)
+			}
+		}
+
+		inner, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("reader %s: %w", name, err)
+		}
+
+		readers = append(readers, &ConfiguredReader{
+			name:     name,
+			inner:    inner,
+			interval: interval,
+			prefix:   cfg.Prefix,
+			include:  cfg.Include,
+			exclude:  cfg.Exclude,
+			now:      time.Now,
+		})
+	}
+
+	return readers, nil
+}
+
+// ConfiguredReader применяет к читателю собственный интервал опроса, префикс и фильтры имён.
+type ConfiguredReader struct {
+	name     string
+	inner    agent.Reader
+	interval time.Duration
+	prefix   string
+	include  []string
+	exclude  []string
+
+	mu          sync.Mutex
+	lastRefresh time.Time
+	// refreshed — опрос прошёл и его значения ещё не забраны; fetched — забраны, но сброс ещё не передан.
+	refreshed bool
+	fetched   bool
+	now       func() time.Time
+}
+
+func (r *ConfiguredReader) Name() string {
+	return r.name
+}
+
+// Refresh опрашивает читатель, только если с прошлого опроса прошёл его интервал.
+// Запас в десятую часть интервала компенсирует неровность общего тикера.
+func (r *ConfiguredReader) Refresh() error {
+	r.mu.Lock()
+	now := r.now()
+	if !r.lastRefresh.IsZero() && now.Sub(r.lastRefresh) < r.interval-r.interval/10 {
+		r.mu.Unlock()
+		return nil
+	}
+	r.lastRefresh = now
+	r.mu.Unlock()
+
+	if err := r.inner.Refresh(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.refreshed = true
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Reset передаёт сброс счётчиков читателю, который его поддерживает, только после сбора
+// его значений: на пропущенном такте накопленные приросты должны дождаться своего опроса.
+func (r *ConfiguredReader) Reset() {
+	r.mu.Lock()
+	fetched := r.fetched
+	r.fetched = false
+	r.mu.Unlock()
+
+	if !fetched {
+		return
+	}
+	if resetable, ok := r.inner.(agent.ResetableReader); ok {
+		resetable.Reset()
+	}
+}
+
+// Fetch отдаёт значения только один раз после состоявшегося опроса. На тактах, где опрос
+// пропущен из-за собственного интервала, метрик нет: иначе прежние gauge попадали бы
+// в агрегатор повторно и искажали среднее и другие агрегаты.
+func (r *ConfiguredReader) Fetch() ([]model.Metrics, error) {
+	r.mu.Lock()
+	refreshed := r.refreshed
+	r.refreshed = false
+	r.mu.Unlock()
+
+	if !refreshed {
+		return nil, nil
+	}
+
+	metrics, err := r.inner.Fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := metrics[:0]
+	for _, metric := range metrics {
+		if !r.accepts(metric.ID) {
+			continue
+		}
+		metric.ID = r.prefix + metric.ID
+		filtered = append(filtered, metric)
+	}
+
+	r.mu.Lock()
+	r.fetched = true
+	r.mu.Unlock()
+
+	return filtered, nil
+}
+
+func (r *ConfiguredReader) accepts(name string) bool {
+	if len(r.include) > 0 && !matchAny(r.include, name) {
+		return false
+	}
+
+	return !matchAny(r.exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,126 @@
+package reader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/agent"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReader struct {
+	refreshes int
+	names     []string
+}
+
+func (r *fakeReader) Refresh() error {
+	r.refreshes++
+	return nil
+}
+
+func (r *fakeReader) Fetch() ([]model.Metrics, error) {
+	metrics := make([]model.Metrics, 0, len(r.names))
+	for _, name := range r.names {
+		value := 1.0
+		metrics = append(metrics, *model.NewGauge(name, &value))
+	}
+	return metrics, nil
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestRegistry_DefaultReaders(t *testing.T) {
+	readers, err := DefaultRegistry().Build(nil, 2*time.Second)
+	require.NoError(t, err)
+	require.Len(t, readers, 2)
+	assert.Equal(t, "runtime", readers[0].(*ConfiguredReader).Name())
+	assert.Equal(t, "system", readers[1].(*ConfiguredReader).Name())
+
+	readers, err = DefaultRegistry().Build(map[string]Config{"runtime": {Enabled: boolPtr(false)}}, 2*time.Second)
+	require.NoError(t, err)
+	require.Len(t, readers, 1)
+	assert.Equal(t, "system", readers[0].(*ConfiguredReader).Name())
+}
+
+func TestRegistry_RejectsBadConfig(t *testing.T) {
+	_, err := DefaultRegistry().Build(map[string]Config{"runtme": {}}, time.Second)
+	assert.ErrorContains(t, err, "unknown reader")
+
+	_, err = DefaultRegistry().Build(map[string]Config{"runtime": {PollInterval: 1}}, 2*time.Second)
+	assert.ErrorContains(t, err, "shorter than agent poll interval")
+
+	_, err = DefaultRegistry().Build(map[string]Config{"runtime": {Include: []string{"Heap["}}}, time.Second)
+	assert.ErrorContains(t, err, "bad filter")
+}
+
+func TestConfiguredReader_FiltersAndPrefix(t *testing.T) {
+	fake := &fakeReader{names: []string{"HeapAlloc", "HeapSys", "StackSys", "NumGC"}}
+	registry := NewRegistry()
+	registry.Register("fake", false, func(Config) (agent.Reader, error) { return fake, nil })
+
+	readers, err := registry.Build(map[string]Config{"fake": {
+		Enabled: boolPtr(true),
+		Prefix:  "go_",
+		Include: []string{"Heap*", "*Sys"},
+		Exclude: []string{"HeapSys"},
+	}}, time.Second)
+	require.NoError(t, err)
+	require.Len(t, readers, 1)
+
+	require.NoError(t, readers[0].Refresh())
+	metrics, err := readers[0].Fetch()
+	require.NoError(t, err)
+	names := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		names = append(names, metric.ID)
+	}
+	assert.Equal(t, []string{"go_HeapAlloc", "go_StackSys"}, names)
+}
+
+func TestConfiguredReader_OwnPollInterval(t *testing.T) {
+	fake := &fakeReader{}
+	registry := NewRegistry()
+	registry.Register("fake", true, func(Config) (agent.Reader, error) { return fake, nil })
+
+	readers, err := registry.Build(map[string]Config{"fake": {PollInterval: 10}}, 2*time.Second)
+	require.NoError(t, err)
+	configured := readers[0].(*ConfiguredReader)
+	now := time.Now()
+	configured.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, configured.Refresh())
+		now = now.Add(2 * time.Second)
+	}
+	assert.Equal(t, 1, fake.refreshes)
+
+	require.NoError(t, configured.Refresh())
+	assert.Equal(t, 2, fake.refreshes, "reader is polled again once its interval has passed")
+}
+
+func TestConfiguredReader_FetchesOnlyAfterRefresh(t *testing.T) {
+	fake := &fakeReader{names: []string{"Load"}}
+	registry := NewRegistry()
+	registry.Register("fake", true, func(Config) (agent.Reader, error) { return fake, nil })
+
+	readers, err := registry.Build(map[string]Config{"fake": {PollInterval: 10}}, 2*time.Second)
+	require.NoError(t, err)
+	configured := readers[0].(*ConfiguredReader)
+	now := time.Now()
+	configured.now = func() time.Time { return now }
+
+	require.NoError(t, configured.Refresh())
+	metrics, err := configured.Fetch()
+	require.NoError(t, err)
+	assert.Len(t, metrics, 1)
+
+	now = now.Add(2 * time.Second)
+	require.NoError(t, configured.Refresh())
+	metrics, err = configured.Fetch()
+	require.NoError(t, err)
+	assert.Empty(t, metrics, "skipped poll must not repeat previous gauges")
+}
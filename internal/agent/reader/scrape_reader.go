@@ -0,0 +1,353 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/goccy/go-json"
+)
+
+// Форматы опрашиваемых HTTP-endpoint'ов.
+const (
+	FormatPrometheus = "prometheus"
+	FormatExpvar     = "expvar"
+)
+
+const (
+	defaultScrapeTimeout = 5 * time.Second
+	// maxScrapeBytes ограничивает размер ответа, чтобы ошибочный адрес не съел память агента.
+	maxScrapeBytes = 16 << 20
+)
+
+type ScrapeOptions struct {
+	URL string `json:"url"`
+	// Format — prometheus или expvar; если не задан, выбирается по Content-Type ответа.
+	Format string `json:"format"`
+	// Timeout в секундах на запрос, по умолчанию 5.
+	Timeout int `json:"timeout"`
+	// Counters — шаблоны path.Match имён expvar, которые растут монотонно и отправляются как counter.
+	Counters []string `json:"counters"`
+}
+
+// ScrapeMetricsReader опрашивает HTTP-endpoint в текстовом формате Prometheus или JSON expvar.
+// Накопительные счётчики превращаются в приросты, остальные значения отправляются как gauge.
+type ScrapeMetricsReader struct {
+	url      string
+	format   string
+	counters []string
+	client   *http.Client
+
+	mu           sync.RWMutex
+	gauges       []model.Metrics
+	counterDelta *counterSet
+}
+
+func NewScrapeMetricsReader(options ScrapeOptions) (*ScrapeMetricsReader, error) {
+	if options.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	switch options.Format {
+	case FormatAuto, FormatPrometheus, FormatExpvar:
+	default:
+		return nil, fmt.Errorf("unknown format %q", options.Format)
+	}
+	for _, pattern := range options.Counters {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad counter pattern %q: %w", pattern, err)
+		}
+	}
+
+	timeout := defaultScrapeTimeout
+	if options.Timeout > 0 {
+		timeout = time.Duration(options.Timeout) * time.Second
+	}
+
+	return &ScrapeMetricsReader{
+		url:          options.URL,
+		format:       options.Format,
+		counters:     options.Counters,
+		client:       &http.Client{Timeout: timeout},
+		counterDelta: newCounterSet(),
+	}, nil
+}
+
+func newScrapeReaderFromConfig(cfg Config) (*ScrapeMetricsReader, error) {
+	var options ScrapeOptions
+	if err := decodeOptions(cfg, &options); err != nil {
+		return nil, err
+	}
+
+	return NewScrapeMetricsReader(options)
+}
+
+func (r *ScrapeMetricsReader) Refresh() error {
+	resp, err := r.client.Get(r.url)
+	if err != nil {
+		return fmt.Errorf("failed to scrape %s: %w", r.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to scrape %s: status %d", r.url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxScrapeBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", r.url, err)
+	}
+
+	format := r.format
+	if format == FormatAuto {
+		format = FormatPrometheus
+		if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+			format = FormatExpvar
+		}
+	}
+
+	var gauges map[string]float64
+	var counters map[string]uint64
+	if format == FormatExpvar {
+		gauges, counters, err = parseExpvar(data, r.counters)
+	} else {
+		gauges, counters, err = parsePrometheusText(data)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.url, err)
+	}
+
+	names := make([]string, 0, len(gauges))
+	for name := range gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]model.Metrics, 0, len(names))
+	for _, name := range names {
+		value := gauges[name]
+		metrics = append(metrics, *model.NewGauge(name, &value))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges = metrics
+	r.counterDelta.observe(counters)
+
+	return nil
+}
+
+func (r *ScrapeMetricsReader) Fetch() ([]model.Metrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append(append([]model.Metrics{}, r.gauges...), r.counterDelta.metrics()...), nil
+}
+
+func (r *ScrapeMetricsReader) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counterDelta.reset()
+}
+
+// parsePrometheusText разбирает текстовый формат экспозиции Prometheus.
+// Метки входят в имя метрики: requests_total{code="200"} → requests_total_code_200.
+// Сэмплы counter, а также _count и _bucket гистограмм и summary считаются накопительными.
+func parsePrometheusText(data []byte) (map[string]float64, map[string]uint64, error) {
+	types := map[string]string{}
+	gauges := map[string]float64{}
+	counters := map[string]uint64{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScrapeBytes)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "#") {
+			fields := strings.Fields(text)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		name, labels, value, err := parsePrometheusSample(text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+
+		id := sanitizeMetricName(name)
+		keys := make([]string, 0, len(labels))
+		for key := range labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			id += "_" + sanitizeMetricName(key) + "_" + labelSuffix(labels[key])
+		}
+
+		if isPrometheusCounter(types, name) {
+			if value >= 0 {
+				counters[id] = uint64(value)
+			}
+			continue
+		}
+		gauges[id] = value
+	}
+
+	return gauges, counters, scanner.Err()
+}
+
+func isPrometheusCounter(types map[string]string, name string) bool {
+	if typ, ok := types[name]; ok {
+		return typ == "counter"
+	}
+	for _, suffix := range []string{"_total", "_count", "_bucket", "_sum"} {
+		family, found := strings.CutSuffix(name, suffix)
+		if !found {
+			continue
+		}
+		switch types[family] {
+		case "counter":
+			return true
+		case "histogram", "summary":
+			return suffix == "_count" || suffix == "_bucket"
+		}
+	}
+
+	return false
+}
+
+// parsePrometheusSample разбирает строку вида name{label="value",...} value [timestamp].
+func parsePrometheusSample(line string) (string, map[string]string, float64, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return "", nil, 0, fmt.Errorf("bad sample %q", line)
+	}
+	name, rest := line[:end], line[end:]
+
+	labels := map[string]string{}
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		labels, rest, err = parsePrometheusLabels(rest[1:])
+		if err != nil {
+			return "", nil, 0, err
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", nil, 0, fmt.Errorf("bad sample %q", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("bad value %q", fields[0])
+	}
+
+	return name, labels, value, nil
+}
+
+func parsePrometheusLabels(s string) (map[string]string, string, error) {
+	labels := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || len(s) < eq+2 || s[eq+1] != '"' {
+			return nil, "", errors.New("bad labels")
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = s[eq+2:]
+
+		var value strings.Builder
+		closed := false
+		for i := 0; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '\\' && i+1 < len(s):
+				i++
+				if s[i] == 'n' {
+					value.WriteByte('\n')
+				} else {
+					value.WriteByte(s[i])
+				}
+			case c == '"':
+				s, closed = s[i+1:], true
+			default:
+				value.WriteByte(c)
+			}
+			if closed {
+				break
+			}
+		}
+		if !closed {
+			return nil, "", errors.New("unterminated label value")
+		}
+		labels[key] = value.String()
+
+		s = strings.TrimLeft(s, " \t")
+		s = strings.TrimPrefix(s, ",")
+	}
+}
+
+// parseExpvar разворачивает JSON expvar в плоские имена: memstats.HeapAlloc → memstats_HeapAlloc.
+// Учитываются только числа; строки, массивы и логические значения пропускаются.
+func parseExpvar(data []byte, counterPatterns []string) (map[string]float64, map[string]uint64, error) {
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, nil, fmt.Errorf("bad expvar json: %w", err)
+	}
+
+	gauges := map[string]float64{}
+	counters := map[string]uint64{}
+	var walk func(prefix string, node map[string]any)
+	walk = func(prefix string, node map[string]any) {
+		for key, value := range node {
+			name := sanitizeMetricName(key)
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+			switch v := value.(type) {
+			case map[string]any:
+				walk(name, v)
+			case float64:
+				if matchAny(counterPatterns, name) {
+					if v >= 0 {
+						counters[name] = uint64(v)
+					}
+					continue
+				}
+				gauges[name] = v
+			}
+		}
+	}
+	walk("", root)
+
+	return gauges, counters, nil
+}
+
+// sanitizeMetricName заменяет недопустимые в имени метрики символы на '_', не трогая остальные.
+func sanitizeMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
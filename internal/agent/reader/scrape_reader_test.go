@@ -0,0 +1,93 @@
+package reader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prometheusSample = `# HELP http_requests_total Requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",code="200"} 10
+http_requests_total{code="500",method="GET"} 2 1700000000000
+# TYPE queue_depth gauge
+queue_depth{queue="a \"fast\", one"} 3.5
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.5"} 4
+latency_seconds_bucket{le="+Inf"} 5
+latency_seconds_sum 1.25
+latency_seconds_count 5
+process_up NaN
+untyped_value 7
+`
+
+func TestParsePrometheusText(t *testing.T) {
+	gauges, counters, err := parsePrometheusText([]byte(prometheusSample))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]uint64{
+		"http_requests_total_code_200_method_GET": 10,
+		"http_requests_total_code_500_method_GET": 2,
+		"latency_seconds_bucket_le_0_5":           4,
+		"latency_seconds_bucket_le_Inf":           5,
+		"latency_seconds_count":                   5,
+	}, counters)
+	assert.Equal(t, map[string]float64{
+		"queue_depth_queue_a__fast___one": 3.5,
+		"latency_seconds_sum":             1.25,
+		"untyped_value":                   7,
+	}, gauges)
+
+	_, _, err = parsePrometheusText([]byte("broken{code=\"200 1\n"))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestParseExpvar(t *testing.T) {
+	data := []byte(`{"cmdline":["app"],"requests":12,"memstats":{"HeapAlloc":1024,"BySize":[{"Size":8}]},"enabled":true,"name":"svc"}`)
+	gauges, counters, err := parseExpvar(data, []string{"requests"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]float64{"memstats_HeapAlloc": 1024}, gauges)
+	assert.Equal(t, map[string]uint64{"requests": 12}, counters)
+}
+
+func TestScrapeReader_DetectsFormatAndCountsDeltas(t *testing.T) {
+	requests := 10
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/debug/vars" {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(`{"goroutines":5}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte("# TYPE jobs_total counter\njobs_total " + strconv.Itoa(requests) + "\n"))
+	}))
+	defer srv.Close()
+
+	reader, err := NewScrapeMetricsReader(ScrapeOptions{URL: srv.URL + "/metrics"})
+	require.NoError(t, err)
+	require.NoError(t, reader.Refresh())
+	assert.EqualValues(t, 0, *metricsByID(mustFetch(t, reader))["jobs_total"].Delta, "first scrape is the baseline")
+
+	requests = 16
+	require.NoError(t, reader.Refresh())
+	assert.EqualValues(t, 6, *metricsByID(mustFetch(t, reader))["jobs_total"].Delta)
+	reader.Reset()
+	assert.EqualValues(t, 0, *metricsByID(mustFetch(t, reader))["jobs_total"].Delta)
+
+	expvar, err := NewScrapeMetricsReader(ScrapeOptions{URL: srv.URL + "/debug/vars"})
+	require.NoError(t, err)
+	require.NoError(t, expvar.Refresh())
+	assert.EqualValues(t, 5, *metricsByID(mustFetch(t, expvar))["goroutines"].Value)
+
+	failing, err := NewScrapeMetricsReader(ScrapeOptions{URL: srv.URL + "/missing", Format: FormatExpvar})
+	require.NoError(t, err)
+	assert.Error(t, failing.Refresh())
+
+	_, err = NewScrapeMetricsReader(ScrapeOptions{URL: srv.URL, Format: "xml"})
+	assert.ErrorContains(t, err, "unknown format")
+}
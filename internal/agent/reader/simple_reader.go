@@ -17,6 +17,10 @@ func NewSimpleMetricsReader() *SimpleMetricsReader {
 	return &SimpleMetricsReader{}
 }
 
+func (r *SimpleMetricsReader) Name() string {
+	return "simple"
+}
+
 func (r *SimpleMetricsReader) Refresh() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
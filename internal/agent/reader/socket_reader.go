@@ -0,0 +1,100 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+)
+
+const defaultSocketTimeout = 5 * time.Second
+
+type SocketOptions struct {
+	Socket string `json:"socket"`
+	// Request отправляется после подключения, например "show stat\n"; без него сокет только читается.
+	Request string `json:"request"`
+	// Timeout в секундах на весь обмен, по умолчанию 5.
+	Timeout int    `json:"timeout"`
+	Format  string `json:"format"`
+}
+
+// SocketMetricsReader при каждом опросе подключается к Unix-сокету и читает ответ до закрытия соединения.
+type SocketMetricsReader struct {
+	socket  string
+	request string
+	timeout time.Duration
+	format  string
+	metrics *customMetrics
+}
+
+func NewSocketMetricsReader(options SocketOptions) (*SocketMetricsReader, error) {
+	if options.Socket == "" {
+		return nil, errors.New("socket is required")
+	}
+	if err := checkFormat(options.Format); err != nil {
+		return nil, err
+	}
+
+	timeout := defaultSocketTimeout
+	if options.Timeout > 0 {
+		timeout = time.Duration(options.Timeout) * time.Second
+	}
+
+	return &SocketMetricsReader{
+		socket:  options.Socket,
+		request: options.Request,
+		timeout: timeout,
+		format:  options.Format,
+		metrics: newCustomMetrics(),
+	}, nil
+}
+
+func newSocketReaderFromConfig(cfg Config) (*SocketMetricsReader, error) {
+	var options SocketOptions
+	if err := decodeOptions(cfg, &options); err != nil {
+		return nil, err
+	}
+
+	return NewSocketMetricsReader(options)
+}
+
+func (r *SocketMetricsReader) Refresh() error {
+	conn, err := net.DialTimeout("unix", r.socket, r.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", r.socket, err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(r.timeout)); err != nil {
+		return err
+	}
+	if r.request != "" {
+		if _, err := io.WriteString(conn, r.request); err != nil {
+			return fmt.Errorf("failed to write to %s: %w", r.socket, err)
+		}
+	}
+
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read from %s: %w", r.socket, err)
+	}
+
+	metrics, err := parseCustomMetrics(data, r.format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.socket, err)
+	}
+	r.metrics.add(metrics)
+
+	return nil
+}
+
+func (r *SocketMetricsReader) Fetch() ([]model.Metrics, error) {
+	return r.metrics.fetch(), nil
+}
+
+func (r *SocketMetricsReader) Reset() {
+	r.metrics.reset()
+}
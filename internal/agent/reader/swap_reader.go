@@ -0,0 +1,45 @@
+package reader
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+type SwapMetricsReader struct {
+	mu   sync.RWMutex
+	swap mem.SwapMemoryStat
+}
+
+func NewSwapMetricsReader() *SwapMetricsReader {
+	return &SwapMetricsReader{}
+}
+
+func (r *SwapMetricsReader) Refresh() error {
+	swap, err := mem.SwapMemory()
+	if err != nil {
+		return fmt.Errorf("failed to get swap info: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.swap = *swap
+
+	return nil
+}
+
+func (r *SwapMetricsReader) Fetch() ([]model.Metrics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	total, used, free, percent := float64(r.swap.Total), float64(r.swap.Used), float64(r.swap.Free), r.swap.UsedPercent
+
+	return []model.Metrics{
+		*model.NewGauge("SwapTotal", &total),
+		*model.NewGauge("SwapUsed", &used),
+		*model.NewGauge("SwapFree", &free),
+		*model.NewGauge("SwapUsedPercent", &percent),
+	}, nil
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+)
+
+// signatureHeaders подписывает payload общим секретом (HMAC) и/или ключом агента.
+// Оба варианта используют одни метку времени и nonce, поэтому сервер проверяет повтор один раз.
+func signatureHeaders(signer *signature.Signer, keySigner *signature.KeySigner, payload []byte) (map[string]string, error) {
+	headers := map[string]string{}
+	if signer == nil && keySigner == nil {
+		return headers, nil
+	}
+
+	timestamp, nonce, err := signature.NewNonce(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	signed := signature.SignedPayload(timestamp, nonce, payload)
+	headers[signature.TimestampHeader] = timestamp
+	headers[signature.NonceHeader] = nonce
+
+	if signer != nil {
+		hash, err := signer.Hash(signed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate hash: %w", err)
+		}
+		headers["HashSHA256"] = hash
+	}
+
+	if keySigner != nil {
+		sig, err := keySigner.Sign(signed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+		headers[signature.AgentKeyIDHeader] = keySigner.KeyID()
+		headers[signature.AgentSignatureHeader] = sig
+	}
+
+	return headers, nil
+}
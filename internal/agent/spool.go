@@ -0,0 +1,423 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/goccy/go-json"
+)
+
+// ErrSpoolFull — пачка не помещается в спул даже после слияния сегментов.
+var ErrSpoolFull = errors.New("spool is full")
+
+const spoolSegmentExt = ".json"
+
+type spoolRecord struct {
+	model.Metrics
+	StoredAt int64 `json:"stored_at"`
+}
+
+type spoolSegment struct {
+	path string
+	seq  uint64
+	size int64
+}
+
+// Spool — ограниченная по размеру очередь неотправленных пачек на диске.
+// Каждая пачка хранится отдельным сегментом и воспроизводится в порядке записи.
+// При переполнении сегменты сливаются в один: дельты счётчиков суммируются,
+// от gauge остаётся последнее значение, устаревшие gauge отбрасываются.
+type Spool struct {
+	dir         string
+	maxBytes    int64
+	maxGaugeAge time.Duration
+
+	// mu защищает файлы спула и держится только на время работы с диском, не во время отправки.
+	mu  sync.Mutex
+	seq uint64
+	// inflight — сегмент, который сейчас отправляется; слияние при переполнении его не трогает.
+	inflight string
+	now      func() time.Time
+
+	// replayMu допускает одно воспроизведение за раз, replaying виден без блокировки.
+	replayMu  sync.Mutex
+	replaying atomic.Bool
+}
+
+// NewSpool открывает спул в каталоге dir и продолжает нумерацию уже лежащих там сегментов.
+// maxGaugeAge <= 0 отключает отбрасывание gauge по возрасту.
+func NewSpool(dir string, maxBytes int64, maxGaugeAge time.Duration) (*Spool, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("spool size limit must be positive, got %d", maxBytes)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spool dir: %w", err)
+	}
+
+	s := &Spool{
+		dir:         dir,
+		maxBytes:    maxBytes,
+		maxGaugeAge: maxGaugeAge,
+		now:         time.Now,
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > 0 {
+		s.seq = segments[len(segments)-1].seq
+	}
+
+	return s, nil
+}
+
+// Put сохраняет пачку отдельным сегментом. Если лимит размера превышен,
+// все сегменты вместе с пачкой сливаются в один; при нехватке места сначала
+// отбрасываются самые старые gauge, счётчики не теряются никогда — вместо этого ErrSpoolFull.
+func (s *Spool) Put(metrics []model.Metrics) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	storedAt := s.now().Unix()
+	records := make([]spoolRecord, 0, len(metrics))
+	for _, metric := range metrics {
+		records = append(records, spoolRecord{Metrics: metric, StoredAt: storedAt})
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to encode spool segment: %w", err)
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+
+	if spoolSize(segments)+int64(len(data)) <= s.maxBytes {
+		return s.writeSegment(data)
+	}
+
+	// Отправляемый сегмент может уже дойти до сервера: слить его с остальными значило бы отправить дважды.
+	limit := s.maxBytes
+	mergeable := make([]spoolSegment, 0, len(segments))
+	for _, segment := range segments {
+		if segment.path == s.inflight {
+			limit -= segment.size
+			continue
+		}
+		mergeable = append(mergeable, segment)
+	}
+
+	return s.compact(mergeable, records, limit)
+}
+
+// Pending сообщает, есть ли недоставленные сегменты или идёт их воспроизведение.
+func (s *Spool) Pending() bool {
+	if s.replaying.Load() {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+	return err != nil || len(segments) > 0
+}
+
+// Replay отправляет сегменты по порядку и удаляет успешно отправленные, пока спул не опустеет.
+// Первая ошибка прерывает воспроизведение, оставшиеся сегменты ждут следующей попытки.
+// Воспроизведение идёт в одной горутине: если оно уже запущено, вызов сразу возвращается,
+// а сегменты, добавленные тем временем, отправит уже идущее воспроизведение.
+func (s *Spool) Replay(send func([]model.Metrics) error) error {
+	if !s.replayMu.TryLock() {
+		return nil
+	}
+	defer s.replayMu.Unlock()
+
+	s.replaying.Store(true)
+	defer s.replaying.Store(false)
+
+	for {
+		segment, records, err := s.next()
+		if err != nil || segment == "" {
+			return err
+		}
+
+		if len(records) > 0 {
+			metrics := make([]model.Metrics, 0, len(records))
+			for _, record := range records {
+				metrics = append(metrics, record.Metrics)
+			}
+			if err := send(metrics); err != nil {
+				unsent := UnsentMetrics(err, metrics)
+				if len(unsent) < len(records) {
+					// Доставленная часть сегмента не должна уйти повторно при следующем воспроизведении.
+					if keepErr := s.keep(segment, records[len(records)-len(unsent):]); keepErr != nil {
+						err = fmt.Errorf("%w (spool: %v)", err, keepErr)
+					}
+				}
+				s.release("")
+				return err
+			}
+		}
+
+		if err := s.release(segment); err != nil {
+			return err
+		}
+	}
+}
+
+// next помечает первый сегмент отправляемым и возвращает его метрики без устаревших gauge.
+// Пустой путь означает, что спул пуст.
+func (s *Spool) next() (string, []spoolRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+	if err != nil {
+		return "", nil, err
+	}
+
+	cutoff := s.gaugeCutoff()
+	for _, segment := range segments {
+		records, err := readSpoolSegment(segment.path)
+		if err != nil {
+			// Недописанный или повреждённый сегмент отправить всё равно не удастся.
+			fmt.Printf("dropping broken spool segment %s: %v\n", segment.path, err)
+			_ = os.Remove(segment.path)
+			continue
+		}
+
+		fresh := make([]spoolRecord, 0, len(records))
+		for _, record := range records {
+			if record.MType == model.Gauge && record.StoredAt < cutoff {
+				continue
+			}
+			fresh = append(fresh, record)
+		}
+
+		s.inflight = segment.path
+		return segment.path, fresh, nil
+	}
+
+	return "", nil, nil
+}
+
+// release снимает отметку отправки; непустой путь означает, что сегмент доставлен и удаляется.
+func (s *Spool) release(delivered string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inflight = ""
+	if delivered == "" {
+		return nil
+	}
+	if err := os.Remove(delivered); err != nil {
+		return fmt.Errorf("failed to remove spool segment: %w", err)
+	}
+
+	return nil
+}
+
+// compact сливает сегменты и свежую пачку в один сегмент размером не больше limit. Новый сегмент пишется
+// до удаления старых: при сбое между этими шагами дельты могут быть отправлены дважды, но не потеряются.
+func (s *Spool) compact(segments []spoolSegment, fresh []spoolRecord, limit int64) error {
+	var records []spoolRecord
+	for _, segment := range segments {
+		stored, err := readSpoolSegment(segment.path)
+		if err != nil {
+			fmt.Printf("dropping broken spool segment %s: %v\n", segment.path, err)
+			continue
+		}
+		records = append(records, stored...)
+	}
+	records = append(records, fresh...)
+
+	merged := mergeSpoolRecords(records, s.gaugeCutoff())
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to encode spool segment: %w", err)
+	}
+
+	for int64(len(data)) > limit {
+		oldest := -1
+		for i, record := range merged {
+			if record.MType == model.Gauge && (oldest < 0 || record.StoredAt < merged[oldest].StoredAt) {
+				oldest = i
+			}
+		}
+		if oldest < 0 {
+			return ErrSpoolFull
+		}
+
+		merged = append(merged[:oldest], merged[oldest+1:]...)
+		if data, err = json.Marshal(merged); err != nil {
+			return fmt.Errorf("failed to encode spool segment: %w", err)
+		}
+	}
+
+	if err := s.writeSegment(data); err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		if err := os.Remove(segment.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove spool segment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// mergeSpoolRecords суммирует дельты счётчиков и оставляет последнее значение gauge,
+// сохраняя порядок первого появления метрики. Gauge старше cutoff отбрасываются.
+func mergeSpoolRecords(records []spoolRecord, cutoff int64) []spoolRecord {
+	merged := make([]spoolRecord, 0, len(records))
+	index := make(map[string]int, len(records))
+
+	for _, record := range records {
+		if record.MType == model.Gauge && record.StoredAt < cutoff {
+			continue
+		}
+
+		key := record.MType + ":" + record.ID
+		i, seen := index[key]
+		if !seen {
+			if record.Delta != nil {
+				delta := *record.Delta
+				record.Delta = &delta
+			}
+			index[key] = len(merged)
+			merged = append(merged, record)
+			continue
+		}
+
+		if record.MType == model.Counter && record.Delta != nil {
+			delta := *record.Delta
+			if merged[i].Delta != nil {
+				delta += *merged[i].Delta
+			}
+			merged[i].Delta = &delta
+			merged[i].StoredAt = record.StoredAt
+			continue
+		}
+
+		merged[i] = record
+	}
+
+	return merged
+}
+
+func (s *Spool) gaugeCutoff() int64 {
+	if s.maxGaugeAge <= 0 {
+		return math.MinInt64
+	}
+
+	return s.now().Add(-s.maxGaugeAge).Unix()
+}
+
+func (s *Spool) segments() ([]spoolSegment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool dir: %w", err)
+	}
+
+	// Имена сегментов дополнены нулями, поэтому порядок ReadDir совпадает с порядком записи.
+	segments := make([]spoolSegment, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spoolSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, spoolSegment{path: filepath.Join(s.dir, name), seq: seq, size: info.Size()})
+	}
+
+	return segments, nil
+}
+
+// keep заменяет отправляемый сегмент его недоставленной частью, сохраняя время записи.
+func (s *Spool) keep(segment string, records []spoolRecord) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to encode spool segment: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.writeFile(segment, data)
+}
+
+func (s *Spool) writeSegment(data []byte) error {
+	s.seq++
+	return s.writeFile(filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.seq, spoolSegmentExt)), data)
+}
+
+// writeFile атомарно записывает сегмент: через временный файл и переименование.
+func (s *Spool) writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(s.dir, ".spool-*")
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func readSpoolSegment(path string) ([]spoolRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []spoolRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func spoolSize(segments []spoolSegment) int64 {
+	var size int64
+	for _, segment := range segments {
+		size += segment.size
+	}
+
+	return size
+}
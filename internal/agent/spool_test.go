@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/GoLessons/go-musthave-metrics/pkg/repeater"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func counter(id string, delta int64) model.Metrics {
+	return *model.NewCounter(id, &delta)
+}
+
+func gauge(id string, value float64) model.Metrics {
+	return *model.NewGauge(id, &value)
+}
+
+func replayAll(t *testing.T, spool *Spool) [][]model.Metrics {
+	t.Helper()
+	var batches [][]model.Metrics
+	require.NoError(t, spool.Replay(func(metrics []model.Metrics) error {
+		batches = append(batches, metrics)
+		return nil
+	}))
+	return batches
+}
+
+func TestSpool_ReplaysInOrderAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewSpool(dir, 1<<20, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", 1)}))
+	require.NoError(t, spool.Put([]model.Metrics{gauge("Alloc", 2)}))
+
+	reopened, err := NewSpool(dir, 1<<20, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Put([]model.Metrics{counter("PollCount", 3)}))
+
+	batches := replayAll(t, reopened)
+	require.Len(t, batches, 3)
+	assert.EqualValues(t, 1, *batches[0][0].Delta)
+	assert.Equal(t, "Alloc", batches[1][0].ID)
+	assert.EqualValues(t, 3, *batches[2][0].Delta)
+
+	assert.Empty(t, replayAll(t, reopened), "replayed segments are removed")
+}
+
+func TestSpool_KeepsSegmentsOnSendFailure(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 1<<20, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", 1)}))
+	require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", 2)}))
+
+	calls := 0
+	err = spool.Replay(func([]model.Metrics) error {
+		calls++
+		if calls == 2 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	require.Error(t, err)
+
+	batches := replayAll(t, spool)
+	require.Len(t, batches, 1)
+	assert.EqualValues(t, 2, *batches[0][0].Delta)
+}
+
+func TestSpool_KeepsOnlyUnsentPartOfSegment(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 1<<20, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, spool.Put([]model.Metrics{counter("c1", 1), counter("c2", 2), counter("c3", 3)}))
+
+	// Отправка по одной прервалась на c2: c1 уже учтён сервером.
+	sender := &flakySenderMock{failID: "c2", failures: 1}
+	require.Error(t, spool.Replay(func(metrics []model.Metrics) error {
+		return sendOnce(sender, false, metrics)
+	}))
+
+	batches := replayAll(t, spool)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 2)
+	assert.Equal(t, "c2", batches[0][0].ID)
+	assert.Equal(t, "c3", batches[0][1].ID)
+}
+
+func TestSpool_ReplayDoesNotBlockPut(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 1<<20, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", 1)}))
+
+	var deltas []int64
+	require.NoError(t, spool.Replay(func(metrics []model.Metrics) error {
+		deltas = append(deltas, *metrics[0].Delta)
+		if len(deltas) == 1 {
+			// Запись в спул во время отправки не ждёт её окончания и отправляется тем же воспроизведением.
+			require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", 2)}))
+			assert.NoError(t, spool.Replay(func([]model.Metrics) error {
+				t.Fatal("concurrent replay must not send")
+				return nil
+			}))
+		}
+		return nil
+	}))
+
+	assert.Equal(t, []int64{1, 2}, deltas)
+	assert.False(t, spool.Pending())
+}
+
+func TestSpool_CompactionSkipsInflightSegment(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 400, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", 1)}))
+
+	var total int64
+	require.NoError(t, spool.Replay(func(metrics []model.Metrics) error {
+		for _, metric := range metrics {
+			total += *metric.Delta
+		}
+		if total == 1 {
+			for i := 2; i <= 20; i++ {
+				require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", int64(i))}))
+			}
+		}
+		return nil
+	}))
+
+	assert.EqualValues(t, 210, total, "segment being sent is neither lost nor sent twice")
+}
+
+func TestSpool_MergesCountersWhenFull(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewSpool(dir, 400, time.Hour)
+	require.NoError(t, err)
+
+	for i := 1; i <= 20; i++ {
+		require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", int64(i)), gauge("Alloc", float64(i))}))
+	}
+
+	segments, err := spool.segments()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, spoolSize(segments), int64(400))
+
+	var total int64
+	var alloc float64
+	for _, batch := range replayAll(t, spool) {
+		for _, metric := range batch {
+			switch metric.ID {
+			case "PollCount":
+				total += *metric.Delta
+			case "Alloc":
+				alloc = *metric.Value
+			}
+		}
+	}
+	assert.EqualValues(t, 210, total, "no counter increments are lost")
+	assert.EqualValues(t, 20, alloc, "latest gauge value wins")
+}
+
+func TestSpool_DropsOldGauges(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 1<<20, time.Minute)
+	require.NoError(t, err)
+	now := time.Now()
+	spool.now = func() time.Time { return now }
+
+	require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", 5), gauge("Alloc", 1)}))
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, spool.Put([]model.Metrics{gauge("HeapInuse", 2)}))
+
+	batches := replayAll(t, spool)
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], 1)
+	assert.Equal(t, "PollCount", batches[0][0].ID)
+	assert.Equal(t, "HeapInuse", batches[1][0].ID)
+}
+
+func TestSpool_RejectsCountersBeyondLimit(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewSpool(dir, 64, time.Hour)
+	require.NoError(t, err)
+
+	err = spool.Put([]model.Metrics{counter("PollCount", 1), counter("AnotherCounter", 1)})
+	assert.ErrorIs(t, err, ErrSpoolFull)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSendWithSpool_BuffersUntilServerRecovers(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 1<<20, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", 1)}))
+
+	down := &batchSenderMock{batchErr: errors.New("dial tcp: connection refused")}
+	require.NoError(t, sendWithSpool(context.Background(), spool, nil, nil, down, true, []model.Metrics{counter("PollCount", 2)}))
+	assert.Equal(t, 1, down.batchCalls, "fresh batch is spooled without retries while server is down")
+
+	up := &batchSenderMock{}
+	require.NoError(t, sendWithSpool(context.Background(), spool, nil, nil, up, true, []model.Metrics{counter("PollCount", 3)}))
+	require.Equal(t, 3, up.batchCalls)
+	require.Len(t, up.sent, 3)
+	for i, metric := range up.sent {
+		assert.EqualValues(t, i+1, *metric.Delta)
+	}
+
+	assert.Empty(t, replayAll(t, spool))
+}
+
+func TestSendWithSpool_FreshBatchWaitsBehindSpooled(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 1<<20, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, spool.Put([]model.Metrics{counter("PollCount", 1)}))
+
+	sender := &batchSenderMock{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	sender.onBatch = func(call int) {
+		if call == 1 {
+			close(started)
+			<-release
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sendWithSpool(context.Background(), spool, nil, nil, sender, true, []model.Metrics{counter("PollCount", 2)})
+	}()
+	<-started
+
+	// Пока идёт воспроизведение, параллельная отправка не обгоняет его, а встаёт в спул.
+	require.NoError(t, sendWithSpool(context.Background(), spool, nil, nil, sender, true, []model.Metrics{counter("PollCount", 3)}))
+	close(release)
+	require.NoError(t, <-done)
+
+	require.Len(t, sender.sent, 3)
+	for i, metric := range sender.sent {
+		assert.EqualValues(t, i+1, *metric.Delta)
+	}
+	assert.False(t, spool.Pending())
+}
+
+func TestSendWithSpool_SpoolsWhileCircuitOpen(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 1<<20, time.Hour)
+	require.NoError(t, err)
+
+	breaker := repeater.NewCircuitBreaker(1, time.Hour, nil)
+	_, _ = breaker.Wrap(func() (any, error) { return nil, errors.New("connection refused") })()
+	require.Equal(t, repeater.StateOpen, breaker.State())
+
+	sender := &batchSenderMock{}
+	require.NoError(t, sendWithSpool(context.Background(), spool, breaker, nil, sender, true, []model.Metrics{counter("PollCount", 1)}))
+	assert.Zero(t, sender.batchCalls, "open circuit must not reach the server")
+
+	batches := replayAll(t, spool)
+	require.Len(t, batches, 1)
+	assert.EqualValues(t, 1, *batches[0][0].Delta)
+}
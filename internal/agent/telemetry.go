@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+)
+
+// Telemetry собирает метрики работы самого агента и отдаёт их как обычный читатель,
+// поэтому они уходят на сервер вместе с остальными. Counter копятся до Reset,
+// gauge хранят последнее значение. Методы безопасны для nil.
+type Telemetry struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	fetched  map[string]int64
+	gauges   map[string]float64
+}
+
+func NewTelemetry() *Telemetry {
+	return &Telemetry{counters: map[string]int64{}, fetched: map[string]int64{}, gauges: map[string]float64{}}
+}
+
+func (t *Telemetry) Count(name string, delta int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counters[name] += delta
+}
+
+func (t *Telemetry) Gauge(name string, value float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.gauges[name] = value
+}
+
+// ObserveReader учитывает длительность опроса читателя и его ошибки.
+func (t *Telemetry) ObserveReader(name string, took time.Duration, err error) {
+	suffix := telemetrySuffix(name)
+	t.Gauge("AgentReaderRefreshMs_"+suffix, float64(took.Microseconds())/1000)
+	if err != nil {
+		t.Count("AgentReaderErrors_"+suffix, 1)
+	}
+}
+
+// Sender возвращает учёт отправки для одного получателя.
+func (t *Telemetry) Sender(destination string) *SenderStats {
+	if t == nil {
+		return nil
+	}
+
+	return &SenderStats{telemetry: t, suffix: telemetrySuffix(destination)}
+}
+
+func (t *Telemetry) Name() string {
+	return "telemetry"
+}
+
+func (t *Telemetry) Refresh() error {
+	return nil
+}
+
+func (t *Telemetry) Fetch() ([]model.Metrics, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	metrics := make([]model.Metrics, 0, len(t.counters)+len(t.gauges))
+	for _, name := range sortedKeys(t.gauges) {
+		value := t.gauges[name]
+		metrics = append(metrics, *model.NewGauge(name, &value))
+	}
+	for _, name := range sortedKeys(t.counters) {
+		delta := t.counters[name]
+		t.fetched[name] = delta
+		metrics = append(metrics, *model.NewCounter(name, &delta))
+	}
+
+	return metrics, nil
+}
+
+// Reset вычитает выданное последним Fetch: события, учтённые после него, не теряются.
+func (t *Telemetry) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name, delta := range t.fetched {
+		t.counters[name] -= delta
+	}
+	clear(t.fetched)
+}
+
+// SenderStats — учёт отправки одного получателя; имена метрик получают суффикс с его именем.
+type SenderStats struct {
+	telemetry *Telemetry
+	suffix    string
+}
+
+func (s *SenderStats) count(name string, delta int64) {
+	if s == nil {
+		return
+	}
+	s.telemetry.Count(name+"_"+s.suffix, delta)
+}
+
+func (s *SenderStats) gauge(name string, value float64) {
+	if s == nil {
+		return
+	}
+	s.telemetry.Gauge(name+"_"+s.suffix, value)
+}
+
+// BatchSent учитывает доставленную пачку и время её отправки вместе с повторами.
+func (s *SenderStats) BatchSent(took time.Duration) {
+	s.count("AgentBatchesSent", 1)
+	s.gauge("AgentSendLatencyMs", float64(took.Microseconds())/1000)
+}
+
+func (s *SenderStats) BatchFailed() {
+	s.count("AgentBatchesFailed", 1)
+}
+
+func (s *SenderStats) BatchRetried() {
+	s.count("AgentBatchRetries", 1)
+}
+
+// BatchDropped учитывает пачку, потерянную безвозвратно, — отвергнутую сервером.
+func (s *SenderStats) BatchDropped() {
+	s.count("AgentBatchesDropped", 1)
+}
+
+func (s *SenderStats) BatchSpooled() {
+	s.count("AgentBatchesSpooled", 1)
+}
+
+// Bytes учитывает размер запроса до сжатия и шифрования и фактически отправленный.
+func (s *SenderStats) Bytes(raw, sent int) {
+	s.count("AgentBytesRaw", int64(raw))
+	s.count("AgentBytesSent", int64(sent))
+}
+
+func (s *SenderStats) QueueDepth(depth int) {
+	s.gauge("AgentQueueDepth", float64(depth))
+}
+
+func telemetrySuffix(name string) string {
+	suffix := strings.Trim(strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name), "_")
+	if suffix == "" {
+		return "default"
+	}
+
+	return suffix
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func telemetryByID(t *testing.T, telemetry *Telemetry) map[string]model.Metrics {
+	t.Helper()
+	metrics, err := telemetry.Fetch()
+	require.NoError(t, err)
+	byID := make(map[string]model.Metrics, len(metrics))
+	for _, metric := range metrics {
+		byID[metric.ID] = metric
+	}
+	return byID
+}
+
+func runWorker(t *testing.T, sender Sender, stats *SenderStats, reports ...Report) {
+	t.Helper()
+	sendChan := make(chan Report, len(reports))
+	for _, report := range reports {
+		sendChan <- report
+	}
+	close(sendChan)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	SenderWorker(context.Background(), sendChan, sender, 0, true, nil, stats, &wg)
+	wg.Wait()
+}
+
+func TestTelemetry_CountsSentAndDroppedBatches(t *testing.T) {
+	telemetry := NewTelemetry()
+	stats := telemetry.Sender("localhost:8080")
+
+	runWorker(t, &batchSenderMock{}, stats, NewReport([]model.Metrics{counter("PollCount", 1)}))
+	rejecting := &batchSenderMock{batchErr: NewSendError(http.StatusBadRequest, "bad metric")}
+	runWorker(t, rejecting, stats, NewReport([]model.Metrics{counter("PollCount", 1)}))
+
+	byID := telemetryByID(t, telemetry)
+	assert.EqualValues(t, 1, *byID["AgentBatchesSent_localhost_8080"].Delta)
+	assert.EqualValues(t, 1, *byID["AgentBatchesFailed_localhost_8080"].Delta)
+	assert.EqualValues(t, 1, *byID["AgentBatchesDropped_localhost_8080"].Delta)
+	assert.Contains(t, byID, "AgentSendLatencyMs_localhost_8080")
+	assert.Contains(t, byID, "AgentQueueDepth_localhost_8080")
+
+	telemetry.Reset()
+	assert.EqualValues(t, 0, *telemetryByID(t, telemetry)["AgentBatchesSent_localhost_8080"].Delta)
+}
+
+func TestTelemetry_RetriableFailureIsKeptAndRetried(t *testing.T) {
+	telemetry := NewTelemetry()
+	agg, err := NewAggregator(GaugeLast, nil)
+	require.NoError(t, err)
+	agg.Add(counter("PollCount", 4))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sender := &batchSenderMock{batchErr: NewSendError(http.StatusServiceUnavailable, "down")}
+	go func() {
+		// После первого повтора завершение агента прерывает отправку.
+		for sender.calls() < 2 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+	}()
+
+	sendChan := make(chan Report, 1)
+	sendChan <- agg.Snapshot()
+	close(sendChan)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	SenderWorker(ctx, sendChan, sender, 0, true, nil, telemetry.Sender("main"), &wg)
+	wg.Wait()
+
+	byID := telemetryByID(t, telemetry)
+	assert.GreaterOrEqual(t, *byID["AgentBatchRetries_main"].Delta, int64(1))
+	assert.EqualValues(t, 1, *byID["AgentBatchesFailed_main"].Delta)
+	assert.NotContains(t, byID, "AgentBatchesDropped_main")
+
+	report := agg.Snapshot()
+	require.Len(t, report.Metrics, 1)
+	assert.EqualValues(t, 4, *report.Metrics[0].Delta, "failed report goes back to the aggregator")
+}
+
+func TestJSONSender_CountsBytesBeforeAndAfterGzip(t *testing.T) {
+	var received int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = len(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	telemetry := NewTelemetry()
+	sender := NewJSONSender(ts.Listener.Addr().String(), true, nil, nil).WithStats(telemetry.Sender("main"))
+	defer sender.Close()
+
+	metrics := make([]model.Metrics, 0, 50)
+	for i := 0; i < 50; i++ {
+		metrics = append(metrics, counter("PollCount", 1))
+	}
+	require.NoError(t, sender.SendBatch(metrics))
+
+	byID := telemetryByID(t, telemetry)
+	assert.EqualValues(t, received, *byID["AgentBytesSent_main"].Delta)
+	assert.Greater(t, *byID["AgentBytesRaw_main"].Delta, *byID["AgentBytesSent_main"].Delta)
+}
@@ -1,6 +1,9 @@
 package agent
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"sync"
 	"testing"
 
@@ -22,20 +25,88 @@ func (m *simpleSenderMock) Send(metric model.Metrics) error {
 }
 func (m *simpleSenderMock) Close() { m.closed = true }
 
+func (m *simpleSenderMock) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
 type batchSenderMock struct {
 	simpleSenderMock
 	batchCalls int
 	batchErr   error
+	// onBatch вызывается после учёта пачки с номером вызова и может придержать отправку.
+	onBatch func(call int)
 }
 
 func (m *batchSenderMock) SendBatch(metrics []model.Metrics) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.batchCalls++
+	call := m.batchCalls
 	m.sent = append(m.sent, metrics...)
+	m.mu.Unlock()
+
+	if m.onBatch != nil {
+		m.onBatch(call)
+	}
 	return m.batchErr
 }
 
+// flakySenderMock отклоняет метрику failID первые failures раз.
+type flakySenderMock struct {
+	simpleSenderMock
+	failID   string
+	failures int
+}
+
+func (m *flakySenderMock) Send(metric model.Metrics) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if metric.ID == m.failID && m.failures > 0 {
+		m.failures--
+		return errors.New("dial tcp: connection refused")
+	}
+	m.sent = append(m.sent, metric)
+	return nil
+}
+
+func TestHandleSingleMode_RetriesOnlyUnsent(t *testing.T) {
+	metrics := []model.Metrics{counter("c1", 1), counter("c2", 2), counter("c3", 3)}
+
+	sender := &flakySenderMock{failID: "c2", failures: 1}
+	if err := handleSingleMode(context.Background(), sender, nil, nil, metrics); err != nil {
+		t.Fatalf("handleSingleMode returned error: %v", err)
+	}
+
+	if len(sender.sent) != len(metrics) {
+		t.Fatalf("expected each metric delivered once, got %d deliveries", len(sender.sent))
+	}
+	for i, metric := range sender.sent {
+		if metric.ID != metrics[i].ID {
+			t.Fatalf("expected %s at %d, got %s", metrics[i].ID, i, metric.ID)
+		}
+	}
+}
+
+func TestSendOnce_ReportsUnsentTail(t *testing.T) {
+	metrics := []model.Metrics{counter("c1", 1), counter("c2", 2), counter("c3", 3)}
+
+	err := sendOnce(&flakySenderMock{failID: "c2", failures: 1}, false, metrics)
+	unsent := UnsentMetrics(err, metrics)
+	if len(unsent) != 2 || unsent[0].ID != "c2" {
+		t.Fatalf("expected c2 and c3 unsent, got %v", unsent)
+	}
+
+	err = sendOnce(&flakySenderMock{failID: "c1", failures: 1}, false, metrics)
+	var partial *UnsentError
+	if errors.As(err, &partial) {
+		t.Fatalf("nothing was delivered, expected plain error, got %v", err)
+	}
+	if len(UnsentMetrics(err, metrics)) != len(metrics) {
+		t.Fatal("expected the whole batch unsent")
+	}
+}
+
 func TestHandleSingleMode_Success(t *testing.T) {
 	val := 42.0
 	metrics := []model.Metrics{
@@ -44,7 +115,7 @@ func TestHandleSingleMode_Success(t *testing.T) {
 	}
 
 	sender := &simpleSenderMock{}
-	if err := handleSingleMode(sender, metrics); err != nil {
+	if err := handleSingleMode(context.Background(), sender, nil, nil, metrics); err != nil {
 		t.Fatalf("handleSingleMode returned error: %v", err)
 	}
 
@@ -62,7 +133,7 @@ func TestHandleBatchMode_UsesBatchWhenAvailable(t *testing.T) {
 	}
 
 	sender := &batchSenderMock{}
-	if err := handleBatchMode(sender, metrics); err != nil {
+	if err := handleBatchMode(context.Background(), sender, nil, nil, metrics); err != nil {
 		t.Fatalf("handleBatchMode returned error: %v", err)
 	}
 
@@ -82,7 +153,7 @@ func TestHandleBatchMode_FallbackToSingle(t *testing.T) {
 	}
 
 	sender := &simpleSenderMock{}
-	if err := handleBatchMode(sender, metrics); err != nil {
+	if err := handleBatchMode(context.Background(), sender, nil, nil, metrics); err != nil {
 		t.Fatalf("handleBatchMode returned error: %v", err)
 	}
 
@@ -90,3 +161,34 @@ func TestHandleBatchMode_FallbackToSingle(t *testing.T) {
 		t.Fatalf("expected %d metrics sent by fallback, got %d", len(metrics), len(sender.sent))
 	}
 }
+
+func TestSenderWorker_RejectedBatchIsNotReturnedToAggregator(t *testing.T) {
+	sender := &batchSenderMock{batchErr: &SendError{Msg: "bad request", Code: http.StatusBadRequest}}
+
+	var (
+		called bool
+		result error
+	)
+	reports := make(chan Report, 1)
+	reports <- Report{
+		Metrics: []model.Metrics{counter("PollCount", 1)},
+		done: func(err error) {
+			called, result = true, err
+		},
+	}
+	close(reports)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	SenderWorker(context.Background(), reports, sender, 0, true, nil, nil, &wg)
+
+	if !called {
+		t.Fatalf("report must be completed")
+	}
+	if result != nil {
+		t.Fatalf("rejected batch must not be merged back for resending, got %v", result)
+	}
+	if sender.batchCalls != 1 {
+		t.Fatalf("rejected batch must not be retried, got %d calls", sender.batchCalls)
+	}
+}
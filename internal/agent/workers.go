@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -10,12 +11,20 @@ import (
 	"github.com/GoLessons/go-musthave-metrics/pkg/repeater"
 )
 
+const (
+	// breakerThreshold — число неудачных попыток подряд, после которого отправка приостанавливается.
+	breakerThreshold = 3
+	breakerCooldown  = 30 * time.Second
+)
+
 func SenderWorker(
 	ctx context.Context,
-	sendChan <-chan []model.Metrics,
+	sendChan <-chan Report,
 	sender Sender,
 	rateLimit int,
 	batch bool,
+	spool *Spool,
+	stats *SenderStats,
 	wg *sync.WaitGroup,
 ) {
 	defer wg.Done()
@@ -26,7 +35,10 @@ func SenderWorker(
 		semaphore = make(chan struct{}, rateLimit)
 	}
 
-	sendMetrics := func(metricsToSend []model.Metrics) {
+	// Размыкатель общий для всех отправок воркера: пока сервер недоступен, пачки не ждут таймаутов.
+	breaker := repeater.NewCircuitBreaker(breakerThreshold, breakerCooldown, NewAgentErrorClassifier().IsRetriable)
+
+	sendMetrics := func(report Report) {
 		defer activeRequests.Done()
 		defer func() {
 			if semaphore != nil {
@@ -34,16 +46,22 @@ func SenderWorker(
 			}
 		}()
 
-		var err error
-		if batch {
-			err = handleBatchMode(sender, metricsToSend)
-		} else {
-			err = handleSingleMode(sender, metricsToSend)
+		err := sendWithSpool(ctx, spool, breaker, stats, sender, batch, report.Metrics)
+		if err == nil {
+			report.Done(nil)
+			return
 		}
 
-		if err != nil {
-			fmt.Printf("metrics sending failed: %v\n", err)
+		fmt.Printf("metrics sending failed: %v\n", err)
+		stats.BatchFailed()
+		if shouldSpool(NewAgentErrorClassifier(), err) {
+			// Агрегатор вернёт значения себе, они уйдут со следующим отчётом.
+			report.Done(err)
+			return
 		}
+		// Отвергнутая сервером пачка не станет лучше от повторной отправки.
+		stats.BatchDropped()
+		report.Done(nil)
 	}
 
 	for {
@@ -51,85 +69,258 @@ func SenderWorker(
 		case <-ctx.Done():
 			activeRequests.Wait()
 			return
-		case metrics, ok := <-sendChan:
+		case report, ok := <-sendChan:
 			if !ok {
 				activeRequests.Wait()
 				return
 			}
+			stats.QueueDepth(len(sendChan))
 
 			if semaphore != nil {
 				select {
 				case semaphore <- struct{}{}:
 				case <-ctx.Done():
+					report.Done(ctx.Err())
 					activeRequests.Wait()
 					return
 				}
 			}
 
 			activeRequests.Add(1)
-			go sendMetrics(metrics)
+			go sendMetrics(report)
 		}
 	}
 }
 
-func handleBatchMode(sender Sender, metrics []model.Metrics) error {
-	try := repeater.NewRepeater(func(err error) {
-		fmt.Printf("Ошибка отправки пакета метрик: %v\n", err)
+// sendWithSpool отправляет пачку так, чтобы сервер получал значения в порядке их снятия.
+// Пока в спуле есть недоставленные пачки, свежая встаёт за ними и уходит при воспроизведении:
+// иначе при RATE_LIMIT > 1 она могла бы обогнать старые. Если сервер по-прежнему недоступен,
+// пачка откладывается в спул без повторных попыток.
+func sendWithSpool(
+	ctx context.Context,
+	spool *Spool,
+	breaker *repeater.CircuitBreaker,
+	stats *SenderStats,
+	sender Sender,
+	batch bool,
+	metrics []model.Metrics,
+) error {
+	if spool == nil {
+		return sendWithRetry(ctx, breaker, stats, sender, batch, metrics)
+	}
+
+	classifier := NewAgentErrorClassifier()
+	if spool.Pending() {
+		// Переполненный спул не примет пачку, тогда она уходит напрямую, как без спула.
+		if err := spool.Put(metrics); err == nil {
+			if err := replaySpool(spool, breaker, stats, sender, batch, classifier); err != nil {
+				fmt.Printf("metrics spooled until server recovers: %v\n", err)
+				stats.BatchSpooled()
+			}
+			return nil
+		}
+	}
+
+	err := sendWithRetry(ctx, breaker, stats, sender, batch, metrics)
+	if err == nil || !shouldSpool(classifier, err) {
+		return err
+	}
+
+	// Уже доставленные по одной метрики в спул не попадают, иначе их приросты учлись бы дважды.
+	if spoolErr := spool.Put(UnsentMetrics(err, metrics)); spoolErr != nil {
+		return fmt.Errorf("%w (spool: %v)", err, spoolErr)
+	}
+	fmt.Printf("metrics spooled until server recovers: %v\n", err)
+	stats.BatchSpooled()
+
+	return nil
+}
+
+// replaySpool досылает накопленное в спуле одной попыткой на сегмент; при недоступности сервера
+// сегменты остаются до следующего отчёта.
+func replaySpool(
+	spool *Spool,
+	breaker *repeater.CircuitBreaker,
+	stats *SenderStats,
+	sender Sender,
+	batch bool,
+	classifier *agentErrorClassifier,
+) error {
+	return spool.Replay(func(spooled []model.Metrics) error {
+		started := time.Now()
+		_, err := breaker.Wrap(func() (any, error) {
+			return nil, sendOnce(sender, batch, spooled)
+		})()
+		if err == nil {
+			stats.BatchSent(time.Since(started))
+		}
+		if err != nil && !classifier.IsRetriable(err) && !errors.Is(err, repeater.ErrCircuitOpen) {
+			// Отвергнутую сервером пачку бессмысленно хранить: она заблокирует остальные.
+			fmt.Printf("spooled metrics rejected, dropping: %v\n", err)
+			stats.BatchDropped()
+			return nil
+		}
+		return err
 	})
-	repeatStrategy := createRetryStrategy()
-	_, err := try.Repeat(
-		repeatStrategy,
-		func() (any, error) {
-			return nil, sendMetricsBatch(sender, metrics)
-		},
-	)
+}
+
+// shouldSpool отбирает ошибки, после которых пачку стоит отправить позже:
+// сервер недоступен, цепь разомкнута или отправку прервало завершение агента.
+func shouldSpool(classifier *agentErrorClassifier, err error) bool {
+	return classifier.IsRetriable(err) ||
+		errors.Is(err, repeater.ErrCircuitOpen) ||
+		errors.Is(err, context.Canceled)
+}
+
+// sendWithRetry отправляет пачку с повторами и учитывает время доставки вместе с паузами.
+func sendWithRetry(ctx context.Context, breaker *repeater.CircuitBreaker, stats *SenderStats, sender Sender, batch bool, metrics []model.Metrics) error {
+	started := time.Now()
+	var err error
+	if batch {
+		err = handleBatchMode(ctx, sender, breaker, stats, metrics)
+	} else {
+		err = handleSingleMode(ctx, sender, breaker, stats, metrics)
+	}
+	if err == nil {
+		stats.BatchSent(time.Since(started))
+	}
+
+	return err
+}
+
+// UnsentError — отправка по одной прервалась, когда часть пачки уже дошла до сервера;
+// Unsent — метрики, которые доставить не удалось.
+type UnsentError struct {
+	Unsent []model.Metrics
+	err    error
+}
+
+func (e *UnsentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *UnsentError) Unwrap() error {
+	return e.err
+}
+
+// UnsentMetrics возвращает недоставленную часть metrics: если ошибка сообщает о частичной отправке —
+// только её, иначе всю пачку.
+func UnsentMetrics(err error, metrics []model.Metrics) []model.Metrics {
+	var unsent *UnsentError
+	if errors.As(err, &unsent) {
+		return unsent.Unsent
+	}
+
+	return metrics
+}
+
+// withUnsent дополняет ошибку списком недоставленных метрик, если часть пачки уже доставлена.
+func withUnsent(err error, unsent []model.Metrics, total int) error {
+	if len(unsent) == total {
+		return err
+	}
+
+	return &UnsentError{Unsent: unsent, err: err}
+}
+
+func sendOnce(sender Sender, batch bool, metrics []model.Metrics) error {
+	var unsent []model.Metrics
+	var err error
+	if batch {
+		unsent, err = sendMetricsBatch(sender, metrics)
+	} else {
+		unsent, err = sendMetricsByOne(sender, metrics)
+	}
 	if err != nil {
-		return fmt.Errorf("can't send metrics batch after retries: %w", err)
+		return withUnsent(err, unsent, len(metrics))
 	}
 
 	return nil
 }
 
-func handleSingleMode(sender Sender, metrics []model.Metrics) error {
+func handleBatchMode(ctx context.Context, sender Sender, breaker *repeater.CircuitBreaker, stats *SenderStats, metrics []model.Metrics) error {
+	return sendRemaining(ctx, breaker, stats, metrics, func(remaining []model.Metrics) ([]model.Metrics, error) {
+		return sendMetricsBatch(sender, remaining)
+	})
+}
+
+func handleSingleMode(ctx context.Context, sender Sender, breaker *repeater.CircuitBreaker, stats *SenderStats, metrics []model.Metrics) error {
+	return sendRemaining(ctx, breaker, stats, metrics, func(remaining []model.Metrics) ([]model.Metrics, error) {
+		return sendMetricsByOne(sender, remaining)
+	})
+}
+
+// sendRemaining повторяет отправку с повторами; каждая попытка досылает только то, что не дошло до сервера
+// в предыдущих, поэтому приросты счётчиков не отправляются дважды.
+func sendRemaining(
+	ctx context.Context,
+	breaker *repeater.CircuitBreaker,
+	stats *SenderStats,
+	metrics []model.Metrics,
+	send func([]model.Metrics) ([]model.Metrics, error),
+) error {
 	try := repeater.NewRepeater(func(err error) {
 		fmt.Printf("Ошибка отправки пакета метрик: %v\n", err)
 	})
 	repeatStrategy := createRetryStrategy()
 
-	_, err := try.Repeat(
+	remaining := metrics
+	_, err := try.RepeatContext(
+		ctx,
 		repeatStrategy,
-		func() (any, error) {
-			return nil, sendMetricsByOne(sender, metrics)
-		},
+		breaker.Wrap(countRetries(stats, func() (any, error) {
+			unsent, err := send(remaining)
+			if err != nil {
+				remaining = unsent
+			}
+			return nil, err
+		})),
 	)
 	if err != nil {
-		return fmt.Errorf("can't send metrics batch after retries: %w", err)
+		return withUnsent(fmt.Errorf("can't send metrics batch after retries: %w", err), remaining, len(metrics))
 	}
 
 	return nil
 }
 
-func sendMetricsBatch(sender Sender, metrics []model.Metrics) error {
+// countRetries учитывает каждую попытку отправки, кроме первой.
+func countRetries(stats *SenderStats, action repeater.Action) repeater.Action {
+	first := true
+	return func() (any, error) {
+		if !first {
+			stats.BatchRetried()
+		}
+		first = false
+		return action()
+	}
+}
+
+// sendMetricsBatch отправляет пачку одним запросом, если отправитель это умеет; при ошибке возвращает недоставленное.
+func sendMetricsBatch(sender Sender, metrics []model.Metrics) ([]model.Metrics, error) {
 	if batchSender, ok := sender.(BatchSender); ok {
-		return batchSender.SendBatch(metrics)
+		if err := batchSender.SendBatch(metrics); err != nil {
+			return metrics, err
+		}
+		return nil, nil
 	}
 	return sendMetricsByOne(sender, metrics)
 }
 
-func sendMetricsByOne(sender Sender, metrics []model.Metrics) error {
-	for _, metric := range metrics {
+// sendMetricsByOne отправляет метрики по одной и при ошибке возвращает те, что ещё не доставлены.
+func sendMetricsByOne(sender Sender, metrics []model.Metrics) ([]model.Metrics, error) {
+	for i, metric := range metrics {
 		if err := sender.Send(metric); err != nil {
-			return fmt.Errorf("can't send metric: %s\n%w", metric.ID, err)
+			return metrics[i:], fmt.Errorf("can't send metric: %s\n%w", metric.ID, err)
 		}
 	}
-	return nil
+	return nil, nil
 }
 
 func createRetryStrategy() repeater.Strategy {
-	return repeater.NewFixedDelaysStrategy(
+	return repeater.NewExponentialBackoffStrategy(
 		NewAgentErrorClassifier().IsRetriable,
 		time.Second*1,
-		time.Second*3,
 		time.Second*5,
+		3,
 	)
 }
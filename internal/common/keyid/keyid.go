@@ -0,0 +1,20 @@
+package keyid
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+)
+
+// FromPublicKey вычисляет идентификатор ключа: первые 8 байт SHA-256 от открытого ключа в PKIX DER.
+// Агент и сервер получают одинаковый kid независимо, без отдельной настройки.
+func FromPublicKey(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
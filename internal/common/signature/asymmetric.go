@@ -0,0 +1,168 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/keyid"
+)
+
+// Заголовки подписи ключом агента: идентификатор ключа и сама подпись в base64.
+// Подписывается то же сообщение, что и для HMAC: SignedPayload(timestamp, nonce, тело).
+const (
+	AgentKeyIDHeader     = "X-Agent-Key-Id"
+	AgentSignatureHeader = "X-Agent-Signature"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported signing key type, want Ed25519 or ECDSA")
+
+// KeySigner подписывает запросы закрытым ключом агента (Ed25519 или ECDSA).
+type KeySigner struct {
+	key crypto.Signer
+	kid string
+}
+
+func NewKeySigner(key crypto.Signer) (*KeySigner, error) {
+	switch key.Public().(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	kid, err := keyid.FromPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySigner{key: key, kid: kid}, nil
+}
+
+// LoadKeySigner читает закрытый ключ агента в PEM (PKCS#8 или SEC 1 для ECDSA).
+func LoadKeySigner(path string) (*KeySigner, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", path)
+	}
+
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+
+	return NewKeySigner(signer)
+}
+
+func (s *KeySigner) KeyID() string {
+	return s.kid
+}
+
+func (s *KeySigner) Sign(payload []byte) (string, error) {
+	var sig []byte
+	var err error
+	switch pub := s.key.Public().(type) {
+	case ed25519.PublicKey:
+		sig, err = s.key.Sign(rand.Reader, payload, crypto.Hash(0))
+	case *ecdsa.PublicKey:
+		hash := ecdsaHash(pub.Curve)
+		sig, err = s.key.Sign(rand.Reader, digest(hash, payload), hash)
+	default:
+		return "", ErrUnsupportedKey
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// VerifyKey проверяет подпись из заголовка AgentSignatureHeader открытым ключом агента.
+func VerifyKey(pub crypto.PublicKey, payload []byte, encodedSignature string) bool {
+	sig, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return false
+	}
+
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, sig)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest(ecdsaHash(key.Curve), payload), sig)
+	default:
+		return false
+	}
+}
+
+// ParsePublicKey разбирает открытый ключ агента в PEM (PKIX).
+func ParsePublicKey(b []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("no PEM public key found")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch pub.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return pub, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// ecdsaHash подбирает хеш под размер кривой, как в ES256/ES384/ES512.
+func ecdsaHash(curve elliptic.Curve) crypto.Hash {
+	switch curve.Params().BitSize {
+	case 384:
+		return crypto.SHA384
+	case 521:
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func digest(hash crypto.Hash, payload []byte) []byte {
+	switch hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384(payload)
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(payload)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(payload)
+		return sum[:]
+	}
+}
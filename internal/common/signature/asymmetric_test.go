@@ -0,0 +1,61 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySigner_SignAndVerify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	for name, key := range map[string]crypto.Signer{"ed25519": edKey, "ecdsa-p384": ecKey} {
+		t.Run(name, func(t *testing.T) {
+			signer, err := NewKeySigner(key)
+			require.NoError(t, err)
+			assert.Len(t, signer.KeyID(), 16)
+
+			sig, err := signer.Sign([]byte("payload"))
+			require.NoError(t, err)
+
+			assert.True(t, VerifyKey(key.Public(), []byte("payload"), sig))
+			assert.False(t, VerifyKey(key.Public(), []byte("tampered"), sig))
+			assert.False(t, VerifyKey(key.Public(), []byte("payload"), "not base64"))
+		})
+	}
+}
+
+func TestLoadKeySigner_PKCS8(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "agent.key")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	signer, err := LoadKeySigner(path)
+	require.NoError(t, err)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	parsed, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	require.NoError(t, err)
+
+	sig, err := signer.Sign([]byte("payload"))
+	require.NoError(t, err)
+	assert.True(t, VerifyKey(parsed, []byte("payload"), sig))
+}
@@ -0,0 +1,34 @@
+package signature
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Заголовки с меткой времени и nonce; оба входят в подпись, поэтому их нельзя подменить.
+const (
+	TimestampHeader = "X-Signature-Timestamp"
+	NonceHeader     = "X-Signature-Nonce"
+)
+
+// SignedPayload склеивает метку времени, nonce и тело в сообщение для HMAC.
+func SignedPayload(timestamp string, nonce string, body []byte) []byte {
+	payload := make([]byte, 0, len(timestamp)+len(nonce)+len(body)+2)
+	payload = append(payload, timestamp...)
+	payload = append(payload, '\n')
+	payload = append(payload, nonce...)
+	payload = append(payload, '\n')
+	return append(payload, body...)
+}
+
+// NewNonce возвращает метку времени в секундах Unix и случайный nonce для очередного запроса.
+func NewNonce(now time.Time) (timestamp string, nonce string, err error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	return strconv.FormatInt(now.Unix(), 10), hex.EncodeToString(buf), nil
+}
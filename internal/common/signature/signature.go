@@ -4,9 +4,11 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"sync"
 )
 
 type Signer struct {
+	mu  sync.RWMutex
 	key string
 }
 
@@ -14,8 +16,23 @@ func NewSign(secret string) *Signer {
 	return &Signer{key: secret}
 }
 
+// SetKey меняет секрет; запросы, проверяемые в этот момент, используют прежний или новый секрет целиком.
+func (sign *Signer) SetKey(secret string) {
+	sign.mu.Lock()
+	defer sign.mu.Unlock()
+
+	sign.key = secret
+}
+
+func (sign *Signer) secret() []byte {
+	sign.mu.RLock()
+	defer sign.mu.RUnlock()
+
+	return []byte(sign.key)
+}
+
 func (sign *Signer) Hash(message []byte) (hash string, err error) {
-	h := hmac.New(sha256.New, []byte(sign.key))
+	h := hmac.New(sha256.New, sign.secret())
 
 	_, err = h.Write(message)
 	if err != nil {
@@ -27,7 +44,7 @@ func (sign *Signer) Hash(message []byte) (hash string, err error) {
 }
 
 func (sign *Signer) Check(hash string, message []byte) bool {
-	h := hmac.New(sha256.New, []byte(sign.key))
+	h := hmac.New(sha256.New, sign.secret())
 	_, err := h.Write(message)
 	if err != nil {
 		return false
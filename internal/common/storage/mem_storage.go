@@ -42,6 +42,13 @@ func (s *MemStorage[T]) Unset(key string) error {
 	return nil
 }
 
+func (s *MemStorage[T]) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.container)
+}
+
 func (s *MemStorage[T]) GetAll() (map[string]T, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
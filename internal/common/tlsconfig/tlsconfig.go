@@ -0,0 +1,258 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkInterval ограничивает частоту проверки файлов на изменение.
+const checkInterval = time.Second
+
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// CAFile на сервере — пакет CA для проверки клиентских сертификатов (включает mTLS),
+	// у клиента — корневые CA для проверки сервера (по умолчанию системные).
+	CAFile     string
+	ServerName string
+}
+
+// Reloader держит актуальные сертификат и пакет CA, перечитывая их при изменении файлов.
+// Проверка выполняется лениво при очередном рукопожатии, не чаще checkInterval.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	stamps    map[string]fileStamp
+	lastCheck time.Time
+	now       func() time.Time
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func NewReloader(certFile string, keyFile string, caFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		now:      time.Now,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.reloadIfChanged()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+func (r *Reloader) CertPool() *x509.CertPool {
+	r.reloadIfChanged()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
+
+func (r *Reloader) load() error {
+	stamps := make(map[string]fileStamp, 3)
+	for _, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	var cert *tls.Certificate
+	if r.certFile != "" || r.keyFile != "" {
+		pair, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %w", err)
+		}
+		cert = &pair
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read ca bundle: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = cert
+	r.pool = pool
+	r.stamps = stamps
+	r.lastCheck = r.now()
+
+	return nil
+}
+
+// reloadIfChanged перечитывает файлы, если изменились их время модификации или размер.
+// При ошибке чтения (например, файл записан наполовину) остаются прежние сертификаты.
+func (r *Reloader) reloadIfChanged() {
+	r.mu.Lock()
+	now := r.now()
+	if now.Sub(r.lastCheck) < checkInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = now
+	stamps := r.stamps
+	r.mu.Unlock()
+
+	changed := false
+	for path, stamp := range stamps {
+		info, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		if !info.ModTime().Equal(stamp.modTime) || info.Size() != stamp.size {
+			changed = true
+		}
+	}
+
+	if changed {
+		_ = r.load()
+	}
+}
+
+// NewServerConfig собирает TLS-конфигурацию сервера; при заданном CAFile клиент обязан предъявить сертификат.
+func NewServerConfig(opts Options) (*tls.Config, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, fmt.Errorf("tls certificate and key are required")
+	}
+
+	reloader, err := NewReloader(opts.CertFile, opts.KeyFile, opts.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return reloader.Certificate(), nil
+		},
+	}
+
+	if opts.CAFile != "" {
+		// Пакет CA может смениться без перезапуска, поэтому цепочка проверяется вручную
+		// по текущему пулу, а не через статичный ClientCAs.
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyClient(state, reloader.CertPool())
+		}
+	}
+
+	return cfg, nil
+}
+
+// NewClientConfig собирает TLS-конфигурацию клиента; клиентский сертификат и пакет CA перечитываются при изменении.
+func NewClientConfig(opts Options) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+	}
+
+	if opts.CertFile == "" && opts.KeyFile == "" && opts.CAFile == "" {
+		return cfg, nil
+	}
+
+	reloader, err := NewReloader(opts.CertFile, opts.KeyFile, opts.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.CAFile != "" {
+		// Как и на сервере, цепочка проверяется вручную по текущему пулу: статичный RootCAs
+		// не увидел бы обновлённый пакет CA. Стандартную проверку заменяет VerifyConnection.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyServer(state, reloader.CertPool())
+		}
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return reloader.Certificate(), nil
+		}
+	}
+
+	return cfg, nil
+}
+
+func verifyServer(state tls.ConnectionState, pool *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("server certificate required")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		DNSName:       state.ServerName,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+
+	return err
+}
+
+func verifyClient(state tls.ConnectionState, pool *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("client certificate required")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	return err
+}
+
+// Identity возвращает subject клиентского сертификата или пустую строку, если его нет.
+func Identity(state *tls.ConnectionState) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+
+	return state.PeerCertificates[0].Subject.String()
+}
@@ -0,0 +1,211 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue выпускает сертификат и пишет его с ключом в dir, возвращая пути.
+func (ca *testCA) issue(t *testing.T, dir string, name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"metrics"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certPath, keyPath
+}
+
+func writeCA(t *testing.T, dir string, ca *testCA) string {
+	t.Helper()
+	path := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(path, ca.pem, 0o600))
+	return path
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caPath := writeCA(t, dir, ca)
+	serverCert, serverKey := ca.issue(t, dir, "server", 2, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "agent-1", 3, x509.ExtKeyUsageClientAuth)
+
+	serverConfig, err := NewServerConfig(Options{CertFile: serverCert, KeyFile: serverKey, CAFile: caPath})
+	require.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, Identity(r.TLS))
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	url := "https://" + listener.Addr().String()
+
+	clientConfig, err := NewClientConfig(Options{CertFile: clientCert, KeyFile: clientKey, CAFile: caPath})
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "CN=agent-1,O=metrics", string(body))
+
+	noCertConfig, err := NewClientConfig(Options{CAFile: caPath})
+	require.NoError(t, err)
+	noCertClient := &http.Client{Transport: &http.Transport{TLSClientConfig: noCertConfig}}
+	_, err = noCertClient.Get(url)
+	assert.Error(t, err, "server must require client certificate")
+
+	otherCA := newTestCA(t)
+	foreignCert, foreignKey := otherCA.issue(t, t.TempDir(), "intruder", 4, x509.ExtKeyUsageClientAuth)
+	foreignConfig, err := NewClientConfig(Options{CertFile: foreignCert, KeyFile: foreignKey, CAFile: caPath})
+	require.NoError(t, err)
+	foreignClient := &http.Client{Transport: &http.Transport{TLSClientConfig: foreignConfig}}
+	_, err = foreignClient.Get(url)
+	assert.Error(t, err, "certificate from unknown CA must be rejected")
+}
+
+func TestClientConfig_VerifiesServerWithReloadedCA(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := newTestCA(t)
+	serverCert, serverKey := serverCA.issue(t, dir, "server", 30, x509.ExtKeyUsageServerAuth)
+
+	serverConfig, err := NewServerConfig(Options{CertFile: serverCert, KeyFile: serverKey})
+	require.NoError(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	caPath := writeCA(t, dir, newTestCA(t))
+	clientConfig, err := NewClientConfig(Options{CAFile: caPath})
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig, DisableKeepAlives: true}}
+	url := "https://" + listener.Addr().String()
+
+	_, err = client.Get(url)
+	require.Error(t, err, "server certificate from unknown CA must be rejected")
+
+	require.NoError(t, os.WriteFile(caPath, serverCA.pem, 0o600))
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(caPath, future, future))
+	time.Sleep(checkInterval + 100*time.Millisecond)
+
+	resp, err := client.Get(url)
+	require.NoError(t, err, "rotated CA bundle must be picked up without rebuilding the config")
+	resp.Body.Close()
+}
+
+func TestReloader_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPath, keyPath := ca.issue(t, dir, "server", 10, x509.ExtKeyUsageServerAuth)
+
+	reloader, err := NewReloader(certPath, keyPath, "")
+	require.NoError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	first, err := x509.ParseCertificate(reloader.Certificate().Certificate[0])
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, first.SerialNumber.Int64())
+
+	ca.issue(t, dir, "server", 11, x509.ExtKeyUsageServerAuth)
+	future := now.Add(time.Hour)
+	require.NoError(t, os.Chtimes(certPath, future, future))
+
+	second, err := x509.ParseCertificate(reloader.Certificate().Certificate[0])
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, second.SerialNumber.Int64(), "files are not rechecked within checkInterval")
+
+	now = now.Add(2 * checkInterval)
+	third, err := x509.ParseCertificate(reloader.Certificate().Certificate[0])
+	require.NoError(t, err)
+	assert.EqualValues(t, 11, third.SerialNumber.Int64())
+}
+
+func TestReloader_KeepsCertificateOnBrokenFile(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPath, keyPath := ca.issue(t, dir, "server", 20, x509.ExtKeyUsageServerAuth)
+
+	reloader, err := NewReloader(certPath, keyPath, "")
+	require.NoError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	require.NoError(t, os.WriteFile(certPath, []byte("broken"), 0o600))
+	now = now.Add(2 * checkInterval)
+
+	cert := reloader.Certificate()
+	require.NotNil(t, cert)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.EqualValues(t, 20, parsed.SerialNumber.Int64())
+}
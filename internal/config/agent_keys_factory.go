@@ -0,0 +1,22 @@
+package config
+
+import (
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/pkg/container"
+)
+
+func AgentKeyringFactory() container.Factory[*security.AgentKeyring] {
+	return func(c container.Container) (*security.AgentKeyring, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.AgentKeysDir == "" {
+			return nil, container.Error("agent keys directory is not configured")
+		}
+
+		return security.NewAgentKeyring(cfg.AgentKeysDir)
+	}
+}
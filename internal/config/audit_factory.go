@@ -0,0 +1,47 @@
+package config
+
+import (
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/audit"
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/GoLessons/go-musthave-metrics/pkg/container"
+)
+
+func AuditSubjectFactory() container.Factory[*audit.AuditSubject] {
+	return func(c container.Container) (*audit.AuditSubject, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		var registry *telemetry.Registry
+		if svc, err := container.GetService[telemetry.Registry](c, "telemetry"); err == nil {
+			registry = svc
+		}
+
+		return audit.NewAuditSubject(AuditObservers(cfg, registry)...), nil
+	}
+}
+
+// AuditObservers строит наблюдателей аудита по настройкам AuditFile и AuditURL.
+// Удалённый наблюдатель работает в фоне, его закрывает AuditSubject.Close или Replace.
+func AuditObservers(cfg *config2.Config, registry *telemetry.Registry) []audit.Auditor {
+	var observers []audit.Auditor
+	if cfg.AuditFile != "" {
+		observers = append(observers, audit.NewObservedAuditor("file", audit.NewFileAuditor(cfg.AuditFile), registry))
+	}
+	if cfg.AuditURL != "" {
+		remote := audit.NewRemoteAuditor(cfg.AuditURL).
+			WithQueue(cfg.AuditQueueSize).
+			WithBatch(cfg.AuditBatchSize, audit.DefaultRemoteFlushInterval).
+			WithFallback(cfg.AuditFallbackFile).
+			WithRegistry(registry)
+		if cfg.AuditKey != "" {
+			remote.WithSigner(signature.NewSign(cfg.AuditKey))
+		}
+		observers = append(observers, audit.NewObservedAuditor("remote", remote, registry))
+	}
+
+	return observers
+}
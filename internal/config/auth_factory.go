@@ -0,0 +1,27 @@
+package config
+
+import (
+	"github.com/GoLessons/go-musthave-metrics/internal/server/auth"
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/pkg/container"
+)
+
+func JWTVerifierFactory() container.Factory[*auth.Verifier] {
+	return func(c container.Container) (*auth.Verifier, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.AuthConfig.JWKSFile == "" {
+			return nil, container.Error("jwks file not configured")
+		}
+
+		keys, err := auth.LoadKeySet(cfg.AuthConfig.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return auth.NewVerifier(keys, cfg.AuthConfig.Issuer, cfg.AuthConfig.Audience), nil
+	}
+}
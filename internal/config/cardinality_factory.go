@@ -0,0 +1,20 @@
+package config
+
+import (
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+)
+
+// NewCardinalityGuard строит ограничитель кардинальности по конфигурации; nil, если лимиты не заданы.
+func NewCardinalityGuard(cfg *config2.Config) *service.CardinalityGuard {
+	guardConfig := service.CardinalityConfig{
+		MaxNames:          int(cfg.Cardinality.MaxNames),
+		MaxNamesPerPrefix: int(cfg.Cardinality.MaxNamesPerPrefix),
+		Overflow:          service.OverflowPolicy(cfg.Cardinality.Overflow),
+	}
+	if !guardConfig.Enabled() {
+		return nil
+	}
+
+	return service.NewCardinalityGuard(guardConfig)
+}
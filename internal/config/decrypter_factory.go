@@ -0,0 +1,22 @@
+package config
+
+import (
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/middleware"
+	"github.com/GoLessons/go-musthave-metrics/pkg/container"
+)
+
+func DecrypterFactory() container.Factory[*middleware.Decrypter] {
+	return func(c container.Container) (*middleware.Decrypter, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.CryptoKey == "" {
+			return nil, container.Error("crypto key not configured")
+		}
+
+		return middleware.NewDecrypterFromFile(cfg.CryptoKey)
+	}
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/limiter"
+	"github.com/GoLessons/go-musthave-metrics/pkg/container"
+)
+
+func LimiterFactory() container.Factory[*limiter.Limiter] {
+	return func(c container.Container) (*limiter.Limiter, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		return limiter.New(limiter.Config{
+			RequestsPerSecond: cfg.LimitConfig.RequestsPerSecond,
+			Burst:             int(cfg.LimitConfig.Burst),
+			MaxBatchSize:      int(cfg.LimitConfig.MaxBatchSize),
+			MaxSeries:         int(cfg.LimitConfig.MaxSeries),
+		}), nil
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"time"
+
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/pkg/container"
+)
+
+// ReplayGuardFactory создаёт общий для HTTP и gRPC кэш nonce; при нулевом окне защита отключена.
+func ReplayGuardFactory() container.Factory[*security.ReplayGuard] {
+	return func(c container.Container) (*security.ReplayGuard, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.ReplayConfig.MaxSkew == 0 {
+			return nil, nil
+		}
+
+		return security.NewReplayGuard(time.Duration(cfg.ReplayConfig.MaxSkew)*time.Second, cfg.ReplayConfig.RequireNonce), nil
+	}
+}
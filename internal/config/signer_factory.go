@@ -0,0 +1,23 @@
+package config
+
+import (
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/pkg/container"
+)
+
+// SignerFactory создаёт подписчик с общим ключом KEY, ключ которого меняется при перезагрузке конфигурации.
+func SignerFactory() container.Factory[*signature.Signer] {
+	return func(c container.Container) (*signature.Signer, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.Key == "" {
+			return nil, container.Error("signing key not configured")
+		}
+
+		return signature.NewSign(cfg.Key), nil
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
+	"github.com/GoLessons/go-musthave-metrics/pkg/container"
+)
+
+func TenantRegistryFactory() container.Factory[*tenant.Registry] {
+	return func(c container.Container) (*tenant.Registry, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.TenantsFile == "" {
+			return nil, container.Error("tenants file not configured")
+		}
+
+		return tenant.LoadRegistry(cfg.TenantsFile)
+	}
+}
+
+func TenantMetricServicesFactory() container.Factory[*service.TenantMetricServices] {
+	return func(c container.Container) (*service.TenantMetricServices, error) {
+		metricService, err := container.GetService[service.MetricService](c, "metricService")
+		if err != nil {
+			return nil, err
+		}
+
+		services := service.NewTenantMetricServices(metricService)
+		if registry, err := container.GetService[telemetry.Registry](c, "telemetry"); err == nil {
+			registry.OnCollect(func() {
+				for tenantID, svc := range services.All() {
+					counters, gauges := svc.Size()
+					registry.StorageSize(tenantID, counters, gauges)
+				}
+			})
+		}
+
+		return services, nil
+	}
+}
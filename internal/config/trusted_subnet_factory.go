@@ -0,0 +1,30 @@
+package config
+
+import (
+	config2 "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/pkg/container"
+)
+
+// TrustedSubnetFactory создаёт доверенную подсеть, общую для HTTP, gRPC и перезагрузки конфигурации.
+func TrustedSubnetFactory() container.Factory[*security.TrustedSubnet] {
+	return func(c container.Container) (*security.TrustedSubnet, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		return security.NewTrustedSubnet(cfg.TrustedSubnet)
+	}
+}
+
+func TrustedProxiesFactory() container.Factory[*security.TrustedProxies] {
+	return func(c container.Container) (*security.TrustedProxies, error) {
+		cfg, err := container.GetService[config2.Config](c, "config")
+		if err != nil {
+			return nil, err
+		}
+
+		return security.ParseTrustedProxies(cfg.LimitConfig.TrustedProxies)
+	}
+}
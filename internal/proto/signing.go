@@ -0,0 +1,13 @@
+package proto
+
+import (
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// SignatureMetadataKey — ключ метаданных с HMAC-SHA256 запроса, аналог заголовка HashSHA256 в HTTP.
+const SignatureMetadataKey = "hashsha256"
+
+// SigningBytes сериализует сообщение детерминированно, чтобы агент и сервер подписывали одни и те же байты.
+func SigningBytes(message protobuf.Message) ([]byte, error) {
+	return protobuf.MarshalOptions{Deterministic: true}.Marshal(message)
+}
@@ -8,10 +8,19 @@ type Auditor interface {
 	Journal(context.Context, *JournalItem) bool
 }
 
+// Pinger — наблюдатель, доступность которого можно проверить, не записывая событие.
+type Pinger interface {
+	Ping(context.Context) error
+}
+
 type JournalItem struct {
 	TS      int64    `json:"ts"`
 	Metrics []string `json:"metrics"`
 	IP      string   `json:"ip_address"`
+	// Identity — subject клиентского сертификата при mTLS.
+	Identity string `json:"identity,omitempty"`
+	// Agent — имя агента, подписавшего запрос своим ключом.
+	Agent string `json:"agent,omitempty"`
 }
 
 func NewJournalItem(ts int64, metrics []string, ipAddress string) *JournalItem {
@@ -18,3 +18,17 @@ func TestJournalItemJSON(t *testing.T) {
 		t.Fatalf("unexpected json:\nexpected: %s\ngot:      %s", expected, string(data))
 	}
 }
+
+func TestJournalItemJSON_WithIdentity(t *testing.T) {
+	item := NewJournalItem(1, []string{"Alloc"}, "10.0.0.1")
+	item.Identity = "CN=agent-1"
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	expected := `{"ts":1,"metrics":["Alloc"],"ip_address":"10.0.0.1","identity":"CN=agent-1"}`
+	if string(data) != expected {
+		t.Fatalf("unexpected json:\nexpected: %s\ngot:      %s", expected, string(data))
+	}
+}
@@ -2,6 +2,7 @@ package audit
 
 import (
 	"context"
+	"errors"
 	"os"
 
 	"github.com/goccy/go-json"
@@ -43,3 +44,17 @@ func (a *FileAuditor) Journal(ctx context.Context, item *JournalItem) (ok bool)
 	ok = err == nil
 	return ok
 }
+
+// Ping проверяет, что файл журнала можно открыть на дозапись.
+func (a *FileAuditor) Ping(ctx context.Context) error {
+	if a.filePath == "" {
+		return errors.New("audit file is not configured")
+	}
+
+	f, err := os.OpenFile(a.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
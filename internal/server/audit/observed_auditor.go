@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"context"
+	"io"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+)
+
+// ObservedAuditor учитывает события, которые наблюдатель не смог доставить.
+type ObservedAuditor struct {
+	name     string
+	auditor  Auditor
+	registry *telemetry.Registry
+}
+
+func NewObservedAuditor(name string, auditor Auditor, registry *telemetry.Registry) *ObservedAuditor {
+	return &ObservedAuditor{name: name, auditor: auditor, registry: registry}
+}
+
+func (a *ObservedAuditor) Journal(ctx context.Context, item *JournalItem) bool {
+	ok := a.auditor.Journal(ctx, item)
+	if !ok {
+		a.registry.AuditFailed(a.name)
+	}
+
+	return ok
+}
+
+func (a *ObservedAuditor) Ping(ctx context.Context) error {
+	if pinger, ok := a.auditor.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+// Close закрывает обёрнутого наблюдателя, если у него есть фоновая работа.
+func (a *ObservedAuditor) Close() error {
+	if closer, ok := a.auditor.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
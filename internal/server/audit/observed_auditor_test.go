@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+)
+
+func TestObservedAuditor_CountsFailures(t *testing.T) {
+	registry := telemetry.NewRegistry()
+	failing := NewObservedAuditor("remote", &stubAuditor{result: false}, registry)
+	working := NewObservedAuditor("file", &stubAuditor{result: true}, registry)
+
+	item := NewJournalItem(1, []string{"A"}, "127.0.0.1")
+	if failing.Journal(context.Background(), item) {
+		t.Fatalf("expected failure to be passed through")
+	}
+	failing.Journal(context.Background(), item)
+	if !working.Journal(context.Background(), item) {
+		t.Fatalf("expected success to be passed through")
+	}
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `metrics_server_audit_failures_total{auditor="remote"} 2`) {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), `auditor="file"`) {
+		t.Fatalf("successful deliveries must not be counted:\n%s", out.String())
+	}
+}
@@ -1,67 +1,315 @@
 package audit
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/GoLessons/go-musthave-metrics/pkg/repeater"
+	"github.com/goccy/go-json"
 )
 
+// Значения по умолчанию для очереди удалённого аудита.
+const (
+	DefaultRemoteQueueSize     = 1024
+	DefaultRemoteBatchSize     = 100
+	DefaultRemoteFlushInterval = time.Second
+)
+
+// remoteCloseTimeout ограничивает дозапись очереди при закрытии; после него повторы прерываются,
+// а недоставленные события уходят в запасной файл.
+const remoteCloseTimeout = 5 * time.Second
+
+// HashHeader — заголовок с HMAC-SHA256 тела пачки, как у запросов агента.
+const HashHeader = "HashSHA256"
+
+// StatusError — приёмник аудита ответил кодом вне 2xx.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("audit receiver responded with status %d", e.Code)
+}
+
+// RemoteAuditor отправляет события POST-запросом на url пачками — JSON-массивом JournalItem.
+// Journal только ставит событие в ограниченную очередь и не ждёт сети. Если очередь заполнена,
+// событие пишется в запасной файл, а без него теряется. Пачка, не доставленная после повторов,
+// тоже уходит в запасной файл.
 type RemoteAuditor struct {
-	url string
+	url           string
+	client        *http.Client
+	signer        *signature.Signer
+	fallback      *FileAuditor
+	registry      *telemetry.Registry
+	strategy      repeater.Strategy
+	batchSize     int
+	flushInterval time.Duration
+
+	// mu защищает closed и отправку в queue: очередь закрывается только под записью.
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *JournalItem
+	start  sync.Once
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
+// NewRemoteAuditor создаёт приёмник с настройками по умолчанию. Фоновая отправка запускается
+// при первом событии, поэтому With* вызываются до начала работы.
 func NewRemoteAuditor(url string) *RemoteAuditor {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &RemoteAuditor{
-		url: url,
+		url:           url,
+		client:        &http.Client{Timeout: 5 * time.Second},
+		strategy:      repeater.NewExponentialBackoffStrategy(isRetriable, 500*time.Millisecond, 5*time.Second, 4),
+		batchSize:     DefaultRemoteBatchSize,
+		flushInterval: DefaultRemoteFlushInterval,
+		queue:         make(chan *JournalItem, DefaultRemoteQueueSize),
+		done:          make(chan struct{}),
+		ctx:           ctx,
+		cancel:        cancel,
 	}
 }
 
-func (a *RemoteAuditor) Journal(ctx context.Context, item *JournalItem) bool {
-	return true
+// WithQueue задаёт ёмкость очереди; неположительное значение оставляет значение по умолчанию.
+func (a *RemoteAuditor) WithQueue(size int) *RemoteAuditor {
+	if size > 0 {
+		a.queue = make(chan *JournalItem, size)
+	}
+	return a
 }
 
-/**
-// possible implementation
+// WithBatch задаёт наибольший размер пачки и период отправки неполной пачки.
+func (a *RemoteAuditor) WithBatch(size int, flushInterval time.Duration) *RemoteAuditor {
+	if size > 0 {
+		a.batchSize = size
+	}
+	if flushInterval > 0 {
+		a.flushInterval = flushInterval
+	}
+	return a
+}
 
-package audit
+// WithSigner включает подпись тела пачки в заголовке HashSHA256.
+func (a *RemoteAuditor) WithSigner(signer *signature.Signer) *RemoteAuditor {
+	a.signer = signer
+	return a
+}
 
-import (
-	"context"
-	"github.com/goccy/go-json"
-	"resty.dev/v3"
-	"time"
-)
+// WithFallback задаёт запасной файл в формате FileAuditor; пустой путь отключает его.
+func (a *RemoteAuditor) WithFallback(path string) *RemoteAuditor {
+	a.fallback = nil
+	if path != "" {
+		a.fallback = NewFileAuditor(path)
+	}
+	return a
+}
 
-type RemoteAuditor struct {
-	url    string
-	client *resty.Client
+// WithRegistry включает учёт событий, ушедших в запасной файл или потерянных при фоновой отправке.
+func (a *RemoteAuditor) WithRegistry(registry *telemetry.Registry) *RemoteAuditor {
+	a.registry = registry
+	return a
 }
 
-func NewRemoteAuditor(url string) *RemoteAuditor {
-	client := resty.New()
-	client.SetTimeout(5 * time.Second).
-		SetHeader("Content-Type", "application/json")
+func (a *RemoteAuditor) WithStrategy(strategy repeater.Strategy) *RemoteAuditor {
+	a.strategy = strategy
+	return a
+}
 
-	return &RemoteAuditor{url: url, client: client}
+func (a *RemoteAuditor) WithClient(client *http.Client) *RemoteAuditor {
+	a.client = client
+	return a
 }
 
+// Journal не блокируется: событие встаёт в очередь, а если места нет или приёмник закрыт — в запасной файл.
 func (a *RemoteAuditor) Journal(ctx context.Context, item *JournalItem) bool {
 	if a.url == "" || item == nil {
 		return false
 	}
+	a.start.Do(a.run)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return a.spill(ctx, telemetry.SpillClosed, item) == 1
+	}
+	select {
+	case a.queue <- item:
+		return true
+	default:
+		return a.spill(ctx, telemetry.SpillQueueFull, item) == 1
+	}
+}
+
+// Close перестаёт принимать события и дожидается отправки очереди не дольше remoteCloseTimeout.
+func (a *RemoteAuditor) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	// Если отправка не запускалась, в очереди ничего нет и ждать нечего.
+	a.start.Do(func() { close(a.done) })
+
+	timer := time.NewTimer(remoteCloseTimeout)
+	defer timer.Stop()
+	select {
+	case <-a.done:
+	case <-timer.C:
+		a.cancel()
+		<-a.done
+	}
+	a.cancel()
 
-	data, err := json.Marshal(item)
+	return nil
+}
+
+func (a *RemoteAuditor) run() {
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(a.flushInterval)
+		defer ticker.Stop()
+
+		batch := make([]*JournalItem, 0, a.batchSize)
+		for {
+			select {
+			case item, ok := <-a.queue:
+				if !ok {
+					a.flush(batch)
+					return
+				}
+				batch = append(batch, item)
+				if len(batch) >= a.batchSize {
+					a.flush(batch)
+					batch = batch[:0]
+				}
+			case <-ticker.C:
+				a.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}()
+}
+
+// flush отправляет пачку с повторами; недоставленные события пишутся в запасной файл.
+func (a *RemoteAuditor) flush(batch []*JournalItem) {
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(batch)
+	if err == nil {
+		_, err = repeater.NewRepeater().RepeatContext(a.ctx, a.strategy, func() (any, error) {
+			return nil, a.send(body)
+		})
+	}
+	if err == nil {
+		return
+	}
+
+	for lost := len(batch) - a.spill(context.Background(), telemetry.SpillUndelivered, batch...); lost > 0; lost-- {
+		a.registry.AuditFailed("remote")
+	}
+}
+
+func (a *RemoteAuditor) send(body []byte) error {
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.url, bytes.NewReader(body))
 	if err != nil {
-		return false
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.signer != nil {
+		hash, err := a.signer.Hash(body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(HashHeader, hash)
 	}
 
-	resp, err := a.client.R().
-		WithContext(ctx).
-		SetHeader("Content-Type", "application/json").
-		SetBody(data).
-		Post(a.url)
+	resp, err := a.client.Do(req)
 	if err != nil {
-		return false
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{Code: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// spill пишет события в запасной файл и возвращает, сколько удалось записать.
+func (a *RemoteAuditor) spill(ctx context.Context, reason string, items ...*JournalItem) int {
+	if a.fallback == nil {
+		return 0
+	}
+
+	written := 0
+	for _, item := range items {
+		if a.fallback.Journal(ctx, item) {
+			a.registry.AuditSpilled(reason)
+			written++
+		}
+	}
+
+	return written
+}
+
+// isRetriable считает временными сетевые ошибки, 429 и ответы 5xx; прочие ответы приёмника повторять бессмысленно.
+// Отмену при закрытии отсекает сам repeater.
+func isRetriable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusTooManyRequests || status.Code >= 500
+	}
+
+	return true
+}
+
+// Ping проверяет, что к приёмнику аудита устанавливается TCP-соединение.
+func (a *RemoteAuditor) Ping(ctx context.Context) error {
+	u, err := url.Parse(a.url)
+	if err != nil {
+		return fmt.Errorf("bad audit url: %w", err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("bad audit url %q: no host", a.url)
+	}
+
+	address := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
 	}
 
-	return resp.IsSuccess()
+	return conn.Close()
 }
-*/
@@ -0,0 +1,192 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/pkg/repeater"
+	"github.com/goccy/go-json"
+)
+
+// receiver собирает пачки, пришедшие на тестовый приёмник аудита.
+type receiver struct {
+	mu      sync.Mutex
+	batches [][]JournalItem
+	bodies  [][]byte
+	hashes  []string
+	calls   atomic.Int32
+}
+
+func (r *receiver) handler(t *testing.T, status func(call int32) int) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		call := r.calls.Add(1)
+		if code := status(call); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+			return
+		}
+		var batch []JournalItem
+		if err := json.Unmarshal(body, &batch); err != nil {
+			t.Errorf("unmarshal batch: %v", err)
+			return
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.batches = append(r.batches, batch)
+		r.bodies = append(r.bodies, body)
+		r.hashes = append(r.hashes, req.Header.Get(HashHeader))
+	}
+}
+
+func (r *receiver) items() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, b := range r.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func noDelays() repeater.Strategy {
+	return repeater.NewFixedDelaysStrategy(isRetriable, 0, 0, 0)
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open fallback: %v", err)
+	}
+	defer f.Close()
+
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+func TestRemoteAuditor_BatchesAndSigns(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r.handler(t, func(int32) int { return http.StatusOK }))
+	defer srv.Close()
+
+	a := NewRemoteAuditor(srv.URL).
+		WithBatch(2, time.Hour).
+		WithSigner(signature.NewSign("audit-secret")).
+		WithStrategy(noDelays())
+
+	for i := 0; i < 3; i++ {
+		if ok := a.Journal(context.Background(), NewJournalItem(int64(i), []string{"A"}, "127.0.0.1")); !ok {
+			t.Fatalf("journal %d: expected item to be queued", i)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if len(r.batches) != 2 || len(r.batches[0]) != 2 || len(r.batches[1]) != 1 {
+		t.Fatalf("expected batches of 2 and 1, got %v", r.batches)
+	}
+	for i, hash := range r.hashes {
+		if !signature.NewSign("audit-secret").Check(hash, r.bodies[i]) {
+			t.Fatalf("batch %d: bad signature %q", i, hash)
+		}
+	}
+}
+
+func TestRemoteAuditor_RetriesTransientErrors(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r.handler(t, func(call int32) int {
+		if call < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}))
+	defer srv.Close()
+
+	a := NewRemoteAuditor(srv.URL).WithBatch(1, time.Hour).WithStrategy(noDelays())
+	a.Journal(context.Background(), NewJournalItem(1, []string{"A"}, "127.0.0.1"))
+	_ = a.Close()
+
+	if got := r.calls.Load(); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+	if r.items() != 1 {
+		t.Fatalf("expected item delivered once, got %d", r.items())
+	}
+}
+
+func TestRemoteAuditor_UndeliveredGoesToFallback(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r.handler(t, func(int32) int { return http.StatusBadRequest }))
+	defer srv.Close()
+
+	fallback := filepath.Join(t.TempDir(), "audit-fallback.log")
+	a := NewRemoteAuditor(srv.URL).WithBatch(2, time.Hour).WithFallback(fallback).WithStrategy(noDelays())
+	a.Journal(context.Background(), NewJournalItem(1, []string{"A"}, "127.0.0.1"))
+	a.Journal(context.Background(), NewJournalItem(2, []string{"B"}, "127.0.0.1"))
+	_ = a.Close()
+
+	if got := r.calls.Load(); got != 1 {
+		t.Fatalf("client errors must not be retried, got %d attempts", got)
+	}
+	if lines := readLines(t, fallback); len(lines) != 2 {
+		t.Fatalf("expected 2 items in fallback, got %v", lines)
+	}
+}
+
+func TestRemoteAuditor_DoesNotBlockWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	fallback := filepath.Join(t.TempDir(), "audit-fallback.log")
+	a := NewRemoteAuditor(srv.URL).WithQueue(1).WithBatch(1, time.Hour).WithFallback(fallback).WithStrategy(noDelays())
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if ok := a.Journal(context.Background(), NewJournalItem(int64(i), []string{"A"}, "127.0.0.1")); !ok {
+			t.Fatalf("journal %d: expected item to be queued or spilled", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("journal must not wait for the receiver, took %v", elapsed)
+	}
+
+	// Одно событие у отправителя, одно в очереди, остальные в запасном файле.
+	if lines := readLines(t, fallback); len(lines) < 8 {
+		t.Fatalf("expected overflow in fallback, got %d lines", len(lines))
+	}
+}
+
+func TestRemoteAuditor_JournalAfterClose(t *testing.T) {
+	a := NewRemoteAuditor("http://127.0.0.1:1")
+	_ = a.Close()
+
+	if ok := a.Journal(context.Background(), NewJournalItem(1, []string{"A"}, "127.0.0.1")); ok {
+		t.Fatalf("closed auditor without fallback must report loss")
+	}
+}
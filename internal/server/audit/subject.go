@@ -2,6 +2,8 @@ package audit
 
 import (
 	"context"
+	"errors"
+	"io"
 	"sync"
 )
 
@@ -41,6 +43,53 @@ func (s *AuditSubject) Deregister(o Auditor) {
 	}
 }
 
+// Replace атомарно заменяет весь список наблюдателей; уже начатые уведомления дорабатывают со старым списком.
+// Прежние наблюдатели, не вошедшие в новый список, закрываются.
+func (s *AuditSubject) Replace(observers ...Auditor) {
+	s.mu.Lock()
+	previous := s.observers
+	s.observers = append([]Auditor(nil), observers...)
+	s.mu.Unlock()
+
+	var removed []Auditor
+	for _, o := range previous {
+		kept := false
+		for _, next := range observers {
+			if next == o {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			removed = append(removed, o)
+		}
+	}
+	_ = closeAll(removed)
+}
+
+// Close закрывает наблюдателей с фоновой работой, дожидаясь отправки накопленных событий.
+func (s *AuditSubject) Close() error {
+	s.mu.RLock()
+	observers := make([]Auditor, len(s.observers))
+	copy(observers, s.observers)
+	s.mu.RUnlock()
+
+	return closeAll(observers)
+}
+
+func closeAll(observers []Auditor) error {
+	var errs []error
+	for _, o := range observers {
+		if closer, ok := o.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func (s *AuditSubject) NotifyAll(ctx context.Context, item *JournalItem) bool {
 	if item == nil {
 		return false
@@ -82,6 +131,33 @@ func (s *AuditSubject) NotifyAll(ctx context.Context, item *JournalItem) bool {
 	return false
 }
 
+// Ping проверяет доступность всех наблюдателей, которые это умеют.
+func (s *AuditSubject) Ping(ctx context.Context) error {
+	s.mu.RLock()
+	observers := make([]Auditor, len(s.observers))
+	copy(observers, s.observers)
+	s.mu.RUnlock()
+
+	var errs []error
+	for _, o := range observers {
+		if pinger, ok := o.(Pinger); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Len возвращает число подписанных наблюдателей.
+func (s *AuditSubject) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.observers)
+}
+
 func (s *AuditSubject) Journal(ctx context.Context, item *JournalItem) bool {
 	return s.NotifyAll(ctx, item)
 }
@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server"
+	"github.com/goccy/go-json"
+)
+
+const (
+	ScopeRead  = "metrics:read"
+	ScopeWrite = "metrics:write"
+	ScopeAdmin = "metrics:admin"
+)
+
+// clockSkew допускает небольшое расхождение часов между сервером и издателем токена.
+const clockSkew = time.Minute
+
+var (
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// stringList принимает как одиночную строку, так и массив строк (aud, scp).
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+
+	return nil
+}
+
+type Claims struct {
+	Subject   string     `json:"sub"`
+	Issuer    string     `json:"iss"`
+	Audience  stringList `json:"aud"`
+	ExpiresAt float64    `json:"exp"`
+	NotBefore float64    `json:"nbf"`
+	Scope     string     `json:"scope"`
+	Scp       stringList `json:"scp"`
+}
+
+func (c *Claims) Scopes() []string {
+	scopes := strings.Fields(c.Scope)
+	for _, s := range c.Scp {
+		scopes = append(scopes, strings.Fields(s)...)
+	}
+
+	return scopes
+}
+
+// HasScope проверяет право доступа; metrics:admin включает все остальные права.
+func (c *Claims) HasScope(scope string) bool {
+	scopes := c.Scopes()
+	return slices.Contains(scopes, scope) || slices.Contains(scopes, ScopeAdmin)
+}
+
+type Verifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewVerifier создаёт проверку JWT; пустые issuer и audience не проверяются.
+func NewVerifier(keys *KeySet, issuer string, audience string) *Verifier {
+	return &Verifier{keys: keys, issuer: issuer, audience: audience, now: time.Now}
+}
+
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidToken)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidToken)
+	}
+
+	if err := v.verifySignature(h, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: bad claims", ErrInvalidToken)
+	}
+
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func (v *Verifier) verifySignature(h header, signingInput string, signature []byte) error {
+	hash, err := hashFor(h.Alg)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	for _, key := range v.keys.find(h.Kid, h.Alg) {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			if strings.HasPrefix(h.Alg, "RS") && rsa.VerifyPKCS1v15(k, hash, digest, signature) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			if curveFor[h.Alg] == k.Curve && verifyECDSA(k, digest, signature) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+}
+
+func (v *Verifier) validateClaims(claims *Claims) error {
+	now := v.now()
+
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if now.After(unixTime(claims.ExpiresAt).Add(clockSkew)) {
+		return fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(unixTime(claims.NotBefore)) {
+		return fmt.Errorf("%w: token not yet valid", ErrInvalidToken)
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if v.audience != "" && !slices.Contains(claims.Audience, v.audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	return nil
+}
+
+func hashFor(alg string) (crypto.Hash, error) {
+	switch alg {
+	case "RS256", "ES256":
+		return crypto.SHA256, nil
+	case "RS384", "ES384":
+		return crypto.SHA384, nil
+	case "RS512", "ES512":
+		return crypto.SHA512, nil
+	}
+
+	return 0, fmt.Errorf("unsupported alg %q", alg)
+}
+
+// curveFor связывает алгоритм ES* с его кривой: ключ другой кривой для алгоритма не подходит (RFC 7518, 3.4).
+var curveFor = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+// verifyECDSA проверяет подпись JWS в формате r||s (RFC 7518, 3.4).
+func verifyECDSA(key *ecdsa.PublicKey, digest []byte, signature []byte) bool {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+
+	return ecdsa.Verify(key, digest, r, s)
+}
+
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// BearerToken извлекает токен из значения заголовка Authorization.
+func BearerToken(authorization string) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, server.Claims, claims)
+}
+
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(server.Claims).(*Claims)
+	return claims, ok && claims != nil
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": "ES256", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// signToken собирает токен с произвольным заголовком и подписью — для проверки отказов верификатора.
+func signToken(t *testing.T, alg string, kid string, claims map[string]any, sign func(input string) []byte) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": alg, "kid": kid}) + "." + encodeSegment(t, claims)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign(input))
+}
+
+// signECDSA подписывает SHA-256 от input ключом key и кодирует r||s полями по size байт.
+func signECDSA(t *testing.T, key *ecdsa.PrivateKey, size int) func(string) []byte {
+	return func(input string) []byte {
+		digest := sha256.Sum256([]byte(input))
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		return sig
+	}
+}
+
+func ecJWK(key *ecdsa.PrivateKey, kid string, crv string) JSONWebKey {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	x := make([]byte, size)
+	y := make([]byte, size)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return JSONWebKey{
+		Kty: "EC",
+		Kid: kid,
+		Crv: crv,
+		X:   base64.RawURLEncoding.EncodeToString(x),
+		Y:   base64.RawURLEncoding.EncodeToString(y),
+	}
+}
+
+func rsaJWK(key *rsa.PrivateKey, kid string) JSONWebKey {
+	return JSONWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	keys, err := NewKeySet(
+		rsaJWK(rsaKey, "rsa-1"),
+		ecJWK(ecKey, "ec-1", "P-256"),
+		ecJWK(ec384Key, "ec-384", "P-384"),
+	)
+	require.NoError(t, err)
+
+	now := time.Unix(1_700_000_000, 0)
+	verifier := NewVerifier(keys, "issuer", "metrics")
+	verifier.now = func() time.Time { return now }
+
+	valid := map[string]any{
+		"sub":   "agent",
+		"iss":   "issuer",
+		"aud":   []string{"metrics"},
+		"exp":   now.Add(time.Hour).Unix(),
+		"scope": "metrics:write metrics:read",
+	}
+	with := func(key string, value any) map[string]any {
+		claims := make(map[string]any, len(valid))
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[key] = value
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "RS256 token", token: signRS256(t, rsaKey, "rsa-1", valid)},
+		{name: "ES256 token", token: signES256(t, ecKey, "ec-1", valid)},
+		{name: "Token without kid", token: signRS256(t, rsaKey, "", valid)},
+		{name: "Foreign key", token: signRS256(t, otherKey, "rsa-1", valid), wantErr: true},
+		{name: "Expired", token: signRS256(t, rsaKey, "rsa-1", with("exp", now.Add(-2*time.Minute).Unix())), wantErr: true},
+		{name: "Not yet valid", token: signRS256(t, rsaKey, "rsa-1", with("nbf", now.Add(time.Hour).Unix())), wantErr: true},
+		{name: "Wrong issuer", token: signRS256(t, rsaKey, "rsa-1", with("iss", "other")), wantErr: true},
+		{name: "Wrong audience", token: signRS256(t, rsaKey, "rsa-1", with("aud", "other")), wantErr: true},
+		{name: "Malformed", token: "abc.def", wantErr: true},
+		{name: "Alg none", token: encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, valid) + ".", wantErr: true},
+		{name: "Alg none with kid", token: signToken(t, "none", "rsa-1", valid, func(string) []byte { return nil }), wantErr: true},
+		{
+			name: "HS256 keyed with RSA public key",
+			token: signToken(t, "HS256", "rsa-1", valid, func(input string) []byte {
+				mac := hmac.New(sha256.New, rsaKey.N.Bytes())
+				mac.Write([]byte(input))
+				return mac.Sum(nil)
+			}),
+			wantErr: true,
+		},
+		{name: "Unknown kid", token: signRS256(t, rsaKey, "rsa-2", valid), wantErr: true},
+		{name: "ES256 with RSA kid", token: signToken(t, "ES256", "rsa-1", valid, signECDSA(t, ecKey, 32)), wantErr: true},
+		{name: "RS256 with EC kid", token: signRS256(t, rsaKey, "ec-1", valid), wantErr: true},
+		{name: "ES256 with P-384 key", token: signToken(t, "ES256", "ec-384", valid, signECDSA(t, ec384Key, 48)), wantErr: true},
+		{name: "ES384 with P-256 key", token: signToken(t, "ES384", "ec-1", valid, signECDSA(t, ecKey, 32)), wantErr: true},
+		{name: "ECDSA signature too short", token: signToken(t, "ES256", "ec-1", valid, func(input string) []byte { return signECDSA(t, ecKey, 32)(input)[:63] }), wantErr: true},
+		{name: "ECDSA signature too long", token: signToken(t, "ES256", "ec-1", valid, func(input string) []byte { return append(signECDSA(t, ecKey, 32)(input), 0) }), wantErr: true},
+		{name: "ECDSA signature with padded fields", token: signToken(t, "ES256", "ec-1", valid, signECDSA(t, ecKey, 33)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "agent", claims.Subject)
+			assert.True(t, claims.HasScope(ScopeWrite))
+			assert.False(t, claims.HasScope(ScopeAdmin))
+		})
+	}
+}
+
+func TestClaims_HasScope(t *testing.T) {
+	claims := &Claims{Scp: stringList{ScopeAdmin}}
+	assert.True(t, claims.HasScope(ScopeRead), "admin scope grants everything")
+
+	var parsed Claims
+	require.NoError(t, json.Unmarshal([]byte(`{"scp":"metrics:read"}`), &parsed))
+	assert.True(t, parsed.HasScope(ScopeRead))
+	assert.False(t, parsed.HasScope(ScopeWrite))
+}
+
+func TestBearerToken(t *testing.T) {
+	token, ok := BearerToken("Bearer abc")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", token)
+
+	_, ok = BearerToken("Basic abc")
+	assert.False(t, ok)
+
+	_, ok = BearerToken("Bearer ")
+	assert.False(t, ok)
+}
@@ -8,4 +8,7 @@ const (
 	MetricType  contextKey = "metricType"
 	MetricValue contextKey = "metricValue"
 	MetricsList contextKey = "metricsList"
+	Tenant      contextKey = "tenant"
+	Claims      contextKey = "claims"
+	Agent       contextKey = "agent"
 )
@@ -1,37 +1,99 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
-	"strings"
 
-	fileconfig "github.com/GoLessons/go-musthave-metrics/pkg/file-config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/pkg/settings"
+	"go.uber.org/zap/zapcore"
 )
 
 type Config struct {
-	Address         string `env:"ADDRESS"`
-	DatabaseDsn     string `env:"DATABASE_DSN"`
+	Address         string `env:"ADDRESS" flag:"address,a" usage:"HTTP server address"`
+	DatabaseDsn     string `env:"DATABASE_DSN" flag:"database-dsn,d" usage:"Database DSN" secret:"true"`
 	DumpConfig      DumpConfig
-	Key             string `env:"KEY"`
-	CryptoKey       string `env:"CRYPTO_KEY"`
-	TrustedSubnet   string `env:"TRUSTED_SUBNET"`
-	AuditFile       string `env:"AUDIT_FILE"`
-	AuditURL        string `env:"AUDIT_URL"`
-	PprofOnShutdown bool   `env:"PPROF_ON_SHUTDOWN"`
-	PprofDir        string `env:"PPROF_DIR"`
-	PprofFilename   string `env:"PPROF_FILENAME"`
-	PprofHTTP       bool   `env:"PPROF_HTTP"`
-	PprofHTTPAddr   string `env:"PPROF_HTTP_ADDR"`
-	GrpcEnabled     bool   `env:"GRPC_ENABLED"`
-	GrpcAddress     string `env:"GRPC_ADDRESS"`
+	LimitConfig     LimitConfig
+	Cardinality     CardinalityConfig
+	AuthConfig      AuthConfig
+	TLSConfig       TLSConfig
+	ReplayConfig    ReplayConfig
+	Key             string `env:"KEY" flag:"key,k" usage:"Key for signature verification" secret:"true"`
+	CryptoKey       string `env:"CRYPTO_KEY" flag:"crypto-key" usage:"Path to RSA private key for request decryption"`
+	TrustedSubnet   string `env:"TRUSTED_SUBNET" flag:"trusted_subnet,t" usage:"Trusted subnet CIDR"`
+	AuditFile       string `env:"AUDIT_FILE" flag:"audit-file" usage:"Audit log file path"`
+	AuditURL        string `env:"AUDIT_URL" flag:"audit-url" usage:"Audit log URL"`
+	PprofOnShutdown bool   `env:"PPROF_ON_SHUTDOWN" flag:"pprof-on-shutdown" usage:"Enable heap profile write on shutdown"`
+	PprofDir        string `env:"PPROF_DIR" flag:"pprof-dir" usage:"Directory to store pprof files"`
+	PprofFilename   string `env:"PPROF_FILENAME" flag:"pprof-filename" usage:"Heap profile filename"`
+	PprofHTTP       bool   `env:"PPROF_HTTP" flag:"pprof-http" usage:"Expose net/http/pprof endpoints"`
+	PprofHTTPAddr   string `env:"PPROF_HTTP_ADDR" flag:"pprof-http-addr" usage:"pprof HTTP listen address"`
+	GrpcEnabled     bool   `env:"GRPC_ENABLED" flag:"grpc-enabled,g" usage:"Enable gRPC server"`
+	GrpcAddress     string `env:"GRPC_ADDRESS" flag:"grpc-address,G" usage:"gRPC server address"`
+	TenantsFile     string `env:"TENANTS_FILE" flag:"tenants-file" usage:"Path to tenants JSON file"`
+	AgentKeysDir    string `env:"AGENT_KEYS_DIR" flag:"agent-keys-dir" usage:"Directory with enrolled agent public keys (enables per-agent signatures)"`
+	LogLevel        string `env:"LOG_LEVEL" flag:"log-level" usage:"Log level: debug, info, warn or error"`
+	// ConfigWatchInterval — период проверки файла конфигурации на изменения в секундах, 0 отключает.
+	ConfigWatchInterval uint64 `env:"CONFIG_WATCH_INTERVAL" flag:"config-watch-interval" usage:"Config file change check interval in seconds (0 disables)"`
+	// AuditKey — секрет HMAC-подписи пачек, отправляемых на AuditURL; пустой — без подписи.
+	AuditKey string `env:"AUDIT_KEY" flag:"audit-key" usage:"HMAC key for signing audit batches sent to the audit URL" secret:"true"`
+	// AuditFallbackFile принимает события, которые не поместились в очередь или не доставлены на AuditURL.
+	AuditFallbackFile string `env:"AUDIT_FALLBACK_FILE" flag:"audit-fallback-file" usage:"File for audit events the audit URL could not take"`
+	AuditQueueSize    int    `env:"AUDIT_QUEUE_SIZE" flag:"audit-queue-size" usage:"Audit URL queue capacity in events"`
+	AuditBatchSize    int    `env:"AUDIT_BATCH_SIZE" flag:"audit-batch-size" usage:"Max audit events per request to the audit URL"`
 }
 
 type DumpConfig struct {
-	StoreInterval   uint64 `env:"STORE_INTERVAL"`
-	FileStoragePath string `env:"FILE_STORAGE_PATH"`
-	Restore         bool   `env:"RESTORE"`
+	StoreInterval   uint64 `env:"STORE_INTERVAL" flag:"store-interval,i" usage:"Store interval in seconds"`
+	FileStoragePath string `env:"FILE_STORAGE_PATH" flag:"file-storage-path,f" usage:"File storage path"`
+	Restore         bool   `env:"RESTORE" flag:"restore,r" usage:"Restore metrics before starting"`
+}
+
+type LimitConfig struct {
+	RequestsPerSecond float64 `env:"RATE_LIMIT_RPS" flag:"rate-limit-rps" usage:"Requests per second per tenant or client IP (0 disables)"`
+	Burst             uint64  `env:"RATE_LIMIT_BURST" flag:"rate-limit-burst" usage:"Request burst per tenant or client IP"`
+	MaxBatchSize      uint64  `env:"MAX_BATCH_SIZE" flag:"max-batch-size" usage:"Max metrics per request (0 disables)"`
+	MaxSeries         uint64  `env:"MAX_SERIES" flag:"max-series" usage:"Max distinct series per tenant or client IP (0 disables)"`
+	TrustedProxies    string  `env:"TRUSTED_PROXIES" flag:"trusted-proxies" usage:"Comma-separated proxy CIDRs allowed to pass client IP in X-Real-IP"`
+}
+
+func (c LimitConfig) Enabled() bool {
+	return c.RequestsPerSecond > 0 || c.MaxBatchSize > 0 || c.MaxSeries > 0
+}
+
+type CardinalityConfig struct {
+	MaxNames          uint64 `env:"MAX_METRIC_NAMES" flag:"max-metric-names" usage:"Max distinct metric names across all tenants (0 disables)"`
+	MaxNamesPerPrefix uint64 `env:"MAX_METRIC_NAMES_PER_PREFIX" flag:"max-metric-names-per-prefix" usage:"Max distinct metric names per name prefix within a tenant (0 disables)"`
+	Overflow          string `env:"CARDINALITY_OVERFLOW" flag:"cardinality-overflow" usage:"Overflow behavior: reject, drop or fold"`
+}
+
+type AuthConfig struct {
+	JWKSFile string `env:"JWKS_FILE" flag:"jwks-file" usage:"Path to JWKS file for bearer token verification"`
+	Issuer   string `env:"JWT_ISSUER" flag:"jwt-issuer" usage:"Expected JWT issuer"`
+	Audience string `env:"JWT_AUDIENCE" flag:"jwt-audience" usage:"Expected JWT audience"`
+	// AdminKey открывает служебные маршруты по заголовку X-Admin-Key; без него и без JWKS они не регистрируются.
+	AdminKey string `env:"ADMIN_KEY" flag:"admin-key" usage:"Key for admin endpoints passed in X-Admin-Key" secret:"true"`
+}
+
+type TLSConfig struct {
+	CertFile     string `env:"TLS_CERT_FILE" flag:"tls-cert" usage:"Path to server TLS certificate"`
+	KeyFile      string `env:"TLS_KEY_FILE" flag:"tls-key" usage:"Path to server TLS private key"`
+	ClientCAFile string `env:"TLS_CLIENT_CA_FILE" flag:"tls-client-ca" usage:"Path to CA bundle for client certificate verification (enables mTLS)"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// ReplayConfig задаёт защиту подписанных запросов от повторов: окно допустимого
+// расхождения часов в секундах (0 отключает проверку) и обязательность метки времени и nonce.
+// RequireNonce включён по умолчанию; false — временный режим перехода для агентов без nonce.
+type ReplayConfig struct {
+	MaxSkew      uint64 `env:"SIGNATURE_MAX_SKEW" flag:"signature-max-skew" usage:"Allowed clock skew for signed requests in seconds (0 disables replay protection)"`
+	RequireNonce bool   `env:"SIGNATURE_REQUIRE_NONCE" flag:"signature-require-nonce" usage:"Reject signed requests without timestamp and nonce (set false only while migrating old agents)"`
 }
 
 type ConfigError struct {
@@ -50,7 +112,7 @@ func wrapError(msg string, err error) error {
 }
 
 func (e *ConfigError) Error() string {
-	if e.err != nil && e.err.Error() != e.Error() {
+	if e.err != nil && e.err.Error() != e.Msg {
 		return fmt.Sprintf("[CONFIG] %s (previous: %v)", e.Msg, e.err)
 	}
 
@@ -61,21 +123,9 @@ func (e *ConfigError) Unwrap() error {
 	return e.err
 }
 
-func LoadConfig(args *map[string]any) (*Config, error) {
-	flags := flag.NewFlagSet("app-config", flag.ContinueOnError)
-
-	envAddress := os.Getenv("ADDRESS")
-	if envAddress == "" {
-		envAddress = "localhost:8080"
-	}
-
-	cfgDefaults := &Config{
-		Address:     envAddress,
-		DatabaseDsn: "",
-		Key:         "",
-		CryptoKey:   "",
-		AuditFile:   "",
-		AuditURL:    "",
+func defaultConfig() *Config {
+	return &Config{
+		Address: "localhost:8080",
 		DumpConfig: DumpConfig{
 			Restore:         false,
 			StoreInterval:   300,
@@ -88,308 +138,136 @@ func LoadConfig(args *map[string]any) (*Config, error) {
 		PprofHTTPAddr:   ":6060",
 		GrpcEnabled:     false,
 		GrpcAddress:     ":50051",
+		Cardinality: CardinalityConfig{
+			Overflow: "reject",
+		},
+		ReplayConfig: ReplayConfig{
+			MaxSkew:      300,
+			RequireNonce: true,
+		},
+		AuditQueueSize:      1024,
+		AuditBatchSize:      100,
+		LogLevel:            "info",
+		ConfigWatchInterval: 5,
 	}
+}
 
-	if configPath := getFileConfigPath(); configPath != "" {
-		if err := fileconfig.LoadInto(configPath, cfgDefaults); err != nil {
-			return nil, wrapError("ошибка чтения файла конфигурации", err)
-		}
-	}
+// LoadConfig собирает конфигурацию: defaults < файл < окружение < флаги < args.
+// args переопределяет поля по пути (DumpConfig.StoreInterval) и нужен тестам.
+func LoadConfig(args *map[string]any) (*Config, error) {
+	cfg, _, err := LoadConfigReport(args)
+	return cfg, err
+}
 
-	address := flags.String("address", cfgDefaults.Address, "HTTP server address")
-	restore := flags.Bool("restore", cfgDefaults.DumpConfig.Restore, "Restore metrics before starting")
-	storeInterval := flags.Uint64("store-interval", cfgDefaults.DumpConfig.StoreInterval, "Store interval in seconds")
-	fileStoragePath := flags.String("file-storage-path", cfgDefaults.DumpConfig.FileStoragePath, "File storage path")
-	databaseDsn := flags.String("database-dsn", cfgDefaults.DatabaseDsn, "Database DSN")
-	key := flags.String("key", cfgDefaults.Key, "Key for signature verification")
-	cryptoKey := flags.String("crypto-key", cfgDefaults.CryptoKey, "Path to RSA private key for request decryption")
-	auditFile := flags.String("audit-file", cfgDefaults.AuditFile, "Audit log file path")
-	auditURL := flags.String("audit-url", cfgDefaults.AuditURL, "Audit log URL")
-	trustedSubnet := flags.String("trusted_subnet", cfgDefaults.TrustedSubnet, "Trusted subnet CIDR")
-	grpcEnabled := flags.Bool("grpc-enabled", cfgDefaults.GrpcEnabled, "Enable gRPC server")
-	grpcAddress := flags.String("grpc-address", cfgDefaults.GrpcAddress, "gRPC server address")
-
-	pprofOnShutdown := flags.Bool("pprof-on-shutdown", cfgDefaults.PprofOnShutdown, "Enable heap profile write on shutdown")
-	pprofDir := flags.String("pprof-dir", cfgDefaults.PprofDir, "Directory to store pprof files")
-	pprofFilename := flags.String("pprof-filename", cfgDefaults.PprofFilename, "Heap profile filename")
-	pprofHTTP := flags.Bool("pprof-http", cfgDefaults.PprofHTTP, "Expose net/http/pprof endpoints")
-	pprofHTTPAddr := flags.String("pprof-http-addr", cfgDefaults.PprofHTTPAddr, "pprof HTTP listen address")
-
-	flags.StringVar(address, "a", *address, "HTTP server address (short)")
-	flags.BoolVar(restore, "r", *restore, "Restore metrics before starting (short)")
-	flags.Uint64Var(storeInterval, "i", *storeInterval, "Store interval in seconds (short)")
-	flags.StringVar(fileStoragePath, "f", *fileStoragePath, "File storage path (short)")
-	flags.StringVar(databaseDsn, "d", *databaseDsn, "Database DSN")
-	flags.StringVar(key, "k", *key, "Key for signature verification (short)")
-	flags.StringVar(trustedSubnet, "t", *trustedSubnet, "Trusted subnet CIDR (short)")
-	flags.BoolVar(grpcEnabled, "g", *grpcEnabled, "Enable gRPC server (short)")
-	flags.StringVar(grpcAddress, "G", *grpcAddress, "gRPC server address (short)")
-
-	filteredArgs := filterArgs(flags, os.Args[1:])
-
-	err := flags.Parse(filteredArgs)
+// LoadConfigReport загружает конфигурацию и возвращает источник каждого значения.
+func LoadConfigReport(args *map[string]any) (*Config, settings.Report, error) {
+	cfg := defaultConfig()
+	loader, err := settings.New(cfg)
 	if err != nil {
-		if strings.Contains(err.Error(), "flag provided but not defined") {
-			return nil, err
-		}
+		return nil, nil, err
 	}
 
-	cfg := &Config{
-		Address:       *address,
-		DatabaseDsn:   *databaseDsn,
-		Key:           *key,
-		CryptoKey:     *cryptoKey,
-		TrustedSubnet: *trustedSubnet,
-		AuditFile:     *auditFile,
-		AuditURL:      *auditURL,
-		DumpConfig: DumpConfig{
-			Restore:         *restore,
-			StoreInterval:   *storeInterval,
-			FileStoragePath: *fileStoragePath,
-		},
-		PprofOnShutdown: *pprofOnShutdown,
-		PprofDir:        *pprofDir,
-		PprofFilename:   *pprofFilename,
-		PprofHTTP:       *pprofHTTP,
-		PprofHTTPAddr:   *pprofHTTPAddr,
-		GrpcEnabled:     *grpcEnabled,
-		GrpcAddress:     *grpcAddress,
+	launch, err := parseFlags(loader)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if v := os.Getenv("ADDRESS"); v != "" {
-		cfg.Address = v
-	}
-	if databaseDsn := os.Getenv("DATABASE_DSN"); databaseDsn != "" {
-		cfg.DatabaseDsn = databaseDsn
-	}
-	if envRestore := os.Getenv("RESTORE"); envRestore != "" {
-		restoreVal, err := strconv.ParseBool(envRestore)
-		if err != nil {
-			return nil, wrapError("ошибка парсинга RESTORE", err)
-		} else {
-			cfg.DumpConfig.Restore = restoreVal
-		}
-	}
-	if envStoreInterval := os.Getenv("STORE_INTERVAL"); envStoreInterval != "" {
-		interval, err := strconv.ParseUint(envStoreInterval, 10, 64)
-		if err != nil {
-			return nil, wrapError("ошибка парсинга STORE_INTERVAL", err)
-		} else {
-			cfg.DumpConfig.StoreInterval = interval
-		}
-	}
-	if envFileStoragePath := os.Getenv("FILE_STORAGE_PATH"); envFileStoragePath != "" {
-		cfg.DumpConfig.FileStoragePath = envFileStoragePath
-	}
-	if envKey := os.Getenv("KEY"); envKey != "" {
-		cfg.Key = envKey
-	}
-	if envCryptoKey := os.Getenv("CRYPTO_KEY"); envCryptoKey != "" {
-		cfg.CryptoKey = envCryptoKey
-	}
-	if envTrustedSubnet := os.Getenv("TRUSTED_SUBNET"); envTrustedSubnet != "" {
-		cfg.TrustedSubnet = envTrustedSubnet
-	}
-	if envAuditFile := os.Getenv("AUDIT_FILE"); envAuditFile != "" {
-		cfg.AuditFile = envAuditFile
-	}
-	if envAuditURL := os.Getenv("AUDIT_URL"); envAuditURL != "" {
-		cfg.AuditURL = envAuditURL
-	}
-	if v := os.Getenv("PPROF_ON_SHUTDOWN"); v != "" {
-		b, err := strconv.ParseBool(v)
-		if err != nil {
-			return nil, wrapError("ошибка парсинга PPROF_ON_SHUTDOWN", err)
-		}
-		cfg.PprofOnShutdown = b
-	}
-	if v := os.Getenv("PPROF_DIR"); v != "" {
-		cfg.PprofDir = v
-	}
-	if v := os.Getenv("PPROF_FILENAME"); v != "" {
-		cfg.PprofFilename = v
-	}
-	if v := os.Getenv("PPROF_HTTP"); v != "" {
-		b, err := strconv.ParseBool(v)
-		if err != nil {
-			return nil, wrapError("ошибка парсинга PPROF_HTTP", err)
-		}
-		cfg.PprofHTTP = b
-	}
-	if v := os.Getenv("PPROF_HTTP_ADDR"); v != "" {
-		cfg.PprofHTTPAddr = v
-	}
-	if v := os.Getenv("GRPC_ENABLED"); v != "" {
-		b, err := strconv.ParseBool(v)
-		if err != nil {
-			return nil, wrapError("ошибка парсинга GRPC_ENABLED", err)
-		}
-		cfg.GrpcEnabled = b
+	if args != nil {
+		loader.WithOverrides(*args)
 	}
-	if v := os.Getenv("GRPC_ADDRESS"); v != "" {
-		cfg.GrpcAddress = v
+	report, err := loader.WithFile(launch.File).Load()
+	if err != nil {
+		return nil, nil, wrapError("ошибка загрузки конфигурации", err)
 	}
 
-	if args != nil {
-		redefineLocal(args, cfg)
+	if err := validate(cfg); err != nil {
+		return nil, nil, err
 	}
 
-	return cfg, nil
+	return cfg, report, nil
+}
+
+// Launch — параметры командной строки, которые управляют запуском, а не входят в конфигурацию.
+type Launch struct {
+	// File — путь к файлу конфигурации из CONFIG или флагов -config/-c; пусто, если файл не задан.
+	File string
+	// PrintConfig — передан флаг -print-config: напечатать конфигурацию и завершиться.
+	PrintConfig bool
 }
 
-func filterArgs(flags *flag.FlagSet, args []string) []string {
-	var filteredArgs []string
-	validFlags := make(map[string]bool)
-
-	flags.VisitAll(func(f *flag.Flag) {
-		validFlags["-"+f.Name] = true
-	})
-
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		if len(arg) > 1 && arg[0] == '-' {
-			eq := strings.Index(arg, "=")
-			flagName := arg
-			if eq != -1 {
-				flagName = arg[:eq]
-			}
-
-			if _, valid := validFlags[flagName]; valid {
-				if eq != -1 {
-					filteredArgs = append(filteredArgs, arg)
-					continue
-				}
-
-				filteredArgs = append(filteredArgs, flagName)
-
-				if i+1 < len(args) && args[i+1][0] != '-' {
-					filteredArgs = append(filteredArgs, args[i+1])
-					i++
-				}
-			}
-		}
+// ParseLaunch разбирает командную строку сервера вместе с флагами конфигурации.
+func ParseLaunch() (Launch, error) {
+	loader, err := settings.New(defaultConfig())
+	if err != nil {
+		return Launch{}, err
 	}
 
-	return filteredArgs
+	return parseFlags(loader)
 }
 
-func redefineLocal(args *map[string]any, cfg *Config) {
-	if val, ok := (*args)["Address"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.Address = strVal
-		}
-	}
+func parseFlags(loader *settings.Loader) (Launch, error) {
+	var launch Launch
 
-	if val, ok := (*args)["DatabaseDsn"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.DatabaseDsn = strVal
-		}
-	}
+	flags := flag.NewFlagSet("app-config", flag.ContinueOnError)
+	loader.DefineFlags(flags)
+	flags.StringVar(&launch.File, "config", "", "Path to config file: .json, .yaml, .yml or .toml")
+	flags.StringVar(&launch.File, "c", "", "Path to config file (short)")
+	flags.BoolVar(&launch.PrintConfig, "print-config", false, "Print effective config with value sources and exit")
 
-	if val, ok := (*args)["DumpConfig.Restore"]; ok {
-		if boolVal, ok := val.(bool); ok {
-			cfg.DumpConfig.Restore = boolVal
-		}
+	if err := flags.Parse(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		return Launch{}, wrapError("ошибка разбора флагов", err)
 	}
 
-	if val, ok := (*args)["DumpConfig.StoreInterval"]; ok {
-		if intValue, ok := val.(uint64); ok {
-			cfg.DumpConfig.StoreInterval = intValue
-		}
+	// В отличие от остальных настроек, переменная CONFIG важнее флагов -config/-c.
+	if v := os.Getenv("CONFIG"); v != "" {
+		launch.File = v
 	}
 
-	if val, ok := (*args)["DumpConfig.FileStoragePath"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.DumpConfig.FileStoragePath = strVal
-		}
-	}
+	return launch, nil
+}
 
-	if val, ok := (*args)["Key"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.Key = strVal
-		}
+func validate(cfg *Config) error {
+	// Как и при перезагрузке: нулевой интервал превратил бы периодическое сохранение в непрерывное.
+	if cfg.DumpConfig.StoreInterval == 0 {
+		return Error("STORE_INTERVAL должен быть положительным")
 	}
 
-	if val, ok := (*args)["TrustedSubnet"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.TrustedSubnet = strVal
-		}
+	if cfg.ReplayConfig.RequireNonce && cfg.ReplayConfig.MaxSkew == 0 {
+		return Error("SIGNATURE_REQUIRE_NONCE требует ненулевого SIGNATURE_MAX_SKEW")
 	}
 
-	if val, ok := (*args)["AuditFile"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.AuditFile = strVal
-		}
+	if cfg.TLSConfig.ClientCAFile != "" && !cfg.TLSConfig.Enabled() {
+		return Error("TLS_CLIENT_CA_FILE требует TLS_CERT_FILE и TLS_KEY_FILE")
 	}
 
-	if val, ok := (*args)["AuditURL"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.AuditURL = strVal
-		}
+	if _, err := zapcore.ParseLevel(cfg.LogLevel); err != nil {
+		return wrapError("неизвестный уровень логирования LOG_LEVEL", err)
 	}
 
-	if val, ok := (*args)["PprofOnShutdown"]; ok {
-		if boolVal, ok := val.(bool); ok {
-			cfg.PprofOnShutdown = boolVal
-		}
-	}
-	if val, ok := (*args)["PprofDir"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.PprofDir = strVal
-		}
-	}
-	if val, ok := (*args)["PprofFilename"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.PprofFilename = strVal
-		}
-	}
-	if val, ok := (*args)["PprofHTTP"]; ok {
-		if boolVal, ok := val.(bool); ok {
-			cfg.PprofHTTP = boolVal
-		}
-	}
-	if val, ok := (*args)["PprofHTTPAddr"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.PprofHTTPAddr = strVal
-		}
+	switch cfg.Cardinality.Overflow {
+	case "reject", "drop", "fold":
+	default:
+		return Error("неизвестная стратегия переполнения CARDINALITY_OVERFLOW: %s", cfg.Cardinality.Overflow)
 	}
-	if val, ok := (*args)["CryptoKey"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.CryptoKey = strVal
-		}
-	}
-	if val, ok := (*args)["GrpcEnabled"]; ok {
-		if boolVal, ok := val.(bool); ok {
-			cfg.GrpcEnabled = boolVal
-		}
-	}
-	if val, ok := (*args)["GrpcAddress"]; ok {
-		if strVal, ok := val.(string); ok {
-			cfg.GrpcAddress = strVal
-		}
+
+	if _, err := security.ParseTrustedProxies(cfg.LimitConfig.TrustedProxies); err != nil {
+		return wrapError("некорректный список подсетей TRUSTED_PROXIES", err)
 	}
-}
 
-func getFileConfigPath() string {
-	if v := os.Getenv("CONFIG"); v != "" {
-		return v
+	if cfg.AuditQueueSize <= 0 || cfg.AuditBatchSize <= 0 {
+		return Error("AUDIT_QUEUE_SIZE и AUDIT_BATCH_SIZE должны быть положительными")
 	}
 
-	args := os.Args[1:]
-	for i := 0; i < len(args); i++ {
-		a := args[i]
-		if strings.HasPrefix(a, "-c=") {
-			return strings.TrimPrefix(a, "-c=")
-		}
-		if strings.HasPrefix(a, "-config=") {
-			return strings.TrimPrefix(a, "-config=")
-		}
-		if a == "-c" || a == "-config" {
-			if i+1 < len(args) {
-				return args[i+1]
-			}
-		}
+	return nil
+}
+
+// PrintConfig печатает итоговую конфигурацию с источником каждого значения; секреты скрыты.
+func PrintConfig(w io.Writer) error {
+	_, report, err := LoadConfigReport(nil)
+	if err != nil {
+		return err
 	}
 
-	return ""
+	_, err = report.WriteTo(w)
+	return err
 }
@@ -33,11 +33,35 @@ func prepareConfigEnv(t *testing.T, configPath string, args ...string) {
 		"TRUSTED_SUBNET",
 		"AUDIT_FILE",
 		"AUDIT_URL",
+		"AUDIT_KEY",
+		"AUDIT_FALLBACK_FILE",
+		"AUDIT_QUEUE_SIZE",
+		"AUDIT_BATCH_SIZE",
 		"PPROF_ON_SHUTDOWN",
 		"PPROF_DIR",
 		"PPROF_FILENAME",
 		"PPROF_HTTP",
 		"PPROF_HTTP_ADDR",
+		"TENANTS_FILE",
+		"RATE_LIMIT_RPS",
+		"RATE_LIMIT_BURST",
+		"MAX_BATCH_SIZE",
+		"MAX_SERIES",
+		"MAX_METRIC_NAMES",
+		"MAX_METRIC_NAMES_PER_PREFIX",
+		"CARDINALITY_OVERFLOW",
+		"JWKS_FILE",
+		"JWT_ISSUER",
+		"JWT_AUDIENCE",
+		"ADMIN_KEY",
+		"TLS_CERT_FILE",
+		"TLS_KEY_FILE",
+		"TLS_CLIENT_CA_FILE",
+		"SIGNATURE_MAX_SKEW",
+		"SIGNATURE_REQUIRE_NONCE",
+		"AGENT_KEYS_DIR",
+		"LOG_LEVEL",
+		"CONFIG_WATCH_INTERVAL",
 	}
 	for _, e := range envs {
 		t.Setenv(e, "")
@@ -123,7 +147,7 @@ func TestLoadConfig_FlagsOverrideFile(t *testing.T) {
 	require.Equal(t, "192.168.0.0/16", cfg.TrustedSubnet)
 }
 
-func TestLoadConfig_EnvOverridesAll(t *testing.T) {
+func TestLoadConfig_EnvOverridesFile(t *testing.T) {
 	v := map[string]any{
 		"Address":       "127.0.0.1:9999",
 		"TrustedSubnet": "10.0.0.0/8",
@@ -135,13 +159,7 @@ func TestLoadConfig_EnvOverridesAll(t *testing.T) {
 	}
 	path := writeJSON(t, v)
 
-	prepareConfigEnv(t, path,
-		"-address=flags:8080",
-		"-restore=false",
-		"-store-interval=777",
-		"-file-storage-path=flags.json",
-		"-trusted_subnet=192.168.0.0/16",
-	)
+	prepareConfigEnv(t, path)
 
 	t.Setenv("ADDRESS", "env:9090")
 	t.Setenv("RESTORE", "true")
@@ -159,6 +177,26 @@ func TestLoadConfig_EnvOverridesAll(t *testing.T) {
 	require.Equal(t, "fd00::/8", cfg.TrustedSubnet)
 }
 
+func TestLoadConfig_FlagsOverrideEnv(t *testing.T) {
+	path := writeJSON(t, map[string]any{"Address": "127.0.0.1:9999"})
+
+	prepareConfigEnv(t, path,
+		"-address=flags:8080",
+		"-store-interval=777",
+	)
+
+	t.Setenv("ADDRESS", "env:9090")
+	t.Setenv("STORE_INTERVAL", "5")
+	t.Setenv("FILE_STORAGE_PATH", "env.json")
+
+	cfg, err := LoadConfig(nil)
+	require.NoError(t, err)
+
+	require.Equal(t, "flags:8080", cfg.Address)
+	require.EqualValues(t, 777, cfg.DumpConfig.StoreInterval)
+	require.Equal(t, "env.json", cfg.DumpConfig.FileStoragePath)
+}
+
 func TestLoadConfig_ConfigPathEnvPreferredOverFlag(t *testing.T) {
 	vEnv := map[string]any{
 		"Address": "env-file:8082",
@@ -197,3 +235,180 @@ func TestLoadConfig_TrustedSubnet_EnvOnly(t *testing.T) {
 
 	require.Equal(t, "2001:db8::/32", cfg.TrustedSubnet)
 }
+
+func TestLoadConfig_Cardinality(t *testing.T) {
+	prepareConfigEnv(t, "", "-max-metric-names=100", "-cardinality-overflow=fold")
+	t.Setenv("MAX_METRIC_NAMES_PER_PREFIX", "10")
+
+	cfg, err := LoadConfig(nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, cfg.Cardinality.MaxNames)
+	require.EqualValues(t, 10, cfg.Cardinality.MaxNamesPerPrefix)
+	require.Equal(t, "fold", cfg.Cardinality.Overflow)
+
+	prepareConfigEnv(t, "")
+	t.Setenv("CARDINALITY_OVERFLOW", "explode")
+	_, err = LoadConfig(nil)
+	require.Error(t, err)
+}
+
+func TestLoadConfig_ReplayProtection(t *testing.T) {
+	prepareConfigEnv(t, "")
+
+	cfg, err := LoadConfig(nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 300, cfg.ReplayConfig.MaxSkew)
+	require.True(t, cfg.ReplayConfig.RequireNonce)
+
+	prepareConfigEnv(t, "", "-signature-max-skew=30")
+	t.Setenv("SIGNATURE_REQUIRE_NONCE", "false")
+	cfg, err = LoadConfig(nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 30, cfg.ReplayConfig.MaxSkew)
+	require.False(t, cfg.ReplayConfig.RequireNonce)
+
+	prepareConfigEnv(t, "", "-signature-max-skew=0")
+	t.Setenv("SIGNATURE_REQUIRE_NONCE", "true")
+	_, err = LoadConfig(nil)
+	require.Error(t, err)
+}
+
+func TestLoadConfig_ReloadSettings(t *testing.T) {
+	prepareConfigEnv(t, "")
+
+	cfg, err := LoadConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, "info", cfg.LogLevel)
+	require.EqualValues(t, 5, cfg.ConfigWatchInterval)
+
+	prepareConfigEnv(t, writeJSON(t, map[string]any{"LogLevel": "warn"}), "-config-watch-interval=0")
+	cfg, err = LoadConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, "warn", cfg.LogLevel)
+	require.EqualValues(t, 0, cfg.ConfigWatchInterval)
+
+	prepareConfigEnv(t, "")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("CONFIG_WATCH_INTERVAL", "30")
+	cfg, err = LoadConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, "debug", cfg.LogLevel)
+	require.EqualValues(t, 30, cfg.ConfigWatchInterval)
+
+	t.Setenv("LOG_LEVEL", "loud")
+	_, err = LoadConfig(nil)
+	require.Error(t, err)
+}
+
+func writeFile(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+	return path
+}
+
+func TestLoadConfig_YAMLAndTOML(t *testing.T) {
+	files := map[string]string{
+		"server.yaml": "Address: ${TEST_HOST}:9999\nDumpConfig:\n  StoreInterval: 60\nCardinality:\n  Overflow: drop\n",
+		"server.toml": "address = \"${TEST_HOST}:9999\"\n\n[dumpConfig]\nstoreInterval = 60\n\n[cardinality]\noverflow = \"drop\"\n",
+	}
+	for name, body := range files {
+		t.Run(name, func(t *testing.T) {
+			prepareConfigEnv(t, writeFile(t, name, body))
+			t.Setenv("TEST_HOST", "yaml-host")
+
+			cfg, err := LoadConfig(nil)
+			require.NoError(t, err)
+			require.Equal(t, "yaml-host:9999", cfg.Address)
+			require.EqualValues(t, 60, cfg.DumpConfig.StoreInterval)
+			require.Equal(t, "drop", cfg.Cardinality.Overflow)
+			require.Equal(t, "metric-storage.json", cfg.DumpConfig.FileStoragePath)
+		})
+	}
+}
+
+func TestLoadConfig_UnknownFileField(t *testing.T) {
+	prepareConfigEnv(t, writeFile(t, "server.yaml", "DumpConfig:\n  StoreIntreval: 60\n"))
+
+	_, err := LoadConfig(nil)
+	require.Error(t, err)
+}
+
+func TestLoadConfigReport_Sources(t *testing.T) {
+	path := writeFile(t, "server.yaml", "Address: file:1\nKey: file-secret\n")
+	prepareConfigEnv(t, path, "-i", "15")
+	t.Setenv("RESTORE", "true")
+
+	_, report, err := LoadConfigReport(nil)
+	require.NoError(t, err)
+
+	expect := map[string]string{
+		"Address":                       "file " + path,
+		"DumpConfig.StoreInterval":      "flag -i",
+		"DumpConfig.Restore":            "env RESTORE",
+		"DumpConfig.FileStoragePath":    "default ",
+		"Cardinality.Overflow":          "default ",
+		"ReplayConfig.RequireNonce":     "default ",
+		"AuthConfig.JWKSFile":           "default ",
+		"LimitConfig.RequestsPerSecond": "default ",
+	}
+	for path, source := range expect {
+		entry, ok := report.Lookup(path)
+		require.True(t, ok, path)
+		require.Equal(t, source, entry.Source+" "+entry.Origin, path)
+	}
+
+	key, _ := report.Lookup("Key")
+	require.NotContains(t, key.Value, "file-secret")
+}
+
+func TestLoadConfig_InvalidFlagValue(t *testing.T) {
+	prepareConfigEnv(t, "", "-store-interval=soon")
+
+	_, err := LoadConfig(nil)
+	require.Error(t, err)
+}
+
+func TestLoadConfig_UnknownFlag(t *testing.T) {
+	prepareConfigEnv(t, "", "-adress", "localhost:9090")
+
+	_, err := LoadConfig(nil)
+	require.Error(t, err)
+}
+
+func TestParseLaunch(t *testing.T) {
+	prepareConfigEnv(t, "", "-a", "", "-c", "server.yaml", "-print-config")
+
+	launch, err := ParseLaunch()
+	require.NoError(t, err)
+	require.Equal(t, Launch{File: "server.yaml", PrintConfig: true}, launch)
+
+	prepareConfigEnv(t, "", "-config=server.toml")
+	launch, err = ParseLaunch()
+	require.NoError(t, err)
+	require.Equal(t, Launch{File: "server.toml"}, launch)
+}
+
+func TestLoadConfig_ZeroStoreInterval(t *testing.T) {
+	prepareConfigEnv(t, "")
+	t.Setenv("STORE_INTERVAL", "0")
+
+	_, err := LoadConfig(nil)
+	require.ErrorContains(t, err, "STORE_INTERVAL")
+}
+
+func TestLoadConfig_RemoteAudit(t *testing.T) {
+	prepareConfigEnv(t, "", "-audit-batch-size", "10")
+	t.Setenv("AUDIT_URL", "http://audit.local/events")
+	t.Setenv("AUDIT_FALLBACK_FILE", "/tmp/audit-fallback.log")
+
+	cfg, err := LoadConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, 1024, cfg.AuditQueueSize)
+	require.Equal(t, 10, cfg.AuditBatchSize)
+	require.Equal(t, "/tmp/audit-fallback.log", cfg.AuditFallbackFile)
+
+	t.Setenv("AUDIT_QUEUE_SIZE", "-1")
+	_, err = LoadConfig(nil)
+	require.Error(t, err)
+}
@@ -7,9 +7,11 @@ import (
 	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
 	config2 "github.com/GoLessons/go-musthave-metrics/internal/config"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/model"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/router"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
 	"github.com/GoLessons/go-musthave-metrics/pkg/container"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
@@ -21,21 +23,35 @@ func InitContainer() (container.Container, error) {
 		return nil, err
 	}
 
-	serverLogger, err := logger.NewLogger(zap.NewProductionConfig())
+	// Уровень логирования общий и меняется при перезагрузке конфигурации без пересоздания логгера.
+	logLevel, err := zap.ParseAtomicLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	loggerConfig := zap.NewProductionConfig()
+	loggerConfig.Level = logLevel
+	serverLogger, err := logger.NewLogger(loggerConfig)
 	if err != nil {
 		return nil, err
 	}
 
 	storageCounter := storage.NewMemStorage[model.Counter]()
 	storageGauge := storage.NewMemStorage[model.Gauge]()
-	metricService := service.NewMetricService(storageCounter, storageGauge)
+	registry := telemetry.NewRegistry()
+	metricService := service.NewMetricService(storageCounter, storageGauge).
+		WithCardinalityGuard(config2.NewCardinalityGuard(cfg)).
+		WithTelemetry(registry)
 
 	services := map[string]any{
 		"logger":         serverLogger,
+		"logLevel":       &logLevel,
 		"config":         cfg,
 		"counterStorage": storageCounter,
 		"gaugeStorage":   storageGauge,
 		"metricService":  metricService,
+		"telemetry":      registry,
+		"health":         health.New(),
+		"startupGate":    &health.Gate{},
 	}
 
 	if cfg.DatabaseDsn != "" {
@@ -56,6 +72,17 @@ func InitContainer() (container.Container, error) {
 	container.SimpleRegisterFactory(&c, "router", router.RouterFactory())
 	container.SimpleRegisterFactory(&c, "dumper", config2.MetricDumperFactory())
 	container.SimpleRegisterFactory(&c, "restorer", config2.MetricRestorerFactory())
+	container.SimpleRegisterFactory(&c, "tenantRegistry", config2.TenantRegistryFactory())
+	container.SimpleRegisterFactory(&c, "tenantServices", config2.TenantMetricServicesFactory())
+	container.SimpleRegisterFactory(&c, "limiter", config2.LimiterFactory())
+	container.SimpleRegisterFactory(&c, "jwtVerifier", config2.JWTVerifierFactory())
+	container.SimpleRegisterFactory(&c, "decrypter", config2.DecrypterFactory())
+	container.SimpleRegisterFactory(&c, "replayGuard", config2.ReplayGuardFactory())
+	container.SimpleRegisterFactory(&c, "agentKeyring", config2.AgentKeyringFactory())
+	container.SimpleRegisterFactory(&c, "auditSubject", config2.AuditSubjectFactory())
+	container.SimpleRegisterFactory(&c, "trustedSubnet", config2.TrustedSubnetFactory())
+	container.SimpleRegisterFactory(&c, "trustedProxies", config2.TrustedProxiesFactory())
+	container.SimpleRegisterFactory(&c, "signer", config2.SignerFactory())
 
 	return c, nil
 }
@@ -0,0 +1,67 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/proto"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/middleware"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// EnvelopeCodec разбирает входящие сообщения как protobuf, предварительно раскрывая конверт
+// шифрования агента. Расшифровка живёт в кодеке, а не в перехватчике, потому что перехватчик
+// получает уже разобранное сообщение. Конверт — JSON и начинается с '{', а сериализованный
+// UpdateMetricsRequest с этого байта начинаться не может (это была бы группа с номером поля 15).
+// При заданном ключе метрики принимаются только в конверте; прочие сообщения (например, пробы
+// health) агент не шифрует, и они разбираются как есть.
+type EnvelopeCodec struct {
+	decrypter *middleware.Decrypter
+	registry  *telemetry.Registry
+}
+
+func NewEnvelopeCodec(decrypter *middleware.Decrypter) *EnvelopeCodec {
+	return &EnvelopeCodec{decrypter: decrypter}
+}
+
+// WithTelemetry включает учёт конвертов, которые не удалось расшифровать.
+func (codecInstance *EnvelopeCodec) WithTelemetry(registry *telemetry.Registry) *EnvelopeCodec {
+	codecInstance.registry = registry
+	return codecInstance
+}
+
+func (codecInstance *EnvelopeCodec) Marshal(value interface{}) ([]byte, error) {
+	messageInstance, ok := value.(protobuf.Message)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal, message is %T, want proto.Message", value)
+	}
+	return protobuf.Marshal(messageInstance)
+}
+
+func (codecInstance *EnvelopeCodec) Unmarshal(data []byte, value interface{}) error {
+	messageInstance, ok := value.(protobuf.Message)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal, message is %T, want proto.Message", value)
+	}
+	if len(data) > 0 && data[0] == '{' {
+		if codecInstance.decrypter == nil {
+			codecInstance.registry.DecryptFailed(telemetry.TransportGRPC)
+			return errors.New("encrypted payload is not supported")
+		}
+		plainData, err := codecInstance.decrypter.Decrypt(data)
+		if err != nil {
+			codecInstance.registry.DecryptFailed(telemetry.TransportGRPC)
+			return fmt.Errorf("failed to decrypt payload: %w", err)
+		}
+		data = plainData
+	} else if _, isMetrics := value.(*proto.UpdateMetricsRequest); isMetrics && codecInstance.decrypter != nil {
+		codecInstance.registry.DecryptFailed(telemetry.TransportGRPC)
+		return errors.New("plaintext payload is not allowed, encryption is required")
+	}
+	return protobuf.Unmarshal(data, messageInstance)
+}
+
+func (codecInstance *EnvelopeCodec) Name() string {
+	return "proto"
+}
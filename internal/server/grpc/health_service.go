@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/proto"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// HealthGRPCService отвечает по стандартному протоколу grpc.health.v1 по тем же проверкам, что и /readyz.
+// Watch не поддерживается.
+type HealthGRPCService struct {
+	grpc_health_v1.UnimplementedHealthServer
+	health *health.Health
+}
+
+func NewHealthGRPCService(healthInstance *health.Health) *HealthGRPCService {
+	return &HealthGRPCService{health: healthInstance}
+}
+
+func (serviceInstance *HealthGRPCService) Check(contextInstance context.Context, requestInstance *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	switch requestInstance.GetService() {
+	case "", proto.Metrics_ServiceDesc.ServiceName:
+	default:
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	statusValue := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	if serviceInstance.health.Check(contextInstance).Ready() {
+		statusValue = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: statusValue}, nil
+}
+
+// exceptHealth пропускает проверки здоровья мимо перехватчика: оркестратор не подписывает запросы
+// и не передаёт токен.
+func exceptHealth(interceptorInstance gogrpc.UnaryServerInterceptor) gogrpc.UnaryServerInterceptor {
+	prefix := "/" + grpc_health_v1.Health_ServiceDesc.ServiceName + "/"
+	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(infoInstance.FullMethod, prefix) {
+			return handlerFunction(contextInstance, requestInstance)
+		}
+		return interceptorInstance(contextInstance, requestInstance, infoInstance, handlerFunction)
+	}
+}
@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealthGRPCService_FollowsReadiness(t *testing.T) {
+	healthInstance := health.New()
+	restoreState := healthInstance.State("restore")
+	serviceInstance := NewHealthGRPCService(healthInstance)
+
+	for _, serviceName := range []string{"", "metrics.Metrics"} {
+		responseInstance, err := serviceInstance.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: serviceName})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if responseInstance.Status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+			t.Fatalf("expected NOT_SERVING for %q before restore, got %v", serviceName, responseInstance.Status)
+		}
+	}
+
+	restoreState.Set(nil)
+	responseInstance, err := serviceInstance.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if responseInstance.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("expected SERVING, got %v", responseInstance.Status)
+	}
+
+	_, err = serviceInstance.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "unknown.Service"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
+
+func TestExceptHealth_SkipsInterceptorForHealth(t *testing.T) {
+	rejecting := func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+	}
+	interceptorInstance := exceptHealth(rejecting)
+	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	responseInstance, err := interceptorInstance(context.Background(), "req", &gogrpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handlerFunction)
+	if err != nil || responseInstance != "ok" {
+		t.Fatalf("health check must bypass the interceptor, got %v, %v", responseInstance, err)
+	}
+
+	_, err = interceptorInstance(context.Background(), "req", &gogrpc.UnaryServerInfo{FullMethod: "/metrics.Metrics/UpdateMetrics"}, handlerFunction)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected interceptor to run for metrics RPC, got %v", err)
+	}
+}
@@ -2,19 +2,29 @@ package grpc
 
 import (
 	"context"
-	"net/netip"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/proto"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/auth"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/limiter"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
 	"go.uber.org/zap"
 	gogrpc "google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
-func LoggingInterceptor(logger *zap.Logger) gogrpc.UnaryServerInterceptor {
+// LoggingInterceptor логирует вызовы и, если передан реестр, учитывает их число и длительность по методу.
+func LoggingInterceptor(logger *zap.Logger, registry *telemetry.Registry) gogrpc.UnaryServerInterceptor {
 	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
 		startTime := time.Now()
 		responseInstance, err := handlerFunction(contextInstance, requestInstance)
@@ -30,21 +40,23 @@ func LoggingInterceptor(logger *zap.Logger) gogrpc.UnaryServerInterceptor {
 				zap.String("code", statusCode.String()),
 			)
 		}
+		registry.ObserveGRPC(infoInstance.FullMethod, statusCode.String(), time.Since(startTime))
 		return responseInstance, err
 	}
 }
 
 func TrustedSubnetInterceptor(trustedCIDR string, logger *zap.Logger) gogrpc.UnaryServerInterceptor {
-	var cidrPrefix netip.Prefix
-	var checkEnabled bool
-	if trustedCIDR != "" {
-		prefix, err := security.ParseTrustedCIDR(trustedCIDR)
-		if err == nil {
-			cidrPrefix = prefix
-			checkEnabled = true
-		}
+	subnetInstance, err := security.NewTrustedSubnet(trustedCIDR)
+	if err != nil {
+		subnetInstance = &security.TrustedSubnet{}
 	}
+	return TrustedSubnetGuardInterceptor(subnetInstance, logger)
+}
+
+// TrustedSubnetGuardInterceptor проверяет x-real-ip по общей подсети; пока она пуста, вызовы пропускаются.
+func TrustedSubnetGuardInterceptor(subnetInstance *security.TrustedSubnet, logger *zap.Logger) gogrpc.UnaryServerInterceptor {
 	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		cidrPrefix, checkEnabled := subnetInstance.Prefix()
 		if !checkEnabled {
 			return handlerFunction(contextInstance, requestInstance)
 		}
@@ -76,3 +88,241 @@ func TrustedSubnetInterceptor(trustedCIDR string, logger *zap.Logger) gogrpc.Una
 		return handlerFunction(contextInstance, requestInstance)
 	}
 }
+
+func TenantInterceptor(registry *tenant.Registry, logger *zap.Logger) gogrpc.UnaryServerInterceptor {
+	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		metadataInstance, ok := metadata.FromIncomingContext(contextInstance)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+		values := metadataInstance.Get(strings.ToLower(tenant.APIKeyHeader))
+		apiKey := ""
+		if len(values) > 0 {
+			apiKey = strings.TrimSpace(values[0])
+		}
+		tenantInstance, ok := registry.ByAPIKey(apiKey)
+		if !ok {
+			if logger != nil && apiKey != "" {
+				logger.Warn("unknown api key", zap.String("method", infoInstance.FullMethod))
+			}
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+		return handlerFunction(tenant.NewContext(contextInstance, tenantInstance), requestInstance)
+	}
+}
+
+// RateLimitInterceptor ограничивает частоту запросов и размер пачки; квоту на серии учитывает SeriesLimitInterceptor.
+func RateLimitInterceptor(limiterInstance *limiter.Limiter, proxiesInstance *security.TrustedProxies, logger *zap.Logger) gogrpc.UnaryServerInterceptor {
+	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		key := limitKey(contextInstance, proxiesInstance)
+		if ok, wait := limiterInstance.Allow(key); !ok {
+			return nil, rejectByLimiter(contextInstance, logger, key, "rate limit exceeded", wait)
+		}
+		if updateRequest, ok := requestInstance.(*proto.UpdateMetricsRequest); ok {
+			if !limiterInstance.AllowBatch(len(updateRequest.Metrics)) {
+				return nil, rejectByLimiter(contextInstance, logger, key, "batch size limit exceeded", time.Minute)
+			}
+		}
+		return handlerFunction(contextInstance, requestInstance)
+	}
+}
+
+// SeriesLimitInterceptor учитывает серии пачки в квоте клиента. Ставится после проверки подписей,
+// как LimitMetrics в HTTP: иначе неподписанные или поддельные запросы навсегда расходовали бы чужую квоту.
+func SeriesLimitInterceptor(limiterInstance *limiter.Limiter, proxiesInstance *security.TrustedProxies, logger *zap.Logger) gogrpc.UnaryServerInterceptor {
+	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		if updateRequest, ok := requestInstance.(*proto.UpdateMetricsRequest); ok {
+			key := limitKey(contextInstance, proxiesInstance)
+			series := make([]string, 0, len(updateRequest.Metrics))
+			for _, protoMetric := range updateRequest.Metrics {
+				series = append(series, limiter.SeriesKey(strings.ToLower(protoMetric.Type.String()), protoMetric.Id))
+			}
+			if !limiterInstance.AdmitSeries(key, series) {
+				return nil, rejectByLimiter(contextInstance, logger, key, "series limit exceeded", time.Minute)
+			}
+		}
+		return handlerFunction(contextInstance, requestInstance)
+	}
+}
+
+// StartupGateInterceptor отвечает Unavailable, пока не завершены миграции и восстановление состояния.
+func StartupGateInterceptor(gateInstance *health.Gate) gogrpc.UnaryServerInterceptor {
+	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		if !gateInstance.IsOpen() {
+			return nil, status.Error(codes.Unavailable, "server is starting")
+		}
+		return handlerFunction(contextInstance, requestInstance)
+	}
+}
+
+func rejectByLimiter(contextInstance context.Context, logger *zap.Logger, key string, reason string, wait time.Duration) error {
+	if logger != nil {
+		logger.Warn("grpc request rejected by limiter", zap.String("client", key), zap.String("reason", reason))
+	}
+	_ = gogrpc.SetHeader(contextInstance, metadata.Pairs("retry-after", strconv.Itoa(limiter.RetryAfterSeconds(wait))))
+	return status.Error(codes.ResourceExhausted, "Too Many Requests")
+}
+
+func limitKey(contextInstance context.Context, proxiesInstance *security.TrustedProxies) string {
+	if tenantID := tenant.IDFromContext(contextInstance); tenantID != "" {
+		return "tenant:" + tenantID
+	}
+	if address := clientAddress(contextInstance, proxiesInstance); address != "" {
+		return "ip:" + address
+	}
+	return "ip:unknown"
+}
+
+// clientAddress возвращает адрес клиента; x-real-ip учитывается только для вызовов через доверенные прокси.
+func clientAddress(contextInstance context.Context, proxiesInstance *security.TrustedProxies) string {
+	peerInstance, ok := peer.FromContext(contextInstance)
+	if !ok || peerInstance.Addr == nil {
+		return ""
+	}
+	realIP := ""
+	if metadataInstance, ok := metadata.FromIncomingContext(contextInstance); ok {
+		if values := metadataInstance.Get("x-real-ip"); len(values) > 0 {
+			realIP = values[0]
+		}
+	}
+	return proxiesInstance.ClientIP(peerInstance.Addr.String(), realIP)
+}
+
+// MethodScopes задаёт право, необходимое для вызова каждого RPC.
+var MethodScopes = map[string]string{
+	proto.Metrics_UpdateMetrics_FullMethodName: auth.ScopeWrite,
+}
+
+// AuthInterceptor проверяет JWT из метаданных authorization; для RPC без явного права требуется metrics:admin.
+func AuthInterceptor(verifierInstance *auth.Verifier, logger *zap.Logger) gogrpc.UnaryServerInterceptor {
+	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		metadataInstance, ok := metadata.FromIncomingContext(contextInstance)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+		}
+		values := metadataInstance.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+		}
+		token, ok := auth.BearerToken(values[0])
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+		}
+		claims, err := verifierInstance.Verify(token)
+		if err != nil {
+			if logger != nil {
+				logger.Warn("grpc bearer token rejected", zap.String("method", infoInstance.FullMethod), zap.Error(err))
+			}
+			return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+		}
+		scope, ok := MethodScopes[infoInstance.FullMethod]
+		if !ok {
+			scope = auth.ScopeAdmin
+		}
+		if !claims.HasScope(scope) {
+			return nil, status.Error(codes.PermissionDenied, "Insufficient Scope")
+		}
+		return handlerFunction(auth.NewContext(contextInstance, claims), requestInstance)
+	}
+}
+
+// SignatureInterceptor проверяет HMAC-SHA256 запроса из метаданных hashsha256 секретом арендатора
+// или общим секретом сервера, а затем метку времени и nonce. Как и в HTTP, запрос без подписи отклоняется,
+// если для него задан секрет и он не подписан ключом агента.
+func SignatureInterceptor(signerInstance *signature.Signer, replayGuardInstance *security.ReplayGuard, logger *zap.Logger, registry *telemetry.Registry) gogrpc.UnaryServerInterceptor {
+	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		requestSigner := signerInstance
+		if tenantInstance, ok := tenant.FromContext(contextInstance); ok && tenantInstance.Signer() != nil {
+			requestSigner = tenantInstance.Signer()
+		}
+		messageInstance, ok := requestInstance.(protobuf.Message)
+		if requestSigner == nil || !ok {
+			return handlerFunction(contextInstance, requestInstance)
+		}
+		metadataInstance, _ := metadata.FromIncomingContext(contextInstance)
+		values := metadataInstance.Get(proto.SignatureMetadataKey)
+		if len(values) == 0 {
+			if security.AgentFromContext(contextInstance) != "" {
+				return handlerFunction(contextInstance, requestInstance)
+			}
+			if logger != nil {
+				logger.Warn("grpc signature is missing", zap.String("method", infoInstance.FullMethod))
+			}
+			registry.SignatureFailed(telemetry.TransportGRPC, telemetry.CheckHMAC)
+			return nil, status.Error(codes.InvalidArgument, "Signature is missing")
+		}
+		payload, err := proto.SigningBytes(messageInstance)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "Internal Server Error")
+		}
+		timestamp := firstMetadataValue(metadataInstance, strings.ToLower(signature.TimestampHeader))
+		nonce := firstMetadataValue(metadataInstance, strings.ToLower(signature.NonceHeader))
+		if timestamp != "" || nonce != "" {
+			payload = signature.SignedPayload(timestamp, nonce, payload)
+		}
+		if !requestSigner.Check(values[0], payload) {
+			if logger != nil {
+				logger.Warn("grpc invalid signature", zap.String("method", infoInstance.FullMethod))
+			}
+			registry.SignatureFailed(telemetry.TransportGRPC, telemetry.CheckHMAC)
+			return nil, status.Error(codes.InvalidArgument, "Invalid signature")
+		}
+		// Если запрос уже подписан ключом агента, nonce израсходован при той проверке.
+		if security.AgentFromContext(contextInstance) != "" {
+			return handlerFunction(contextInstance, requestInstance)
+		}
+		if err := replayGuardInstance.Check(timestamp, nonce); err != nil {
+			if logger != nil {
+				logger.Warn("grpc signed request rejected", zap.String("method", infoInstance.FullMethod), zap.Error(err))
+			}
+			registry.SignatureFailed(telemetry.TransportGRPC, telemetry.CheckReplay)
+			return nil, status.Error(codes.InvalidArgument, "Invalid signature")
+		}
+		return handlerFunction(contextInstance, requestInstance)
+	}
+}
+
+// AgentSignatureInterceptor требует подпись запроса ключом зарегистрированного агента
+// и кладёт имя агента в контекст.
+func AgentSignatureInterceptor(agentKeyringInstance *security.AgentKeyring, replayGuardInstance *security.ReplayGuard, logger *zap.Logger, registry *telemetry.Registry) gogrpc.UnaryServerInterceptor {
+	return func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		metadataInstance, _ := metadata.FromIncomingContext(contextInstance)
+		timestamp := firstMetadataValue(metadataInstance, strings.ToLower(signature.TimestampHeader))
+		nonce := firstMetadataValue(metadataInstance, strings.ToLower(signature.NonceHeader))
+		messageInstance, ok := requestInstance.(protobuf.Message)
+		if !ok || timestamp == "" || nonce == "" {
+			return nil, rejectAgent(logger, registry, infoInstance.FullMethod, security.ErrReplayMissing)
+		}
+		payload, err := proto.SigningBytes(messageInstance)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "Internal Server Error")
+		}
+		agentName, err := agentKeyringInstance.Verify(
+			firstMetadataValue(metadataInstance, strings.ToLower(signature.AgentKeyIDHeader)),
+			signature.SignedPayload(timestamp, nonce, payload),
+			firstMetadataValue(metadataInstance, strings.ToLower(signature.AgentSignatureHeader)),
+		)
+		if err != nil {
+			return nil, rejectAgent(logger, registry, infoInstance.FullMethod, err)
+		}
+		if err := replayGuardInstance.Check(timestamp, nonce); err != nil {
+			return nil, rejectAgent(logger, registry, infoInstance.FullMethod, err)
+		}
+		return handlerFunction(security.NewAgentContext(contextInstance, agentName), requestInstance)
+	}
+}
+
+func rejectAgent(logger *zap.Logger, registry *telemetry.Registry, method string, err error) error {
+	registry.SignatureFailed(telemetry.TransportGRPC, telemetry.CheckAgent)
+	if logger != nil {
+		logger.Warn("grpc agent signature rejected", zap.String("method", method), zap.Error(err))
+	}
+	return status.Error(codes.Unauthenticated, "Unauthenticated")
+}
+
+func firstMetadataValue(metadataInstance metadata.MD, key string) string {
+	if values := metadataInstance.Get(key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
@@ -2,15 +2,27 @@ package grpc
 
 import (
 	"context"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/proto"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/auth"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/limiter"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
 	"go.uber.org/zap"
 	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 func TestLoggingInterceptor_PassesThrough(t *testing.T) {
-	interceptorInstance := LoggingInterceptor(zap.NewNop())
+	interceptorInstance := LoggingInterceptor(zap.NewNop(), nil)
 	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
 		return "ok", nil
 	}
@@ -85,3 +97,169 @@ func TestTrustedSubnetInterceptor_UntrustedIP_ReturnsPermissionDenied(t *testing
 		t.Fatalf("unexpected response: %v", responseInstance)
 	}
 }
+
+func TestTenantInterceptor_ResolvesTenant(t *testing.T) {
+	registryInstance, err := tenant.NewRegistry(tenant.Tenant{ID: "team-a", APIKey: "key-a"})
+	if err != nil {
+		t.Fatalf("registry error: %v", err)
+	}
+	interceptorInstance := TenantInterceptor(registryInstance, zap.NewNop())
+	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+		return tenant.IDFromContext(contextInstance), nil
+	}
+	infoInstance := &gogrpc.UnaryServerInfo{FullMethod: "/x"}
+	metadataContext := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "key-a"))
+	responseInstance, err := interceptorInstance(metadataContext, "req", infoInstance, handlerFunction)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if responseInstance != "team-a" {
+		t.Fatalf("unexpected tenant: %v", responseInstance)
+	}
+}
+
+func TestTenantInterceptor_UnknownKey_ReturnsUnauthenticated(t *testing.T) {
+	registryInstance, err := tenant.NewRegistry(tenant.Tenant{ID: "team-a", APIKey: "key-a"})
+	if err != nil {
+		t.Fatalf("registry error: %v", err)
+	}
+	interceptorInstance := TenantInterceptor(registryInstance, zap.NewNop())
+	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	infoInstance := &gogrpc.UnaryServerInfo{FullMethod: "/x"}
+	metadataContext := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "other"))
+	_, err = interceptorInstance(metadataContext, "req", infoInstance, handlerFunction)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+}
+
+func TestStartupGateInterceptor_UnavailableUntilOpen(t *testing.T) {
+	gateInstance := &health.Gate{}
+	interceptorInstance := StartupGateInterceptor(gateInstance)
+	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	infoInstance := &gogrpc.UnaryServerInfo{FullMethod: proto.Metrics_UpdateMetrics_FullMethodName}
+
+	_, err := interceptorInstance(context.Background(), "req", infoInstance, handlerFunction)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable, got %v", err)
+	}
+	gateInstance.Open()
+	if _, err := interceptorInstance(context.Background(), "req", infoInstance, handlerFunction); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRateLimitInterceptor_RateExceeded_ReturnsResourceExhausted(t *testing.T) {
+	interceptorInstance := RateLimitInterceptor(limiter.New(limiter.Config{RequestsPerSecond: 1, Burst: 1}), nil, zap.NewNop())
+	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	infoInstance := &gogrpc.UnaryServerInfo{FullMethod: "/x"}
+	metadataContext := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-real-ip", "10.0.0.1"))
+	if _, err := interceptorInstance(metadataContext, "req", infoInstance, handlerFunction); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err := interceptorInstance(metadataContext, "req", infoInstance, handlerFunction)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+}
+
+func TestClientAddress_TrustsRealIPOnlyFromProxies(t *testing.T) {
+	peerContext := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.168.1.5"), Port: 4321}})
+	metadataContext := metadata.NewIncomingContext(peerContext, metadata.Pairs("x-real-ip", "10.0.0.7"))
+
+	if address := clientAddress(metadataContext, nil); address != "192.168.1.5" {
+		t.Fatalf("x-real-ip from untrusted peer must be ignored, got %s", address)
+	}
+	proxiesInstance, err := security.ParseTrustedProxies("192.168.1.0/24")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if address := clientAddress(metadataContext, proxiesInstance); address != "10.0.0.7" {
+		t.Fatalf("x-real-ip from trusted proxy must be used, got %s", address)
+	}
+}
+
+func TestRateLimitInterceptor_BatchTooLarge_ReturnsResourceExhausted(t *testing.T) {
+	interceptorInstance := RateLimitInterceptor(limiter.New(limiter.Config{MaxBatchSize: 1}), nil, zap.NewNop())
+	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	infoInstance := &gogrpc.UnaryServerInfo{FullMethod: "/metrics.Metrics/UpdateMetrics"}
+	requestInstance := &proto.UpdateMetricsRequest{
+		Metrics: []*proto.Metric{
+			{Id: "g1", Type: proto.Metric_GAUGE, Value: 1},
+			{Id: "g2", Type: proto.Metric_GAUGE, Value: 2},
+		},
+	}
+	_, err := interceptorInstance(context.Background(), requestInstance, infoInstance, handlerFunction)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+}
+
+func TestSeriesLimitInterceptor_ForgedRequestDoesNotSpendQuota(t *testing.T) {
+	limiterInstance := limiter.New(limiter.Config{MaxSeries: 1})
+	signerInstance := signature.NewSign("secret")
+	signatureInstance := SignatureInterceptor(signerInstance, security.NewReplayGuard(time.Minute, true), zap.NewNop(), nil)
+	seriesInstance := SeriesLimitInterceptor(limiterInstance, nil, zap.NewNop())
+	// Порядок как в server_factory: сначала подпись, затем квота на серии.
+	interceptorInstance := func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+		return signatureInstance(contextInstance, requestInstance, infoInstance, func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+			return seriesInstance(contextInstance, requestInstance, infoInstance, handlerFunction)
+		})
+	}
+	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	infoInstance := &gogrpc.UnaryServerInfo{FullMethod: proto.Metrics_UpdateMetrics_FullMethodName}
+	peerContext := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4321}})
+	signedContext := func(requestInstance *proto.UpdateMetricsRequest, secret string) context.Context {
+		payload, err := proto.SigningBytes(requestInstance)
+		if err != nil {
+			t.Fatalf("signing bytes: %v", err)
+		}
+		timestamp, nonce, err := signature.NewNonce(time.Now())
+		if err != nil {
+			t.Fatalf("nonce: %v", err)
+		}
+		hash, err := signature.NewSign(secret).Hash(signature.SignedPayload(timestamp, nonce, payload))
+		if err != nil {
+			t.Fatalf("hash: %v", err)
+		}
+		return metadata.NewIncomingContext(peerContext, metadata.Pairs(
+			proto.SignatureMetadataKey, hash,
+			"x-signature-timestamp", timestamp,
+			"x-signature-nonce", nonce,
+		))
+	}
+
+	forged := &proto.UpdateMetricsRequest{Metrics: []*proto.Metric{{Id: "forged", Type: proto.Metric_GAUGE, Value: 1}}}
+	_, err := interceptorInstance(signedContext(forged, "wrong"), forged, infoInstance, handlerFunction)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+
+	genuine := &proto.UpdateMetricsRequest{Metrics: []*proto.Metric{{Id: "genuine", Type: proto.Metric_GAUGE, Value: 1}}}
+	if _, err := interceptorInstance(signedContext(genuine, "secret"), genuine, infoInstance, handlerFunction); err != nil {
+		t.Fatalf("forged request must not spend the series quota: %v", err)
+	}
+}
+
+func TestAuthInterceptor_MissingToken_ReturnsUnauthenticated(t *testing.T) {
+	interceptorInstance := AuthInterceptor(auth.NewVerifier(&auth.KeySet{}, "", ""), zap.NewNop())
+	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	infoInstance := &gogrpc.UnaryServerInfo{FullMethod: proto.Metrics_UpdateMetrics_FullMethodName}
+	metadataContext := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic abc"))
+	_, err := interceptorInstance(metadataContext, "req", infoInstance, handlerFunction)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+}
@@ -2,30 +2,36 @@ package grpc
 
 import (
 	"context"
+	"errors"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/proto"
 	"github.com/GoLessons/go-musthave-metrics/internal/proto/convert"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type MetricsGRPCService struct {
 	proto.UnimplementedMetricsServer
-	metricService *service.MetricService
+	services *service.TenantMetricServices
 }
 
-func NewMetricsGRPCService(metricService *service.MetricService) *MetricsGRPCService {
-	return &MetricsGRPCService{metricService: metricService}
+func NewMetricsGRPCService(services *service.TenantMetricServices) *MetricsGRPCService {
+	return &MetricsGRPCService{services: services}
 }
 
 func (serviceInstance *MetricsGRPCService) UpdateMetrics(contextInstance context.Context, requestInstance *proto.UpdateMetricsRequest) (*proto.UpdateMetricsResponse, error) {
+	metricService := serviceInstance.services.ForTenant(tenant.IDFromContext(contextInstance))
 	for _, protoMetric := range requestInstance.Metrics {
 		metric, err := convert.ProtoToModel(protoMetric)
 		if err != nil {
 			return nil, status.Error(codes.InvalidArgument, "Bad Request")
 		}
-		if err := serviceInstance.metricService.Save(metric); err != nil {
+		if err := metricService.SaveFrom(metric, clientAddress(contextInstance, nil)); err != nil {
+			if errors.Is(err, service.ErrCardinalityLimit) {
+				return nil, status.Error(codes.ResourceExhausted, "Cardinality Limit Exceeded")
+			}
 			return nil, status.Error(codes.InvalidArgument, "Bad Request")
 		}
 	}
@@ -16,7 +16,7 @@ func TestMetricsGRPCService_UpdateMetrics_Success(t *testing.T) {
 	counterStorage := storage.NewMemStorage[serverModel.Counter]()
 	gaugeStorage := storage.NewMemStorage[serverModel.Gauge]()
 	metricService := service.NewMetricService(counterStorage, gaugeStorage)
-	serviceInstance := NewMetricsGRPCService(metricService)
+	serviceInstance := NewMetricsGRPCService(service.NewTenantMetricServices(metricService))
 
 	requestInstance := &proto.UpdateMetricsRequest{
 		Metrics: []*proto.Metric{
@@ -41,7 +41,7 @@ func TestMetricsGRPCService_UpdateMetrics_InvalidType_ReturnsInvalidArgument(t *
 	counterStorage := storage.NewMemStorage[serverModel.Counter]()
 	gaugeStorage := storage.NewMemStorage[serverModel.Gauge]()
 	metricService := service.NewMetricService(counterStorage, gaugeStorage)
-	serviceInstance := NewMetricsGRPCService(metricService)
+	serviceInstance := NewMetricsGRPCService(service.NewTenantMetricServices(metricService))
 
 	requestInstance := &proto.UpdateMetricsRequest{
 		Metrics: []*proto.Metric{
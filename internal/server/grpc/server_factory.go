@@ -1,12 +1,23 @@
 package grpc
 
 import (
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/common/tlsconfig"
 	"github.com/GoLessons/go-musthave-metrics/internal/proto"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/auth"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/limiter"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/middleware"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
 	"github.com/GoLessons/go-musthave-metrics/pkg/container"
 	"go.uber.org/zap"
 	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func BuildGRPCServer(containerInstance container.Container) (*gogrpc.Server, error) {
@@ -22,20 +33,124 @@ func BuildGRPCServer(containerInstance container.Container) (*gogrpc.Server, err
 	if err != nil {
 		return nil, err
 	}
+	tenantServicesInstance := service.NewTenantMetricServices(metricServiceInstance)
+	if servicesInstance, err := container.GetService[service.TenantMetricServices](containerInstance, "tenantServices"); err == nil {
+		tenantServicesInstance = servicesInstance
+	}
 
-	interceptorList := []gogrpc.UnaryServerInterceptor{LoggingInterceptor(loggerInstance)}
-	if configInstance.TrustedSubnet != "" {
-		interceptorList = append(interceptorList, TrustedSubnetInterceptor(configInstance.TrustedSubnet, loggerInstance))
+	var registryInstance *telemetry.Registry
+	if telemetryInstance, err := container.GetService[telemetry.Registry](containerInstance, "telemetry"); err == nil {
+		registryInstance = telemetryInstance
 	}
 
-	var serverInstance *gogrpc.Server
+	interceptorList := []gogrpc.UnaryServerInterceptor{LoggingInterceptor(loggerInstance, registryInstance)}
+	if gateInstance, err := container.GetService[health.Gate](containerInstance, "startupGate"); err == nil {
+		interceptorList = append(interceptorList, StartupGateInterceptor(gateInstance))
+	}
+	trustedSubnetInstance, err := container.GetService[security.TrustedSubnet](containerInstance, "trustedSubnet")
+	if err != nil {
+		if trustedSubnetInstance, err = security.NewTrustedSubnet(configInstance.TrustedSubnet); err != nil {
+			return nil, err
+		}
+	}
+	// Подключается всегда: пустая подсеть пропускает запросы, а задать её можно перезагрузкой конфигурации.
+	interceptorList = append(interceptorList, TrustedSubnetGuardInterceptor(trustedSubnetInstance, loggerInstance))
+	if configInstance.AuthConfig.JWKSFile != "" {
+		verifierInstance, err := container.GetService[auth.Verifier](containerInstance, "jwtVerifier")
+		if err != nil {
+			return nil, err
+		}
+		interceptorList = append(interceptorList, AuthInterceptor(verifierInstance, loggerInstance))
+	}
+	if configInstance.TenantsFile != "" {
+		registryInstance, err := container.GetService[tenant.Registry](containerInstance, "tenantRegistry")
+		if err != nil {
+			return nil, err
+		}
+		interceptorList = append(interceptorList, TenantInterceptor(registryInstance, loggerInstance))
+	}
+	var limiterInstance *limiter.Limiter
+	var proxiesInstance *security.TrustedProxies
+	if configInstance.LimitConfig.Enabled() {
+		limiterInstance, err = container.GetService[limiter.Limiter](containerInstance, "limiter")
+		if err != nil {
+			return nil, err
+		}
+		proxiesInstance, err = container.GetService[security.TrustedProxies](containerInstance, "trustedProxies")
+		if err != nil {
+			if proxiesInstance, err = security.ParseTrustedProxies(configInstance.LimitConfig.TrustedProxies); err != nil {
+				return nil, err
+			}
+		}
+		interceptorList = append(interceptorList, RateLimitInterceptor(limiterInstance, proxiesInstance, loggerInstance))
+	}
+	if configInstance.AgentKeysDir != "" {
+		agentKeyringInstance, err := container.GetService[security.AgentKeyring](containerInstance, "agentKeyring")
+		if err != nil {
+			return nil, err
+		}
+		replayGuardInstance, err := container.GetService[security.ReplayGuard](containerInstance, "replayGuard")
+		if err != nil {
+			return nil, err
+		}
+		interceptorList = append(interceptorList, AgentSignatureInterceptor(agentKeyringInstance, replayGuardInstance, loggerInstance, registryInstance))
+	}
+	if configInstance.Key != "" || configInstance.TenantsFile != "" {
+		var signerInstance *signature.Signer
+		if configInstance.Key != "" {
+			signerInstance = signature.NewSign(configInstance.Key)
+			if sharedSignerInstance, err := container.GetService[signature.Signer](containerInstance, "signer"); err == nil {
+				signerInstance = sharedSignerInstance
+			}
+		}
+		replayGuardInstance, err := container.GetService[security.ReplayGuard](containerInstance, "replayGuard")
+		if err != nil {
+			return nil, err
+		}
+		interceptorList = append(interceptorList, SignatureInterceptor(signerInstance, replayGuardInstance, loggerInstance, registryInstance))
+	}
+	if limiterInstance != nil {
+		interceptorList = append(interceptorList, SeriesLimitInterceptor(limiterInstance, proxiesInstance, loggerInstance))
+	}
+
+	// Логирование стоит первым и учитывает проверки здоровья, остальные перехватчики их пропускают.
+	for index := 1; index < len(interceptorList); index++ {
+		interceptorList[index] = exceptHealth(interceptorList[index])
+	}
+
+	var serverOptions []gogrpc.ServerOption
 	if len(interceptorList) == 1 {
-		serverInstance = gogrpc.NewServer(gogrpc.UnaryInterceptor(interceptorList[0]))
+		serverOptions = append(serverOptions, gogrpc.UnaryInterceptor(interceptorList[0]))
 	} else {
-		serverInstance = gogrpc.NewServer(gogrpc.ChainUnaryInterceptor(interceptorList...))
+		serverOptions = append(serverOptions, gogrpc.ChainUnaryInterceptor(interceptorList...))
+	}
+	if configInstance.CryptoKey != "" {
+		decrypterInstance, err := container.GetService[middleware.Decrypter](containerInstance, "decrypter")
+		if err != nil {
+			return nil, err
+		}
+		serverOptions = append(serverOptions, gogrpc.ForceServerCodec(NewEnvelopeCodec(decrypterInstance).WithTelemetry(registryInstance)))
+	}
+	if configInstance.TLSConfig.Enabled() {
+		tlsConfigInstance, err := tlsconfig.NewServerConfig(tlsconfig.Options{
+			CertFile: configInstance.TLSConfig.CertFile,
+			KeyFile:  configInstance.TLSConfig.KeyFile,
+			CAFile:   configInstance.TLSConfig.ClientCAFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+		serverOptions = append(serverOptions, gogrpc.Creds(credentials.NewTLS(tlsConfigInstance)))
+	}
+	serverInstance := gogrpc.NewServer(serverOptions...)
+
+	healthInstance := health.New()
+	if checksInstance, err := container.GetService[health.Health](containerInstance, "health"); err == nil {
+		healthInstance = checksInstance
 	}
 
-	proto.RegisterMetricsServer(serverInstance, NewMetricsGRPCService(metricServiceInstance))
+	proto.RegisterMetricsServer(serverInstance, NewMetricsGRPCService(tenantServicesInstance))
+	grpc_health_v1.RegisterHealthServer(serverInstance, NewHealthGRPCService(healthInstance))
 
 	return serverInstance, nil
 }
@@ -0,0 +1,213 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/agent"
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/proto"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/middleware"
+	serverModel "github.com/GoLessons/go-musthave-metrics/internal/server/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func startSecuredServer(t *testing.T, secret string, decrypterInstance *middleware.Decrypter) (string, *storage.MemStorage[serverModel.Counter]) {
+	t.Helper()
+	counterStorage := storage.NewMemStorage[serverModel.Counter]()
+	gaugeStorage := storage.NewMemStorage[serverModel.Gauge]()
+	metricService := service.NewMetricService(counterStorage, gaugeStorage)
+
+	serverInstance := gogrpc.NewServer(
+		gogrpc.UnaryInterceptor(SignatureInterceptor(signature.NewSign(secret), security.NewReplayGuard(time.Minute, true), zap.NewNop(), nil)),
+		gogrpc.ForceServerCodec(NewEnvelopeCodec(decrypterInstance)),
+	)
+	proto.RegisterMetricsServer(serverInstance, NewMetricsGRPCService(service.NewTenantMetricServices(metricService)))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = serverInstance.Serve(listener) }()
+	t.Cleanup(serverInstance.Stop)
+
+	return listener.Addr().String(), counterStorage
+}
+
+func writeRSAKeyPair(t *testing.T) (string, string) {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}), 0o600))
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600))
+
+	return privatePath, publicPath
+}
+
+func TestTransportSecurity_SignedAndEncryptedBatch(t *testing.T) {
+	privatePath, publicPath := writeRSAKeyPair(t)
+	decrypterInstance, err := middleware.NewDecrypterFromFile(privatePath)
+	require.NoError(t, err)
+	encrypterInstance, err := agent.NewEncrypterFromFile(publicPath)
+	require.NoError(t, err)
+
+	address, counterStorage := startSecuredServer(t, "secret", decrypterInstance)
+
+	senderInstance := agent.NewGRPCSender(address).
+		WithSigner(signature.NewSign("secret")).
+		WithEncrypter(encrypterInstance)
+	defer senderInstance.Close()
+
+	delta := int64(7)
+	require.NoError(t, senderInstance.SendBatch([]model.Metrics{{ID: "c1", MType: model.Counter, Delta: &delta}}))
+
+	counterValue, err := counterStorage.Get("c1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), counterValue.Value())
+}
+
+func TestTransportSecurity_WrongSecret_ReturnsInvalidArgument(t *testing.T) {
+	address, counterStorage := startSecuredServer(t, "secret", nil)
+
+	senderInstance := agent.NewGRPCSender(address).WithSigner(signature.NewSign("other"))
+	defer senderInstance.Close()
+
+	delta := int64(1)
+	err := senderInstance.SendBatch([]model.Metrics{{ID: "c1", MType: model.Counter, Delta: &delta}})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = counterStorage.Get("c1")
+	assert.Error(t, err)
+}
+
+func TestTransportSecurity_EncryptedWithoutServerKey_IsRejected(t *testing.T) {
+	_, publicPath := writeRSAKeyPair(t)
+	encrypterInstance, err := agent.NewEncrypterFromFile(publicPath)
+	require.NoError(t, err)
+
+	address, _ := startSecuredServer(t, "secret", nil)
+
+	senderInstance := agent.NewGRPCSender(address).WithEncrypter(encrypterInstance)
+	defer senderInstance.Close()
+
+	delta := int64(1)
+	assert.Error(t, senderInstance.SendBatch([]model.Metrics{{ID: "c1", MType: model.Counter, Delta: &delta}}))
+}
+
+func TestTransportSecurity_PlaintextWithServerKey_IsRejected(t *testing.T) {
+	privatePath, _ := writeRSAKeyPair(t)
+	decrypterInstance, err := middleware.NewDecrypterFromFile(privatePath)
+	require.NoError(t, err)
+
+	address, counterStorage := startSecuredServer(t, "secret", decrypterInstance)
+
+	senderInstance := agent.NewGRPCSender(address).WithSigner(signature.NewSign("secret"))
+	defer senderInstance.Close()
+
+	delta := int64(1)
+	assert.Error(t, senderInstance.SendBatch([]model.Metrics{{ID: "c1", MType: model.Counter, Delta: &delta}}))
+
+	_, err = counterStorage.Get("c1")
+	assert.Error(t, err, "plaintext batch must not reach the storage")
+}
+
+func TestSignatureInterceptor_ReplayedRequest_ReturnsInvalidArgument(t *testing.T) {
+	signerInstance := signature.NewSign("secret")
+	interceptorInstance := SignatureInterceptor(signerInstance, security.NewReplayGuard(time.Minute, true), zap.NewNop(), nil)
+	handlerFunction := func(contextInstance context.Context, requestInstance interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	infoInstance := &gogrpc.UnaryServerInfo{FullMethod: proto.Metrics_UpdateMetrics_FullMethodName}
+	requestInstance := &proto.UpdateMetricsRequest{Metrics: []*proto.Metric{{Id: "c1", Type: proto.Metric_COUNTER, Delta: 1}}}
+
+	payload, err := proto.SigningBytes(requestInstance)
+	require.NoError(t, err)
+	timestamp, nonce, err := signature.NewNonce(time.Now())
+	require.NoError(t, err)
+	hash, err := signerInstance.Hash(signature.SignedPayload(timestamp, nonce, payload))
+	require.NoError(t, err)
+	metadataContext := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		proto.SignatureMetadataKey, hash,
+		"x-signature-timestamp", timestamp,
+		"x-signature-nonce", nonce,
+	))
+
+	_, err = interceptorInstance(metadataContext, requestInstance, infoInstance, handlerFunction)
+	require.NoError(t, err)
+	_, err = interceptorInstance(metadataContext, requestInstance, infoInstance, handlerFunction)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	legacyHash, err := signerInstance.Hash(payload)
+	require.NoError(t, err)
+	legacyContext := metadata.NewIncomingContext(context.Background(), metadata.Pairs(proto.SignatureMetadataKey, legacyHash))
+	_, err = interceptorInstance(legacyContext, requestInstance, infoInstance, handlerFunction)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "strict mode requires timestamp and nonce")
+
+	_, err = interceptorInstance(context.Background(), requestInstance, infoInstance, handlerFunction)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "unsigned request must be rejected when a key is set")
+}
+
+func TestTransportSecurity_AgentSignature(t *testing.T) {
+	keysDir := t.TempDir()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(keysDir, "agent-1.pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600))
+
+	agentKeyringInstance, err := security.NewAgentKeyring(keysDir)
+	require.NoError(t, err)
+	var agentName string
+	serverInstance := gogrpc.NewServer(gogrpc.ChainUnaryInterceptor(
+		AgentSignatureInterceptor(agentKeyringInstance, security.NewReplayGuard(time.Minute, false), zap.NewNop(), nil),
+		func(contextInstance context.Context, requestInstance interface{}, infoInstance *gogrpc.UnaryServerInfo, handlerFunction gogrpc.UnaryHandler) (interface{}, error) {
+			agentName = security.AgentFromContext(contextInstance)
+			return handlerFunction(contextInstance, requestInstance)
+		},
+	))
+	counterStorage := storage.NewMemStorage[serverModel.Counter]()
+	metricService := service.NewMetricService(counterStorage, storage.NewMemStorage[serverModel.Gauge]())
+	proto.RegisterMetricsServer(serverInstance, NewMetricsGRPCService(service.NewTenantMetricServices(metricService)))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = serverInstance.Serve(listener) }()
+	defer serverInstance.Stop()
+
+	keySignerInstance, err := signature.NewKeySigner(privateKey)
+	require.NoError(t, err)
+	senderInstance := agent.NewGRPCSender(listener.Addr().String()).WithKeySigner(keySignerInstance)
+	defer senderInstance.Close()
+
+	delta := int64(2)
+	require.NoError(t, senderInstance.SendBatch([]model.Metrics{{ID: "c1", MType: model.Counter, Delta: &delta}}))
+	assert.Equal(t, "agent-1", agentName)
+
+	unsignedSender := agent.NewGRPCSender(listener.Addr().String())
+	defer unsignedSender.Close()
+	err = unsignedSender.SendBatch([]model.Metrics{{ID: "c1", MType: model.Counter, Delta: &delta}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
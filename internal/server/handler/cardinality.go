@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/goccy/go-json"
+)
+
+const defaultCardinalityTop = 10
+
+type tenantCardinality struct {
+	Tenant string `json:"tenant,omitempty"`
+	service.CardinalityReport
+}
+
+type CardinalityController struct {
+	services *service.TenantMetricServices
+}
+
+func NewCardinalityController(services *service.TenantMetricServices) *CardinalityController {
+	return &CardinalityController{services: services}
+}
+
+// Get отдаёт по каждому арендатору число серий и крупнейших нарушителей по префиксам и IP клиентов.
+// Размер списков задаётся параметром top.
+func (controller *CardinalityController) Get(w http.ResponseWriter, r *http.Request) {
+	top := defaultCardinalityTop
+	if v := r.URL.Query().Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	services := controller.services.All()
+	reports := make([]tenantCardinality, 0, len(services))
+	for tenantID, metricService := range services {
+		reports = append(reports, tenantCardinality{
+			Tenant:            tenantID,
+			CardinalityReport: metricService.Cardinality().Report(top),
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Tenant < reports[j].Tenant
+	})
+
+	responseBody, err := json.Marshal(reports)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(responseBody)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
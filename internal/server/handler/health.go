@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	"github.com/goccy/go-json"
+)
+
+type HealthController struct {
+	health *health.Health
+}
+
+func NewHealthController(h *health.Health) *HealthController {
+	return &HealthController{health: h}
+}
+
+// Live отвечает, пока процесс способен обрабатывать запросы; зависимости не проверяются.
+func (controller *HealthController) Live(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, health.Report{Status: health.StatusOK, Checks: map[string]health.Result{}})
+}
+
+// Ready отдаёт результаты всех проверок; пока хоть одна не прошла, статус ответа 503.
+func (controller *HealthController) Ready(w http.ResponseWriter, r *http.Request) {
+	report := controller.health.Check(r.Context())
+
+	code := http.StatusOK
+	if !report.Ready() {
+		code = http.StatusServiceUnavailable
+	}
+	writeHealth(w, code, report)
+}
+
+func writeHealth(w http.ResponseWriter, code int, report health.Report) {
+	responseBody, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_, _ = w.Write(responseBody)
+}
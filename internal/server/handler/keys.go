@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+type KeyReloader interface {
+	Reload() error
+	KeyIDs() []string
+}
+
+type KeysController struct {
+	reloader KeyReloader
+	logger   *zap.Logger
+}
+
+func NewKeysController(reloader KeyReloader, logger *zap.Logger) *KeysController {
+	return &KeysController{reloader: reloader, logger: logger}
+}
+
+// Reload перечитывает ключи расшифровки и отдаёт идентификаторы активных ключей.
+func (controller *KeysController) Reload(w http.ResponseWriter, r *http.Request) {
+	if err := controller.reloader.Reload(); err != nil {
+		controller.logger.Error("keys reload failed", zap.Error(err))
+		http.Error(w, "Keys reload failed", http.StatusInternalServerError)
+		return
+	}
+
+	keyIDs := controller.reloader.KeyIDs()
+	controller.logger.Info("keys reloaded", zap.Strings("kids", keyIDs))
+
+	responseBody, err := json.Marshal(map[string][]string{"keys": keyIDs})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(responseBody)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -4,19 +4,17 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
-	"github.com/GoLessons/go-musthave-metrics/internal/server/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
 )
 
 type ListController struct {
-	counterStorage storage.Storage[model.Counter]
-	gaugeStorage   storage.Storage[model.Gauge]
+	services *service.TenantMetricServices
 }
 
-func NewListController(counterStorage storage.Storage[model.Counter], gaugeStorage storage.Storage[model.Gauge]) *ListController {
+func NewListController(services *service.TenantMetricServices) *ListController {
 	return &ListController{
-		counterStorage: counterStorage,
-		gaugeStorage:   gaugeStorage,
+		services: services,
 	}
 }
 
@@ -28,7 +26,9 @@ func (controller *ListController) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gaugeMetrics, err := controller.gaugeStorage.GetAll()
+	metricService := controller.services.ForTenant(tenant.IDFromContext(r.Context()))
+
+	gaugeMetrics, err := metricService.GetAllGauges()
 	if err != nil {
 		http.Error(w, "Can't read gaugeMetrics", http.StatusInternalServerError)
 		return
@@ -42,7 +42,7 @@ func (controller *ListController) Get(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	counterMetrics, err := controller.counterStorage.GetAll()
+	counterMetrics, err := metricService.GetAllCounters()
 	if err != nil {
 		http.Error(w, "Can't read counterMetrics", http.StatusInternalServerError)
 		return
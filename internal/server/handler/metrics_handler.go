@@ -1,21 +1,26 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/GoLessons/go-musthave-metrics/internal/common/tlsconfig"
 	"github.com/GoLessons/go-musthave-metrics/internal/model"
 	"github.com/GoLessons/go-musthave-metrics/internal/server"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/audit"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
 	"github.com/goccy/go-json"
 	"go.uber.org/zap"
 )
 
 type metricsController struct {
-	metricService   service.MetricService
+	services        *service.TenantMetricServices
 	responseBuilder ResponseBuilder
 	logger          *zap.Logger
 	auditor         audit.Subject
@@ -24,13 +29,13 @@ type metricsController struct {
 type ResponseBuilder func(*http.ResponseWriter, *model.Metrics)
 
 func NewMetricsController(
-	metricService service.MetricService,
+	services *service.TenantMetricServices,
 	responseBuilder ResponseBuilder,
 	logger *zap.Logger,
 	auditor audit.Subject,
 ) *metricsController {
 	return &metricsController{
-		metricService:   metricService,
+		services:        services,
 		responseBuilder: responseBuilder,
 		logger:          logger,
 		auditor:         auditor,
@@ -43,7 +48,7 @@ func (h *metricsController) Get(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Info("Get metric", zap.Any("metric", metricData))
 
-	metric, err := h.metricService.Read(metricData.MType, metricData.ID)
+	metric, err := h.metricService(ctx).Read(metricData.MType, metricData.ID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -58,9 +63,10 @@ func (h *metricsController) Update(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Info("Updated metric", zap.Any("metric", metricData))
 
-	err := h.metricService.Save(metricData)
+	err := h.metricService(ctx).SaveFrom(metricData, clientIP(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), saveErrorStatus(err))
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -69,6 +75,8 @@ func (h *metricsController) Update(w http.ResponseWriter, r *http.Request) {
 	if h.auditor != nil {
 		ip := clientIP(r)
 		item := audit.NewJournalItem(time.Now().Unix(), []string{metricData.ID}, ip)
+		item.Identity = tlsconfig.Identity(r.TLS)
+		item.Agent = security.AgentFromContext(ctx)
 		h.auditor.NotifyAll(ctx, item)
 	}
 }
@@ -79,10 +87,11 @@ func (h *metricsController) UpdateBatch(w http.ResponseWriter, r *http.Request)
 
 	h.logger.Info("Updated metrics batch", zap.Int("count", len(metricsArray)))
 
+	metricService := h.metricService(ctx)
 	for _, metricData := range metricsArray {
-		err := h.metricService.Save(metricData)
+		err := metricService.SaveFrom(metricData, clientIP(r))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), saveErrorStatus(err))
 			return
 		}
 	}
@@ -96,10 +105,16 @@ func (h *metricsController) UpdateBatch(w http.ResponseWriter, r *http.Request)
 			names = append(names, m.ID)
 		}
 		item := audit.NewJournalItem(time.Now().Unix(), names, ip)
+		item.Identity = tlsconfig.Identity(r.TLS)
+		item.Agent = security.AgentFromContext(ctx)
 		h.auditor.NotifyAll(ctx, item)
 	}
 }
 
+func (h *metricsController) metricService(ctx context.Context) *service.MetricService {
+	return h.services.ForTenant(tenant.IDFromContext(ctx))
+}
+
 func JSONResposeBuilder(w *http.ResponseWriter, metric *model.Metrics) {
 	responseBody, err := json.Marshal(metric)
 	if err != nil {
@@ -131,6 +146,14 @@ func PlainResposeBuilder(w *http.ResponseWriter, metric *model.Metrics) {
 	}
 }
 
+func saveErrorStatus(err error) int {
+	if errors.Is(err, service.ErrCardinalityLimit) {
+		return http.StatusUnprocessableEntity
+	}
+
+	return http.StatusInternalServerError
+}
+
 func clientIP(r *http.Request) string {
 	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
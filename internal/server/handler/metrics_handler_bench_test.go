@@ -18,7 +18,7 @@ func BenchmarkMetricsHandler_Update_Plain(b *testing.B) {
 	sCounter := storage.NewMemStorage[serverModel.Counter]()
 	sGauge := storage.NewMemStorage[serverModel.Gauge]()
 	ms := service.NewMetricService(sCounter, sGauge)
-	h := NewMetricsController(*ms, PlainResposeBuilder, zap.NewNop(), nil)
+	h := NewMetricsController(service.NewTenantMetricServices(ms), PlainResposeBuilder, zap.NewNop(), nil)
 
 	metric := model.NewGauge("bench_plain", ptrFloat(123.456))
 	b.ReportAllocs()
@@ -34,7 +34,7 @@ func BenchmarkMetricsHandler_Update_JSON(b *testing.B) {
 	sCounter := storage.NewMemStorage[serverModel.Counter]()
 	sGauge := storage.NewMemStorage[serverModel.Gauge]()
 	ms := service.NewMetricService(sCounter, sGauge)
-	h := NewMetricsController(*ms, JSONResposeBuilder, zap.NewNop(), nil)
+	h := NewMetricsController(service.NewTenantMetricServices(ms), JSONResposeBuilder, zap.NewNop(), nil)
 
 	metric := model.NewCounter("bench_json", ptrInt(42))
 	b.ReportAllocs()
@@ -50,7 +50,7 @@ func BenchmarkMetricsHandler_Get_JSON(b *testing.B) {
 	sCounter := storage.NewMemStorage[serverModel.Counter]()
 	sGauge := storage.NewMemStorage[serverModel.Gauge]()
 	ms := service.NewMetricService(sCounter, sGauge)
-	h := NewMetricsController(*ms, JSONResposeBuilder, zap.NewNop(), nil)
+	h := NewMetricsController(service.NewTenantMetricServices(ms), JSONResposeBuilder, zap.NewNop(), nil)
 
 	// Seed counter
 	_ = ms.Save(*model.NewCounter("bench_get_json", ptrInt(5)))
@@ -69,7 +69,7 @@ func BenchmarkMetricsHandler_UpdateBatch_JSON(b *testing.B) {
 	sCounter := storage.NewMemStorage[serverModel.Counter]()
 	sGauge := storage.NewMemStorage[serverModel.Gauge]()
 	ms := service.NewMetricService(sCounter, sGauge)
-	h := NewMetricsController(*ms, JSONResposeBuilder, zap.NewNop(), nil)
+	h := NewMetricsController(service.NewTenantMetricServices(ms), JSONResposeBuilder, zap.NewNop(), nil)
 
 	metrics := make([]model.Metrics, 64)
 	for i := range metrics {
@@ -0,0 +1,150 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Статусы проверок и сервера в целом.
+const (
+	StatusOK      = "ok"
+	StatusFail    = "fail"
+	StatusPending = "pending"
+)
+
+// checkTimeout ограничивает одну проверку, чтобы зависшая зависимость не задерживала ответ.
+const checkTimeout = 2 * time.Second
+
+// ErrPending — этап запуска ещё не завершён.
+var ErrPending = errors.New("not completed yet")
+
+type Check func(ctx context.Context) error
+
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// State — результат этапа, о котором сообщает сам код запуска (восстановление, миграции, сохранение).
+type State struct {
+	mu  sync.RWMutex
+	err error
+}
+
+func (s *State) Set(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+func (s *State) check(context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.err
+}
+
+// Gate закрыт, пока не завершены этапы запуска (миграции и восстановление состояния): до этого
+// сервер отвечает только на пробы, чтобы записи не легли поверх ещё не восстановленных данных.
+// Отсутствующий (nil) шлюз считается открытым.
+type Gate struct {
+	open atomic.Bool
+}
+
+func (g *Gate) Open() {
+	g.open.Store(true)
+}
+
+func (g *Gate) IsOpen() bool {
+	return g == nil || g.open.Load()
+}
+
+// Health собирает проверки готовности сервера. Сервер готов, когда успешны все проверки.
+type Health struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+func New() *Health {
+	return &Health{checks: make(map[string]Check)}
+}
+
+func (h *Health) Register(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks[name] = check
+}
+
+// State регистрирует проверку с результатом, который выставляется через Set; до этого она в ожидании.
+func (h *Health) State(name string) *State {
+	state := &State{err: ErrPending}
+	h.Register(name, state.check)
+
+	return state
+}
+
+// Check выполняет все проверки параллельно.
+func (h *Health) Check(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := make(map[string]Check, len(h.checks))
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	h.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			result := resultOf(check(checkCtx))
+			mu.Lock()
+			report.Checks[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		switch result.Status {
+		case StatusFail:
+			report.Status = StatusFail
+		case StatusPending:
+			if report.Status == StatusOK {
+				report.Status = StatusPending
+			}
+		}
+	}
+
+	return report
+}
+
+func resultOf(err error) Result {
+	switch {
+	case err == nil:
+		return Result{Status: StatusOK}
+	case errors.Is(err, ErrPending):
+		return Result{Status: StatusPending}
+	default:
+		return Result{Status: StatusFail, Error: err.Error()}
+	}
+}
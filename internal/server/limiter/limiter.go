@@ -0,0 +1,239 @@
+package limiter
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	RequestsPerSecond float64
+	Burst             int
+	MaxBatchSize      int
+	MaxSeries         int
+	IdleTTL           time.Duration
+	// SeriesTTL — сколько хранится учёт серий клиента по IP после его последней пачки.
+	SeriesTTL time.Duration
+	// MaxSeriesClients ограничивает число IP, для которых ведётся учёт серий.
+	MaxSeriesClients int
+}
+
+// ipKeyPrefix отличает ключи клиентов по IP: их число не ограничено списком арендаторов.
+const ipKeyPrefix = "ip:"
+
+func (c Config) Enabled() bool {
+	return c.RequestsPerSecond > 0 || c.MaxBatchSize > 0 || c.MaxSeries > 0
+}
+
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst int, now time.Time) *TokenBucket {
+	b := float64(burst)
+	if b < 1 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+
+	return &TokenBucket{rate: rate, burst: b, tokens: b, last: now}
+}
+
+// Take списывает n токенов; при нехватке возвращает время до их накопления.
+func (b *TokenBucket) Take(n float64, now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+
+	if b.tokens >= n {
+		b.tokens -= n
+		return true, 0
+	}
+
+	wait := (n - b.tokens) / b.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+type client struct {
+	bucket   *TokenBucket
+	lastSeen time.Time
+}
+
+type seriesSet struct {
+	known    map[string]struct{}
+	lastSeen time.Time
+}
+
+type Limiter struct {
+	mu      sync.Mutex
+	cfg     Config
+	clients map[string]*client
+	// series хранит учтённые серии отдельно от корзин: квота на серии не сбрасывается
+	// оттого, что клиент какое-то время молчал и его корзину убрал sweep.
+	series    map[string]*seriesSet
+	ipSeries  int
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func New(cfg Config) *Limiter {
+	if cfg.IdleTTL <= 0 {
+		cfg.IdleTTL = 10 * time.Minute
+	}
+	if cfg.SeriesTTL <= 0 {
+		cfg.SeriesTTL = 24 * time.Hour
+	}
+	if cfg.MaxSeriesClients <= 0 {
+		cfg.MaxSeriesClients = 1 << 16
+	}
+
+	return &Limiter{
+		cfg:     cfg,
+		clients: make(map[string]*client),
+		series:  make(map[string]*seriesSet),
+		now:     time.Now,
+	}
+}
+
+// Allow проверяет частоту запросов для ключа (арендатор или IP клиента).
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.cfg.RequestsPerSecond <= 0 {
+		return true, 0
+	}
+
+	now := l.now()
+	c := l.client(key, now)
+
+	return c.bucket.Take(1, now)
+}
+
+func (l *Limiter) AllowBatch(size int) bool {
+	return l.cfg.MaxBatchSize <= 0 || size <= l.cfg.MaxBatchSize
+}
+
+// AdmitSeries учитывает новые серии клиента и отказывает всей пачке,
+// если после неё число различных серий превысит лимит.
+func (l *Limiter) AdmitSeries(key string, series []string) bool {
+	if l.cfg.MaxSeries <= 0 {
+		return true
+	}
+
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	set, ok := l.series[key]
+	if !ok {
+		if strings.HasPrefix(key, ipKeyPrefix) {
+			if l.ipSeries >= l.cfg.MaxSeriesClients {
+				l.evictOldestIP()
+			}
+			l.ipSeries++
+		}
+		set = &seriesSet{known: make(map[string]struct{})}
+		l.series[key] = set
+	}
+	set.lastSeen = now
+
+	fresh := make(map[string]struct{})
+	for _, s := range series {
+		if _, ok := set.known[s]; !ok {
+			fresh[s] = struct{}{}
+		}
+	}
+
+	if len(set.known)+len(fresh) > l.cfg.MaxSeries {
+		return false
+	}
+
+	for s := range fresh {
+		set.known[s] = struct{}{}
+	}
+
+	return true
+}
+
+// evictOldestIP освобождает место под нового клиента, забывая серии самого давнего IP.
+func (l *Limiter) evictOldestIP() {
+	oldest := ""
+	var oldestSeen time.Time
+	for key, set := range l.series {
+		if !strings.HasPrefix(key, ipKeyPrefix) {
+			continue
+		}
+		if oldest == "" || set.lastSeen.Before(oldestSeen) {
+			oldest, oldestSeen = key, set.lastSeen
+		}
+	}
+	if oldest != "" {
+		delete(l.series, oldest)
+		l.ipSeries--
+	}
+}
+
+func (l *Limiter) client(key string, now time.Time) *client {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{}
+		if l.cfg.RequestsPerSecond > 0 {
+			c.bucket = NewTokenBucket(l.cfg.RequestsPerSecond, l.cfg.Burst, now)
+		}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+
+	return c
+}
+
+// sweep удаляет корзины давно неактивных клиентов, чтобы сам лимитер не рос без ограничений.
+// Серии арендаторов остаются: они соответствуют сериям в хранилище. Серии клиентов по IP
+// живут дольше корзин (SeriesTTL), но не вечно: иначе каждый новый адрес занимал бы память навсегда.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.cfg.IdleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, c := range l.clients {
+		if now.Sub(c.lastSeen) >= l.cfg.IdleTTL {
+			delete(l.clients, key)
+		}
+	}
+
+	for key, set := range l.series {
+		if strings.HasPrefix(key, ipKeyPrefix) && now.Sub(set.lastSeen) >= l.cfg.SeriesTTL {
+			delete(l.series, key)
+			l.ipSeries--
+		}
+	}
+}
+
+// RetryAfterSeconds округляет задержку вверх до целых секунд для заголовка Retry-After.
+func RetryAfterSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
+
+func SeriesKey(metricType string, id string) string {
+	return metricType + ":" + id
+}
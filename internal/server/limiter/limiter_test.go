@@ -0,0 +1,110 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	start := time.Unix(0, 0)
+	bucket := NewTokenBucket(2, 2, start)
+
+	ok, _ := bucket.Take(1, start)
+	assert.True(t, ok)
+	ok, _ = bucket.Take(1, start)
+	assert.True(t, ok)
+
+	ok, wait := bucket.Take(1, start)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	ok, _ = bucket.Take(1, start.Add(500*time.Millisecond))
+	assert.True(t, ok)
+}
+
+func TestLimiter_AllowPerKey(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(Config{RequestsPerSecond: 1, Burst: 1})
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("tenant:a")
+	assert.True(t, ok)
+	ok, wait := l.Allow("tenant:a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, RetryAfterSeconds(wait))
+
+	ok, _ = l.Allow("tenant:b")
+	assert.True(t, ok, "other clients must have their own bucket")
+
+	now = now.Add(time.Second)
+	ok, _ = l.Allow("tenant:a")
+	assert.True(t, ok)
+}
+
+func TestLimiter_AllowBatch(t *testing.T) {
+	assert.True(t, New(Config{}).AllowBatch(1000))
+
+	l := New(Config{MaxBatchSize: 2})
+	assert.True(t, l.AllowBatch(2))
+	assert.False(t, l.AllowBatch(3))
+}
+
+func TestLimiter_AdmitSeries(t *testing.T) {
+	l := New(Config{MaxSeries: 2})
+
+	assert.True(t, l.AdmitSeries("ip:1", []string{"gauge:a", "gauge:a"}))
+	assert.True(t, l.AdmitSeries("ip:1", []string{"gauge:a", "counter:b"}))
+	assert.False(t, l.AdmitSeries("ip:1", []string{"gauge:a", "gauge:c"}), "batch with a new series over the limit is rejected")
+	assert.True(t, l.AdmitSeries("ip:1", []string{"counter:b"}), "known series stay admitted")
+	assert.True(t, l.AdmitSeries("ip:2", []string{"gauge:c"}))
+}
+
+func TestLimiter_SweepIdleClients(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(Config{RequestsPerSecond: 1, Burst: 1, MaxSeries: 1, IdleTTL: time.Minute})
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("ip:1")
+	assert.True(t, ok)
+	assert.True(t, l.AdmitSeries("ip:1", []string{"gauge:a"}))
+	assert.False(t, l.AdmitSeries("ip:1", []string{"gauge:b"}))
+
+	now = now.Add(2 * time.Minute)
+	l.Allow("ip:2")
+	assert.NotContains(t, l.clients, "ip:1", "idle bucket is swept")
+	assert.False(t, l.AdmitSeries("ip:1", []string{"gauge:b"}), "series quota survives the sweep")
+	assert.True(t, l.AdmitSeries("ip:1", []string{"gauge:a"}))
+}
+
+func TestLimiter_ExpiresIPSeries(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(Config{MaxSeries: 1, IdleTTL: time.Minute, SeriesTTL: time.Hour})
+	l.now = func() time.Time { return now }
+
+	assert.True(t, l.AdmitSeries("ip:1", []string{"gauge:a"}))
+	assert.True(t, l.AdmitSeries("tenant:a", []string{"gauge:a"}))
+
+	now = now.Add(30 * time.Minute)
+	assert.False(t, l.AdmitSeries("ip:1", []string{"gauge:b"}), "quota survives bucket sweeps")
+
+	now = now.Add(2 * time.Hour)
+	assert.True(t, l.AdmitSeries("ip:2", []string{"gauge:a"}))
+	assert.NotContains(t, l.series, "ip:1", "idle IP series expire after SeriesTTL")
+	assert.Contains(t, l.series, "tenant:a", "tenant series are kept")
+}
+
+func TestLimiter_BoundsIPSeriesClients(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(Config{MaxSeries: 1, MaxSeriesClients: 2})
+	l.now = func() time.Time { return now }
+
+	for _, key := range []string{"ip:1", "ip:2", "ip:3"} {
+		assert.True(t, l.AdmitSeries(key, []string{"gauge:a"}))
+		now = now.Add(time.Second)
+	}
+
+	assert.Len(t, l.series, 2)
+	assert.NotContains(t, l.series, "ip:1", "least recently seen IP is evicted")
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/auth"
+	"go.uber.org/zap"
+)
+
+// AdminKeyHeader передаёт ключ администратора служебных маршрутов.
+const AdminKeyHeader = "X-Admin-Key"
+
+// AdminAuthMiddleware пускает к служебным маршрутам по ключу администратора
+// или по JWT с правом metrics:admin.
+type AdminAuthMiddleware struct {
+	key    string
+	bearer *BearerAuthMiddleware
+	logger *zap.Logger
+}
+
+func NewAdminAuthMiddleware(key string, bearer *BearerAuthMiddleware, logger *zap.Logger) *AdminAuthMiddleware {
+	return &AdminAuthMiddleware{key: key, bearer: bearer, logger: logger}
+}
+
+// Configured сообщает, задан ли хотя бы один способ проверки администратора.
+func (m *AdminAuthMiddleware) Configured() bool {
+	return m.key != "" || m.bearer != nil
+}
+
+// RequireAdmin проверяет X-Admin-Key, если заголовок передан, иначе — JWT с правом metrics:admin.
+func (m *AdminAuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
+	var bearer http.Handler
+	if m.bearer != nil {
+		bearer = m.bearer.RequireScope(auth.ScopeAdmin)(next)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(AdminKeyHeader); key != "" || bearer == nil {
+			if m.key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(m.key)) != 1 {
+				if m.logger != nil {
+					m.logger.Warn("admin key rejected", zap.String("uri", r.RequestURI))
+				}
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bearer.ServeHTTP(w, r)
+	})
+}
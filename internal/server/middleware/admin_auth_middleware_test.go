@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAuthMiddleware_RequireAdmin(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	send := func(m *AdminAuthMiddleware, key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/cardinality", nil)
+		if key != "" {
+			req.Header.Set(AdminKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		m.RequireAdmin(next).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	withKey := NewAdminAuthMiddleware("admin-secret", nil, nil)
+	assert.True(t, withKey.Configured())
+	assert.Equal(t, http.StatusOK, send(withKey, "admin-secret"))
+	assert.Equal(t, http.StatusUnauthorized, send(withKey, "wrong"))
+	assert.Equal(t, http.StatusUnauthorized, send(withKey, ""))
+
+	withoutKey := NewAdminAuthMiddleware("", nil, nil)
+	assert.False(t, withoutKey.Configured())
+	assert.Equal(t, http.StatusUnauthorized, send(withoutKey, "anything"))
+}
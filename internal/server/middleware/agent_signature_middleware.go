@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"go.uber.org/zap"
+)
+
+// AgentSignatureMiddleware требует подпись запроса ключом зарегистрированного агента (Ed25519 или ECDSA).
+type AgentSignatureMiddleware struct {
+	keyring  *security.AgentKeyring
+	replay   *security.ReplayGuard
+	logger   *zap.Logger
+	registry *telemetry.Registry
+}
+
+func NewAgentSignatureMiddleware(keyring *security.AgentKeyring, replay *security.ReplayGuard, logger *zap.Logger) *AgentSignatureMiddleware {
+	return &AgentSignatureMiddleware{keyring: keyring, replay: replay, logger: logger}
+}
+
+// WithTelemetry включает учёт отклонённых подписей агентов.
+func (m *AgentSignatureMiddleware) WithTelemetry(registry *telemetry.Registry) *AgentSignatureMiddleware {
+	m.registry = registry
+	return m
+}
+
+// VerifyAgent проверяет подпись тела (для запросов без тела — пути) вместе с меткой времени и nonce
+// и кладёт имя агента в контекст. Должен стоять до VerifySignature и до распаковки тела.
+func (m *AgentSignatureMiddleware) VerifyAgent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timestamp := r.Header.Get(signature.TimestampHeader)
+		nonce := r.Header.Get(signature.NonceHeader)
+		// Без метки времени и nonce подпись агента можно было бы повторять бесконечно.
+		if timestamp == "" || nonce == "" {
+			m.reject(w, r, security.ErrReplayMissing)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			m.logger.Error("failed to read body", zap.Error(err))
+			http.Error(w, "Failed to read request body", http.StatusInternalServerError)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		signed := body
+		if len(body) == 0 {
+			signed = []byte(r.URL.Path)
+		}
+
+		agent, err := m.keyring.Verify(
+			r.Header.Get(signature.AgentKeyIDHeader),
+			signature.SignedPayload(timestamp, nonce, signed),
+			r.Header.Get(signature.AgentSignatureHeader),
+		)
+		if err != nil {
+			m.reject(w, r, err)
+			return
+		}
+
+		if err := m.replay.Check(timestamp, nonce); err != nil {
+			m.reject(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(security.NewAgentContext(r.Context(), agent)))
+	})
+}
+
+func (m *AgentSignatureMiddleware) reject(w http.ResponseWriter, r *http.Request, err error) {
+	m.logger.Warn("agent signature rejected", zap.String("client", LimitKey(r, nil)), zap.Error(err))
+	m.registry.SignatureFailed(telemetry.TransportHTTP, telemetry.CheckAgent)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/auth"
+	"go.uber.org/zap"
+)
+
+type BearerAuthMiddleware struct {
+	verifier *auth.Verifier
+	logger   *zap.Logger
+}
+
+func NewBearerAuthMiddleware(verifier *auth.Verifier, logger *zap.Logger) *BearerAuthMiddleware {
+	return &BearerAuthMiddleware{verifier: verifier, logger: logger}
+}
+
+// RequireScope пропускает запрос только с действительным JWT, содержащим нужное право.
+func (m *BearerAuthMiddleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := auth.BearerToken(r.Header.Get("Authorization"))
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := m.verifier.Verify(token)
+			if err != nil {
+				if m.logger != nil {
+					m.logger.Warn("bearer token rejected", zap.String("uri", r.RequestURI), zap.Error(err))
+				}
+
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !claims.HasScope(scope) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), claims)))
+		})
+	}
+}
@@ -10,41 +10,205 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
 
 	"encoding/pem"
 
+	"github.com/GoLessons/go-musthave-metrics/internal/common/keyid"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
 	"github.com/goccy/go-json"
 	"go.uber.org/zap"
 )
 
+// Decrypter — связка закрытых ключей, по которой расшифровываются тела запросов.
+// Несколько активных ключей позволяют ротацию: агенты переходят на новый ключ постепенно.
 type Decrypter struct {
-	priv *rsa.PrivateKey
+	path string
+
+	mu   sync.RWMutex
+	keys map[string]*rsa.PrivateKey
+	ids  []string
 }
 
 type encryptedContainer struct {
 	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
 	K   string `json:"k"`
 	N   string `json:"n"`
 	D   string `json:"d"`
 	V   int    `json:"v"`
 }
 
+// NewDecrypterFromFile загружает ключи из PEM-файла (в нём может быть несколько ключей)
+// или из всех файлов *.pem и *.key в каталоге.
 func NewDecrypterFromFile(path string) (*Decrypter, error) {
-	b, err := os.ReadFile(path)
+	d := &Decrypter{}
+	if err := d.ReloadFrom(path); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reload перечитывает ключи; при ошибке остаётся прежняя связка.
+func (d *Decrypter) Reload() error {
+	d.mu.RLock()
+	path := d.path
+	d.mu.RUnlock()
+
+	return d.ReloadFrom(path)
+}
+
+// ReloadFrom загружает ключи из другого файла или каталога; при ошибке остаются прежние путь и связка.
+func (d *Decrypter) ReloadFrom(path string) error {
+	privs, err := loadPrivateKeys(path)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*rsa.PrivateKey, len(privs))
+	ids := make([]string, 0, len(privs))
+	for _, priv := range privs {
+		kid, err := keyid.FromPublicKey(&priv.PublicKey)
+		if err != nil {
+			return err
+		}
+		if _, exists := keys[kid]; exists {
+			continue
+		}
+		keys[kid] = priv
+		ids = append(ids, kid)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.path = path
+	d.keys = keys
+	d.ids = ids
+
+	return nil
+}
+
+func (d *Decrypter) KeyIDs() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return slices.Clone(d.ids)
+}
+
+// decryptKey расшифровывает сеансовый ключ ключом с указанным kid,
+// а для конвертов без kid от старых агентов перебирает все ключи.
+func (d *Decrypter) decryptKey(kid string, ek []byte) ([]byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if kid != "" {
+		priv, ok := d.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id: %s", kid)
+		}
+		return rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, ek, nil)
+	}
+
+	for _, id := range d.ids {
+		if k, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, d.keys[id], ek, nil); err == nil {
+			return k, nil
+		}
+	}
+	return nil, errors.New("no key could decrypt the payload")
+}
+
+// Decrypt раскрывает конверт агента (JSON с зашифрованным сеансовым ключом и данными AES-GCM).
+func (d *Decrypter) Decrypt(body []byte) ([]byte, error) {
+	var cont encryptedContainer
+	if err := json.Unmarshal(body, &cont); err != nil {
+		return nil, err
+	}
+	if cont.Alg != "aes256gcm+rsa-oaep" || cont.V != 1 || cont.K == "" || cont.N == "" || cont.D == "" {
+		return nil, errors.New("unsupported envelope")
+	}
+
+	ek, err := base64.StdEncoding.DecodeString(cont.K)
 	if err != nil {
 		return nil, err
 	}
-	priv, err := parsePrivateKey(b)
+	nonce, err := base64.StdEncoding.DecodeString(cont.N)
 	if err != nil {
 		return nil, err
 	}
-	return &Decrypter{priv: priv}, nil
+	if len(nonce) != 12 {
+		return nil, errors.New("invalid nonce size")
+	}
+	ct, err := base64.StdEncoding.DecodeString(cont.D)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := d.decryptKey(cont.Kid, ek)
+	if err != nil {
+		return nil, err
+	}
+	a, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	g, err := cipher.NewGCM(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Open(nil, nonce, ct, nil)
 }
 
-func parsePrivateKey(b []byte) (*rsa.PrivateKey, error) {
+func loadPrivateKeys(path string) ([]*rsa.PrivateKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".pem" && ext != ".key") {
+				continue
+			}
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+		sort.Strings(files)
+	}
+
+	var keys []*rsa.PrivateKey
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parsePrivateKeys(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		keys = append(keys, parsed...)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no valid private key found")
+	}
+	return keys, nil
+}
+
+func parsePrivateKeys(b []byte) ([]*rsa.PrivateKey, error) {
+	var keys []*rsa.PrivateKey
 	var block *pem.Block
 	rest := b
 	for {
@@ -58,7 +222,7 @@ func parsePrivateKey(b []byte) (*rsa.PrivateKey, error) {
 			if err != nil {
 				return nil, err
 			}
-			return key, nil
+			keys = append(keys, key)
 		case "PRIVATE KEY":
 			keyAny, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 			if err != nil {
@@ -66,24 +230,34 @@ func parsePrivateKey(b []byte) (*rsa.PrivateKey, error) {
 			}
 			switch k := keyAny.(type) {
 			case *rsa.PrivateKey:
-				return k, nil
+				keys = append(keys, k)
 			default:
 				return nil, errors.New("unsupported private key type")
 			}
 		}
 	}
-	return nil, errors.New("no valid private key found")
+	if len(keys) == 0 {
+		return nil, errors.New("no valid private key found")
+	}
+	return keys, nil
 }
 
 type DecryptMiddleware struct {
 	decrypter *Decrypter
 	logger    *zap.Logger
+	registry  *telemetry.Registry
 }
 
 func NewDecryptMiddleware(decrypter *Decrypter, logger *zap.Logger) *DecryptMiddleware {
 	return &DecryptMiddleware{decrypter: decrypter, logger: logger}
 }
 
+// WithTelemetry включает учёт запросов, отклонённых из-за невозможности расшифровать тело.
+func (m *DecryptMiddleware) WithTelemetry(registry *telemetry.Registry) *DecryptMiddleware {
+	m.registry = registry
+	return m
+}
+
 func (m *DecryptMiddleware) DecryptBody(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		enc := r.Header.Get("X-Encrypted")
@@ -92,10 +266,12 @@ func (m *DecryptMiddleware) DecryptBody(next http.Handler) http.Handler {
 			return
 		}
 		if m.decrypter == nil {
+			m.registry.DecryptFailed(telemetry.TransportHTTP)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 		if enc != "aes256gcm+rsa-oaep;v=1" {
+			m.registry.DecryptFailed(telemetry.TransportHTTP)
 			http.Error(w, "Bad Request", http.StatusBadRequest)
 			return
 		}
@@ -107,49 +283,9 @@ func (m *DecryptMiddleware) DecryptBody(next http.Handler) http.Handler {
 		}
 		defer r.Body.Close()
 
-		var cont encryptedContainer
-		if err := json.Unmarshal(body, &cont); err != nil {
-			http.Error(w, "Bad Request", http.StatusBadRequest)
-			return
-		}
-		if cont.Alg != "aes256gcm+rsa-oaep" || cont.V != 1 || cont.K == "" || cont.N == "" || cont.D == "" {
-			http.Error(w, "Bad Request", http.StatusBadRequest)
-			return
-		}
-
-		ek, err := base64.StdEncoding.DecodeString(cont.K)
-		if err != nil {
-			http.Error(w, "Bad Request", http.StatusBadRequest)
-			return
-		}
-		nonce, err := base64.StdEncoding.DecodeString(cont.N)
-		if err != nil || len(nonce) != 12 {
-			http.Error(w, "Bad Request", http.StatusBadRequest)
-			return
-		}
-		ct, err := base64.StdEncoding.DecodeString(cont.D)
-		if err != nil {
-			http.Error(w, "Bad Request", http.StatusBadRequest)
-			return
-		}
-
-		k, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, m.decrypter.priv, ek, nil)
-		if err != nil {
-			http.Error(w, "Bad Request", http.StatusBadRequest)
-			return
-		}
-		a, err := aes.NewCipher(k)
-		if err != nil {
-			http.Error(w, "Bad Request", http.StatusBadRequest)
-			return
-		}
-		g, err := cipher.NewGCM(a)
-		if err != nil {
-			http.Error(w, "Bad Request", http.StatusBadRequest)
-			return
-		}
-		pt, err := g.Open(nil, nonce, ct, nil)
+		pt, err := m.decrypter.Decrypt(body)
 		if err != nil {
+			m.registry.DecryptFailed(telemetry.TransportHTTP)
 			http.Error(w, "Bad Request", http.StatusBadRequest)
 			return
 		}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/agent"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeKeyPair пишет закрытый ключ в keysDir и открытый рядом, возвращая путь к открытому.
+func writeKeyPair(t *testing.T, keysDir string, name string) string {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	privPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+	require.NoError(t, os.WriteFile(filepath.Join(keysDir, name+".pem"), privPEM, 0o600))
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(t, err)
+	pubPath := filepath.Join(t.TempDir(), name+".pub")
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o600))
+
+	return pubPath
+}
+
+func TestDecryptMiddleware_KeyRotation(t *testing.T) {
+	keysDir := t.TempDir()
+	oldPub := writeKeyPair(t, keysDir, "old")
+
+	decrypter, err := NewDecrypterFromFile(keysDir)
+	require.NoError(t, err)
+	require.Len(t, decrypter.KeyIDs(), 1)
+
+	var received []byte
+	mw := NewDecryptMiddleware(decrypter, zap.NewNop()).DecryptBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(body []byte, headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, "/update", bytes.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	encrypt := func(pubPath string) ([]byte, map[string]string) {
+		e, err := agent.NewEncrypterFromFile(pubPath)
+		require.NoError(t, err)
+		body, headers, err := e.Encrypt([]byte("payload"))
+		require.NoError(t, err)
+		return body, headers
+	}
+
+	body, headers := encrypt(oldPub)
+	assert.Equal(t, http.StatusOK, send(body, headers))
+	assert.Equal(t, "payload", string(received))
+
+	newPub := writeKeyPair(t, keysDir, "new")
+	body, headers = encrypt(newPub)
+	assert.Equal(t, http.StatusBadRequest, send(body, headers), "key is unknown until reload")
+
+	require.NoError(t, decrypter.Reload())
+	assert.Len(t, decrypter.KeyIDs(), 2)
+	assert.Equal(t, http.StatusOK, send(body, headers))
+
+	body, headers = encrypt(oldPub)
+	assert.Equal(t, http.StatusOK, send(body, headers), "old key stays active during rotation")
+
+	var cont encryptedContainer
+	require.NoError(t, json.Unmarshal(body, &cont))
+	cont.Kid = ""
+	legacy, err := json.Marshal(cont)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, send(legacy, headers), "envelope without kid is decrypted by trying every key")
+}
+
+func TestDecrypter_ReloadKeepsKeysOnError(t *testing.T) {
+	keysDir := t.TempDir()
+	writeKeyPair(t, keysDir, "current")
+
+	decrypter, err := NewDecrypterFromFile(keysDir)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(keysDir, "broken.pem"), []byte("garbage"), 0o600))
+	assert.Error(t, decrypter.Reload())
+	assert.Len(t, decrypter.KeyIDs(), 1)
+}
@@ -4,10 +4,13 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
-func NewLoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
+// NewLoggingMiddleware логирует запросы и, если передан реестр, учитывает их число и длительность по шаблону маршрута.
+func NewLoggingMiddleware(logger *zap.Logger, registry *telemetry.Registry) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -19,6 +22,18 @@ func NewLoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 
 			duration := time.Since(start)
 
+			statusCode := rw.statusCode
+			if statusCode == 0 {
+				statusCode = http.StatusOK
+			}
+			if registry != nil {
+				var route string
+				if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil {
+					route = routeCtx.RoutePattern()
+				}
+				registry.ObserveHTTP(route, r.Method, statusCode, duration)
+			}
+
 			// Логируем информацию о запросе и ответе
 			logger.Info("HTTP request",
 				zap.String("method", r.Method),
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLoggingMiddleware_CountsByRoutePattern(t *testing.T) {
+	registry := telemetry.NewRegistry()
+	r := chi.NewRouter()
+	r.Use(NewLoggingMiddleware(zap.NewNop(), registry))
+	r.Get("/value/{metricType}/{metricName}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("1"))
+	})
+	r.Route("/update", func(r chi.Router) {
+		r.Use(NewDecryptMiddleware(nil, zap.NewNop()).WithTelemetry(registry).DecryptBody)
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	for _, name := range []string{"a", "b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/value/gauge/"+name, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	for _, method := range []string{"BREW", "X-RANDOM-1"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/missing", nil))
+	}
+	req := httptest.NewRequest(http.MethodPost, "/update/", strings.NewReader("{}"))
+	req.Header.Set("X-Encrypted", "aes256gcm+rsa-oaep;v=1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var out strings.Builder
+	require.NoError(t, registry.WriteText(&out))
+
+	assert.Contains(t, out.String(), `metrics_server_http_requests_total{route="/value/{metricType}/{metricName}",method="GET",code="200"} 2`)
+	assert.Contains(t, out.String(), `metrics_server_http_requests_total{route="unmatched",method="GET",code="404"} 1`)
+	assert.Contains(t, out.String(), `metrics_server_http_requests_total{route="unmatched",method="other",code="405"} 2`)
+	assert.NotContains(t, out.String(), "BREW")
+	assert.Contains(t, out.String(), `metrics_server_http_requests_total{route="/update",method="POST",code="500"} 1`)
+	assert.Contains(t, out.String(), `metrics_server_decrypt_failures_total{transport="http"} 1`)
+	assert.Contains(t, out.String(), `metrics_server_http_request_duration_seconds_count{route="/value/{metricType}/{metricName}",method="GET"} 2`)
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/server"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/limiter"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
+	"go.uber.org/zap"
+)
+
+// quotaRetryAfter подсказывает клиенту паузу при превышении квот на размер пачки и число серий.
+const quotaRetryAfter = time.Minute
+
+type RateLimitMiddleware struct {
+	limiter *limiter.Limiter
+	proxies *security.TrustedProxies
+	logger  *zap.Logger
+}
+
+func NewRateLimitMiddleware(l *limiter.Limiter, logger *zap.Logger) *RateLimitMiddleware {
+	return &RateLimitMiddleware{limiter: l, logger: logger}
+}
+
+// WithTrustedProxies разрешает брать IP клиента из X-Real-IP для запросов, пришедших через эти прокси.
+func (m *RateLimitMiddleware) WithTrustedProxies(proxies *security.TrustedProxies) *RateLimitMiddleware {
+	m.proxies = proxies
+	return m
+}
+
+func (m *RateLimitMiddleware) LimitRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := LimitKey(r, m.proxies)
+		if ok, wait := m.limiter.Allow(key); !ok {
+			m.reject(w, key, "rate limit exceeded", wait)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitMetrics проверяет квоты по уже разобранным из запроса метрикам,
+// поэтому должен стоять после MetricCtxFromPath, MetricCtxFromBody или MetricsListCtxFromBody.
+func (m *RateLimitMiddleware) LimitMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var metrics []model.Metrics
+		if list, ok := r.Context().Value(server.MetricsList).([]model.Metrics); ok {
+			metrics = list
+		} else if metric, ok := r.Context().Value(server.Metric).(model.Metrics); ok {
+			metrics = []model.Metrics{metric}
+		}
+
+		key := LimitKey(r, m.proxies)
+		if !m.limiter.AllowBatch(len(metrics)) {
+			m.reject(w, key, "batch size limit exceeded", quotaRetryAfter)
+			return
+		}
+
+		series := make([]string, 0, len(metrics))
+		for _, metric := range metrics {
+			series = append(series, limiter.SeriesKey(metric.MType, metric.ID))
+		}
+		if !m.limiter.AdmitSeries(key, series) {
+			m.reject(w, key, "series limit exceeded", quotaRetryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *RateLimitMiddleware) reject(w http.ResponseWriter, key string, reason string, wait time.Duration) {
+	if m.logger != nil {
+		m.logger.Warn("request rejected by limiter", zap.String("client", key), zap.String("reason", reason))
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(limiter.RetryAfterSeconds(wait)))
+	http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+}
+
+// LimitKey определяет, по кому считать квоты: по арендатору, если он известен, иначе по IP клиента.
+// X-Real-IP учитывается только для запросов от доверенных прокси, иначе берётся адрес соединения.
+func LimitKey(r *http.Request, proxies *security.TrustedProxies) string {
+	if id := tenant.IDFromContext(r.Context()); id != "" {
+		return "tenant:" + id
+	}
+
+	return "ip:" + proxies.ClientIP(r.RemoteAddr, r.Header.Get("X-Real-IP"))
+}
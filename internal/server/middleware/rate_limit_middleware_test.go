@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/server"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/limiter"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRateLimitMiddleware_LimitRequests(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := NewRateLimitMiddleware(limiter.New(limiter.Config{RequestsPerSecond: 1, Burst: 1}), zap.NewNop()).LimitRequests(next)
+
+	send := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/update", nil)
+		req.RemoteAddr = ip + ":4321"
+		rr := httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, send("10.0.0.1").Code)
+
+	rr := send("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, send("10.0.0.2").Code)
+}
+
+func TestRateLimitMiddleware_LimitMetrics(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := NewRateLimitMiddleware(limiter.New(limiter.Config{MaxBatchSize: 2, MaxSeries: 2}), zap.NewNop()).LimitMetrics(next)
+
+	value := 1.0
+	send := func(metrics ...model.Metrics) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/updates", nil)
+		req = req.WithContext(context.WithValue(req.Context(), server.MetricsList, metrics))
+		rr := httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name           string
+		metrics        []model.Metrics
+		expectedStatus int
+	}{
+		{name: "Batch within limits → 200", metrics: []model.Metrics{*model.NewGauge("a", &value), *model.NewGauge("b", &value)}, expectedStatus: http.StatusOK},
+		{name: "Batch too large → 429", metrics: []model.Metrics{*model.NewGauge("a", &value), *model.NewGauge("b", &value), *model.NewGauge("c", &value)}, expectedStatus: http.StatusTooManyRequests},
+		{name: "New series over limit → 429", metrics: []model.Metrics{*model.NewGauge("c", &value)}, expectedStatus: http.StatusTooManyRequests},
+		{name: "Known series → 200", metrics: []model.Metrics{*model.NewGauge("a", &value)}, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedStatus, send(tt.metrics...).Code)
+		})
+	}
+}
+
+func TestLimitKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/update", nil)
+	req.RemoteAddr = "192.168.1.5:4321"
+	assert.Equal(t, "ip:192.168.1.5", LimitKey(req, nil))
+
+	req.Header.Set("X-Real-IP", "10.0.0.7")
+	assert.Equal(t, "ip:192.168.1.5", LimitKey(req, nil), "X-Real-IP from an untrusted peer is ignored")
+
+	proxies, err := security.ParseTrustedProxies("192.168.1.0/24")
+	require.NoError(t, err)
+	assert.Equal(t, "ip:10.0.0.7", LimitKey(req, proxies))
+
+	req.RemoteAddr = "172.16.0.1:4321"
+	assert.Equal(t, "ip:172.16.0.1", LimitKey(req, proxies))
+}
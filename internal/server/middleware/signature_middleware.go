@@ -6,13 +6,18 @@ import (
 	"net/http"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
 	"go.uber.org/zap"
 )
 
 type SignatureMiddleware struct {
 	signer     *signature.Signer
+	replay     *security.ReplayGuard
 	HashHeader string
 	logger     *zap.Logger
+	registry   *telemetry.Registry
 }
 
 type signatureWriter struct {
@@ -38,13 +43,36 @@ func NewSignatureMiddleware(signer *signature.Signer, logger *zap.Logger) *Signa
 	}
 }
 
+// WithReplayGuard включает защиту от повторов по подписанным метке времени и nonce.
+func (m *SignatureMiddleware) WithReplayGuard(guard *security.ReplayGuard) *SignatureMiddleware {
+	m.replay = guard
+	return m
+}
+
+// WithTelemetry включает учёт запросов, отклонённых проверкой подписи или защитой от повторов.
+func (m *SignatureMiddleware) WithTelemetry(registry *telemetry.Registry) *SignatureMiddleware {
+	m.registry = registry
+	return m
+}
+
 func (m *SignatureMiddleware) VerifySignature(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signer := m.signerFor(r)
+		if signer == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		hash := r.Header.Get(m.HashHeader)
 		if hash == "" {
-			m.logger.Error("no hash header", zap.String("hashHeader", m.HashHeader))
-			next.ServeHTTP(w, r)
-			//http.Error(w, "Signature is missing", http.StatusBadRequest)
+			// Запрос, подписанный ключом агента, не обязан нести HMAC; иначе без подписи его можно подделать и повторить.
+			if security.AgentFromContext(r.Context()) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			m.logger.Warn("no hash header", zap.String("hashHeader", m.HashHeader), zap.String("client", LimitKey(r, nil)))
+			m.registry.SignatureFailed(telemetry.TransportHTTP, telemetry.CheckHMAC)
+			http.Error(w, "Signature is missing", http.StatusBadRequest)
 			return
 		}
 
@@ -57,8 +85,29 @@ func (m *SignatureMiddleware) VerifySignature(next http.Handler) http.Handler {
 
 		r.Body = io.NopCloser(bytes.NewBuffer(body))
 
-		if !m.signer.Check(hash, body) {
+		timestamp := r.Header.Get(signature.TimestampHeader)
+		nonce := r.Header.Get(signature.NonceHeader)
+		payload := body
+		if timestamp != "" || nonce != "" {
+			payload = signature.SignedPayload(timestamp, nonce, body)
+		}
+
+		if !signer.Check(hash, payload) {
 			m.logger.Error("invalid signature", zap.String("hashHeader", m.HashHeader), zap.String("signature", hash), zap.String("body", string(body)))
+			m.registry.SignatureFailed(telemetry.TransportHTTP, telemetry.CheckHMAC)
+			http.Error(w, "Invalid signature", http.StatusBadRequest)
+			return
+		}
+
+		// Если запрос уже подписан ключом агента, nonce израсходован при той проверке.
+		if security.AgentFromContext(r.Context()) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := m.replay.Check(timestamp, nonce); err != nil {
+			m.logger.Warn("signed request rejected", zap.String("client", LimitKey(r, nil)), zap.Error(err))
+			m.registry.SignatureFailed(telemetry.TransportHTTP, telemetry.CheckReplay)
 			http.Error(w, "Invalid signature", http.StatusBadRequest)
 			return
 		}
@@ -79,7 +128,14 @@ func (m *SignatureMiddleware) AddSignature(next http.Handler) http.Handler {
 			return
 		}
 
-		hash, err := m.signer.Hash(rec.body)
+		signer := m.signerFor(r)
+		if signer == nil {
+			w.WriteHeader(rec.statusCode)
+			_, _ = w.Write(rec.body)
+			return
+		}
+
+		hash, err := signer.Hash(rec.body)
 		if err != nil {
 			http.Error(w, "failed to sign response", http.StatusInternalServerError)
 			return
@@ -91,3 +147,12 @@ func (m *SignatureMiddleware) AddSignature(next http.Handler) http.Handler {
 		_, _ = w.Write(rec.body)
 	})
 }
+
+// signerFor выбирает секрет арендатора, если он задан, иначе общий секрет сервера.
+func (m *SignatureMiddleware) signerFor(r *http.Request) *signature.Signer {
+	if t, ok := tenant.FromContext(r.Context()); ok && t.Signer() != nil {
+		return t.Signer()
+	}
+
+	return m.signer
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+)
+
+// SkipPaths применяет middleware ко всем запросам, кроме перечисленных путей.
+func SkipPaths(mw func(http.Handler) http.Handler, paths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(paths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+)
+
+// startupRetryAfter подсказывает клиенту паузу, пока сервер завершает запуск.
+const startupRetryAfter = "1"
+
+type StartupGateMiddleware struct {
+	gate *health.Gate
+}
+
+func NewStartupGateMiddleware(gate *health.Gate) *StartupGateMiddleware {
+	return &StartupGateMiddleware{gate: gate}
+}
+
+// RejectUntilOpen отвечает 503, пока не завершены миграции и восстановление состояния.
+func (m *StartupGateMiddleware) RejectUntilOpen(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.gate.IsOpen() {
+			w.Header().Set("Retry-After", startupRetryAfter)
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
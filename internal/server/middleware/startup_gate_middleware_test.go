@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartupGateMiddleware_RejectsUntilOpen(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	gate := &health.Gate{}
+	handler := SkipPaths(NewStartupGateMiddleware(gate).RejectUntilOpen, "/healthz", "/readyz")(next)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, nil))
+		return rr
+	}
+
+	rr := send("/update/counter/c/1")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, send("/healthz").Code, "probes are served while starting")
+
+	gate.Open()
+	assert.Equal(t, http.StatusOK, send("/update/counter/c/1").Code)
+}
@@ -6,11 +6,12 @@ import (
 	"strings"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/common/netaddr"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
 	"go.uber.org/zap"
 )
 
 type TrustedSubnetCheckMiddleware struct {
-	cidr   netip.Prefix
+	subnet *security.TrustedSubnet
 	logger *zap.Logger
 }
 
@@ -20,11 +21,26 @@ func NewTrustedSubnetChecker(cidr string, logger *zap.Logger) (*TrustedSubnetChe
 		return nil, err
 	}
 
-	return &TrustedSubnetCheckMiddleware{cidr: p, logger: logger}, nil
+	subnet := &security.TrustedSubnet{}
+	if err := subnet.Set(p.String()); err != nil {
+		return nil, err
+	}
+	return NewTrustedSubnetMiddleware(subnet, logger), nil
+}
+
+// NewTrustedSubnetMiddleware проверяет запросы по общей подсети; пока она пуста, запросы пропускаются.
+func NewTrustedSubnetMiddleware(subnet *security.TrustedSubnet, logger *zap.Logger) *TrustedSubnetCheckMiddleware {
+	return &TrustedSubnetCheckMiddleware{subnet: subnet, logger: logger}
 }
 
 func (m *TrustedSubnetCheckMiddleware) AllowOnlyTrusted(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cidr, enabled := m.subnet.Prefix()
+		if !enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		xRealIP := strings.TrimSpace(r.Header.Get("X-Real-IP"))
 		if xRealIP == "" {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
@@ -41,9 +57,9 @@ func (m *TrustedSubnetCheckMiddleware) AllowOnlyTrusted(next http.Handler) http.
 			return
 		}
 
-		if !m.cidr.Contains(addr) {
+		if !cidr.Contains(addr) {
 			if m.logger != nil {
-				m.logger.Warn("ip not in trusted subnet", zap.String("ip", xRealIP), zap.String("cidr", m.cidr.String()))
+				m.logger.Warn("ip not in trusted subnet", zap.String("ip", xRealIP), zap.String("cidr", cidr.String()))
 			}
 
 			http.Error(w, "Forbidden", http.StatusForbidden)
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
+	"go.uber.org/zap"
+)
+
+type TenantMiddleware struct {
+	registry *tenant.Registry
+	logger   *zap.Logger
+}
+
+func NewTenantMiddleware(registry *tenant.Registry, logger *zap.Logger) *TenantMiddleware {
+	return &TenantMiddleware{registry: registry, logger: logger}
+}
+
+func (m *TenantMiddleware) ResolveTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey := strings.TrimSpace(r.Header.Get(tenant.APIKeyHeader))
+		if apiKey == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		t, ok := m.registry.ByAPIKey(apiKey)
+		if !ok {
+			if m.logger != nil {
+				m.logger.Warn("unknown api key", zap.String("uri", r.RequestURI))
+			}
+
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.NewContext(r.Context(), t)))
+	})
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTenantMiddleware_ResolveTenant(t *testing.T) {
+	registry, err := tenant.NewRegistry(tenant.Tenant{ID: "team-a", APIKey: "key-a"})
+	require.NoError(t, err)
+
+	var resolved string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resolved = tenant.IDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := NewTenantMiddleware(registry, zap.NewNop()).ResolveTenant(next)
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		expectedStatus int
+		expectedTenant string
+	}{
+		{name: "Missing key → 401", apiKey: "", expectedStatus: http.StatusUnauthorized},
+		{name: "Unknown key → 401", apiKey: "other", expectedStatus: http.StatusUnauthorized},
+		{name: "Known key → 200", apiKey: "key-a", expectedStatus: http.StatusOK, expectedTenant: "team-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved = ""
+			req := httptest.NewRequest(http.MethodPost, "/update", nil)
+			if tt.apiKey != "" {
+				req.Header.Set(tenant.APIKeyHeader, tt.apiKey)
+			}
+			rr := httptest.NewRecorder()
+
+			mw.ServeHTTP(rr, req)
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedTenant, resolved)
+		})
+	}
+}
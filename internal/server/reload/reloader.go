@@ -0,0 +1,228 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/audit"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/middleware"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	ErrSigningToggle    = errors.New("enabling or disabling KEY requires restart")
+	ErrEncryptionToggle = errors.New("enabling or disabling CRYPTO_KEY requires restart")
+	ErrStoreInterval    = errors.New("STORE_INTERVAL must be positive")
+)
+
+// Targets — разделяемые объекты, которые перезагрузка меняет на месте. Пустые поля пропускаются.
+type Targets struct {
+	TrustedSubnet *security.TrustedSubnet
+	Signer        *signature.Signer
+	Decrypter     *middleware.Decrypter
+	Audit         *audit.AuditSubject
+	// AuditObservers строит наблюдателей аудита по новой конфигурации.
+	AuditObservers func(cfg *config.Config) []audit.Auditor
+	StoreInterval  *atomic.Uint64
+	LogLevel       *zap.AtomicLevel
+}
+
+// Reloader перечитывает конфигурацию и применяет настройки, которые безопасно менять без перезапуска:
+// доверенную подсеть, ключ подписи, ключи расшифровки, аудит, период сохранения и уровень логирования.
+// Конфигурация применяется целиком или не применяется вовсе.
+type Reloader struct {
+	mu      sync.Mutex
+	current config.Config
+	targets Targets
+	load    func() (*config.Config, error)
+	logger  *zap.Logger
+}
+
+func NewReloader(current *config.Config, targets Targets, logger *zap.Logger) *Reloader {
+	return &Reloader{
+		current: *current,
+		targets: targets,
+		load:    func() (*config.Config, error) { return config.LoadConfig(nil) },
+		logger:  logger,
+	}
+}
+
+// WithLoader заменяет источник конфигурации, по умолчанию config.LoadConfig.
+func (r *Reloader) WithLoader(load func() (*config.Config, error)) *Reloader {
+	r.load = load
+	return r
+}
+
+// Reload перечитывает конфигурацию и применяет её; при ошибке действуют прежние настройки.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.load()
+	if err == nil {
+		err = r.apply(next)
+	}
+	if err != nil {
+		r.logger.Error("Конфигурация не перезагружена", zap.Error(err))
+		return err
+	}
+
+	if ignored := restartOnly(r.current, *next); len(ignored) > 0 {
+		r.logger.Warn("Часть настроек применится только после перезапуска", zap.Strings("fields", ignored))
+	}
+	r.current = *next
+	r.logger.Info("Конфигурация перезагружена",
+		zap.String("trustedSubnet", next.TrustedSubnet),
+		zap.Uint64("storeInterval", next.DumpConfig.StoreInterval),
+		zap.String("logLevel", next.LogLevel),
+		zap.String("auditFile", next.AuditFile),
+		zap.String("auditURL", next.AuditURL),
+	)
+
+	return nil
+}
+
+// apply сначала проверяет всё, что может не пройти, и только затем меняет объекты.
+func (r *Reloader) apply(next *config.Config) error {
+	if (r.current.Key == "") != (next.Key == "") {
+		return ErrSigningToggle
+	}
+	if (r.current.CryptoKey == "") != (next.CryptoKey == "") {
+		return ErrEncryptionToggle
+	}
+	if next.DumpConfig.StoreInterval == 0 {
+		return ErrStoreInterval
+	}
+	if next.TrustedSubnet != "" {
+		if _, err := security.ParseTrustedCIDR(next.TrustedSubnet); err != nil {
+			return err
+		}
+	}
+	level, err := zapcore.ParseLevel(next.LogLevel)
+	if err != nil {
+		return err
+	}
+
+	// Ключи читаются с диска, но Decrypter меняет связку, только если прочитал её целиком.
+	if r.targets.Decrypter != nil && next.CryptoKey != "" {
+		if err := r.targets.Decrypter.ReloadFrom(next.CryptoKey); err != nil {
+			return err
+		}
+	}
+
+	if r.targets.TrustedSubnet != nil {
+		if err := r.targets.TrustedSubnet.Set(next.TrustedSubnet); err != nil {
+			return err
+		}
+	}
+	if r.targets.Signer != nil && next.Key != "" {
+		r.targets.Signer.SetKey(next.Key)
+	}
+	if r.targets.Audit != nil && r.targets.AuditObservers != nil {
+		r.targets.Audit.Replace(r.targets.AuditObservers(next)...)
+	}
+	if r.targets.StoreInterval != nil {
+		r.targets.StoreInterval.Store(next.DumpConfig.StoreInterval)
+	}
+	if r.targets.LogLevel != nil {
+		r.targets.LogLevel.SetLevel(level)
+	}
+
+	return nil
+}
+
+// restartOnly возвращает изменившиеся настройки, которые перезагрузка не применяет.
+func restartOnly(current, next config.Config) []string {
+	current, next = withoutReloadable(current), withoutReloadable(next)
+
+	var changed []string
+	currentValue, nextValue := reflect.ValueOf(current), reflect.ValueOf(next)
+	for i := 0; i < currentValue.NumField(); i++ {
+		if !reflect.DeepEqual(currentValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			changed = append(changed, currentValue.Type().Field(i).Name)
+		}
+	}
+
+	return changed
+}
+
+func withoutReloadable(cfg config.Config) config.Config {
+	cfg.TrustedSubnet = ""
+	cfg.Key = ""
+	cfg.CryptoKey = ""
+	cfg.AuditFile = ""
+	cfg.AuditURL = ""
+	cfg.AuditKey = ""
+	cfg.AuditFallbackFile = ""
+	cfg.AuditQueueSize = 0
+	cfg.AuditBatchSize = 0
+	cfg.DumpConfig.StoreInterval = 0
+	cfg.LogLevel = ""
+
+	return cfg
+}
+
+// Run перезагружает конфигурацию по SIGHUP и при изменении файла path, который проверяется раз в interval.
+// Пустой path или нулевой interval отключают слежение за файлом.
+func (r *Reloader) Run(ctx context.Context, path string, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var poll <-chan time.Time
+	if path != "" && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	stamp, _ := stampOf(path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			stamp, _ = stampOf(path)
+			_ = r.Reload()
+		case <-poll:
+			next, err := stampOf(path)
+			if err != nil || next.same(stamp) {
+				continue
+			}
+			stamp = next
+			_ = r.Reload()
+		}
+	}
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (s fileStamp) same(other fileStamp) bool {
+	return s.size == other.size && s.modTime.Equal(other.modTime)
+}
+
+func stampOf(path string) (fileStamp, error) {
+	if path == "" {
+		return fileStamp{}, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
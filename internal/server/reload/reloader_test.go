@@ -0,0 +1,166 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/audit"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type fixture struct {
+	subnet   *security.TrustedSubnet
+	signer   *signature.Signer
+	subject  *audit.AuditSubject
+	interval *atomic.Uint64
+	level    zap.AtomicLevel
+	next     config.Config
+	reloader *Reloader
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	current := config.Config{
+		Key:           "old",
+		TrustedSubnet: "10.0.0.0/8",
+		DumpConfig:    config.DumpConfig{StoreInterval: 300},
+		LogLevel:      "info",
+	}
+	subnet, err := security.NewTrustedSubnet(current.TrustedSubnet)
+	require.NoError(t, err)
+
+	f := &fixture{
+		subnet:   subnet,
+		signer:   signature.NewSign(current.Key),
+		subject:  audit.NewAuditSubject(),
+		interval: &atomic.Uint64{},
+		level:    zap.NewAtomicLevelAt(zapcore.InfoLevel),
+		next:     current,
+	}
+	f.interval.Store(current.DumpConfig.StoreInterval)
+	f.reloader = NewReloader(&current, Targets{
+		TrustedSubnet: f.subnet,
+		Signer:        f.signer,
+		Audit:         f.subject,
+		AuditObservers: func(cfg *config.Config) []audit.Auditor {
+			if cfg.AuditFile == "" {
+				return nil
+			}
+			return []audit.Auditor{audit.NewFileAuditor(cfg.AuditFile)}
+		},
+		StoreInterval: f.interval,
+		LogLevel:      &f.level,
+	}, zap.NewNop()).WithLoader(func() (*config.Config, error) {
+		next := f.next
+		return &next, nil
+	})
+
+	return f
+}
+
+func TestReloader_AppliesSafeSettings(t *testing.T) {
+	f := newFixture(t)
+	f.next.TrustedSubnet = "192.168.0.0/16"
+	f.next.Key = "new"
+	f.next.AuditFile = filepath.Join(t.TempDir(), "audit.log")
+	f.next.DumpConfig.StoreInterval = 10
+	f.next.LogLevel = "debug"
+
+	require.NoError(t, f.reloader.Reload())
+
+	prefix, enabled := f.subnet.Prefix()
+	assert.True(t, enabled)
+	assert.Equal(t, "192.168.0.0/16", prefix.String())
+	hash, err := signature.NewSign("new").Hash([]byte("body"))
+	require.NoError(t, err)
+	assert.True(t, f.signer.Check(hash, []byte("body")))
+	assert.Equal(t, 1, f.subject.Len())
+	assert.EqualValues(t, 10, f.interval.Load())
+	assert.Equal(t, zapcore.DebugLevel, f.level.Level())
+}
+
+func TestReloader_DisablesTrustedSubnet(t *testing.T) {
+	f := newFixture(t)
+	f.next.TrustedSubnet = ""
+
+	require.NoError(t, f.reloader.Reload())
+
+	_, enabled := f.subnet.Prefix()
+	assert.False(t, enabled)
+}
+
+func TestReloader_RejectsInvalidConfig(t *testing.T) {
+	cases := map[string]func(cfg *config.Config){
+		"invalid subnet":    func(cfg *config.Config) { cfg.TrustedSubnet = "not-a-cidr" },
+		"invalid level":     func(cfg *config.Config) { cfg.LogLevel = "loud" },
+		"zero interval":     func(cfg *config.Config) { cfg.DumpConfig.StoreInterval = 0 },
+		"signing disabled":  func(cfg *config.Config) { cfg.Key = "" },
+		"encryption toggle": func(cfg *config.Config) { cfg.CryptoKey = "/keys/private.pem" },
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			f.next.Key = "new"
+			f.next.AuditFile = filepath.Join(t.TempDir(), "audit.log")
+			mutate(&f.next)
+
+			require.Error(t, f.reloader.Reload())
+
+			prefix, _ := f.subnet.Prefix()
+			assert.Equal(t, "10.0.0.0/8", prefix.String())
+			hash, err := signature.NewSign("old").Hash([]byte("body"))
+			require.NoError(t, err)
+			assert.True(t, f.signer.Check(hash, []byte("body")))
+			assert.Equal(t, 0, f.subject.Len())
+			assert.EqualValues(t, 300, f.interval.Load())
+			assert.Equal(t, zapcore.InfoLevel, f.level.Level())
+		})
+	}
+}
+
+func TestReloader_RestartOnlyFields(t *testing.T) {
+	current := config.Config{Address: ":8080", Key: "a", LogLevel: "info"}
+	next := current
+	next.Address = ":9090"
+	next.Key = "b"
+	next.LogLevel = "warn"
+
+	assert.Equal(t, []string{"Address"}, restartOnly(current, next))
+}
+
+func TestReloader_RunWatchesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{}`), 0o644))
+
+	f := newFixture(t)
+	f.next.DumpConfig.StoreInterval = 42
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.reloader.Run(ctx, path, 10*time.Millisecond)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.EqualValues(t, 300, f.interval.Load(), "unchanged file must not trigger reload")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"StoreInterval": 42}`), 0o644))
+	assert.Eventually(t, func() bool { return f.interval.Load() == 42 }, time.Second, 10*time.Millisecond)
+}
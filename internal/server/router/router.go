@@ -5,13 +5,17 @@ import (
 	"net/http"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
-	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/audit"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/auth"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/config"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/handler"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/limiter"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/middleware"
-	serverModel "github.com/GoLessons/go-musthave-metrics/internal/server/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/security"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/tenant"
 	"github.com/GoLessons/go-musthave-metrics/pkg/container"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
@@ -24,19 +28,14 @@ func RouterFactory() container.Factory[*chi.Mux] {
 			return nil, err
 		}
 
-		counterStorage, err := container.GetService[storage.MemStorage[serverModel.Counter]](c, "counterStorage")
-		if err != nil {
-			return nil, err
-		}
-
-		gaugeStorage, err := container.GetService[storage.MemStorage[serverModel.Gauge]](c, "gaugeStorage")
+		metricService, err := container.GetService[service.MetricService](c, "metricService")
 		if err != nil {
 			return nil, err
 		}
 
-		metricService, err := container.GetService[service.MetricService](c, "metricService")
-		if err != nil {
-			return nil, err
+		tenantServices := service.NewTenantMetricServices(metricService)
+		if svc, err := container.GetService[service.TenantMetricServices](c, "tenantServices"); err == nil {
+			tenantServices = svc
 		}
 
 		var db *sql.DB
@@ -49,64 +48,141 @@ func RouterFactory() container.Factory[*chi.Mux] {
 			return nil, err
 		}
 
-		r := chi.NewRouter()
+		var registry *telemetry.Registry
+		if svc, err := container.GetService[telemetry.Registry](c, "telemetry"); err == nil {
+			registry = svc
+		}
 
-		r.Use(middleware.NewLoggingMiddleware(logger))
+		healthChecks := health.New()
+		if svc, err := container.GetService[health.Health](c, "health"); err == nil {
+			healthChecks = svc
+		}
+
+		var tenantMiddleware *middleware.TenantMiddleware
+		if cfg.TenantsFile != "" {
+			registry, err := container.GetService[tenant.Registry](c, "tenantRegistry")
+			if err != nil {
+				return nil, err
+			}
+			tenantMiddleware = middleware.NewTenantMiddleware(registry, logger)
+		}
 
-		var trustedChecker *middleware.TrustedSubnetCheckMiddleware
-		if cfg.TrustedSubnet != "" {
-			c, err := middleware.NewTrustedSubnetChecker(cfg.TrustedSubnet, logger)
+		var bearerAuth *middleware.BearerAuthMiddleware
+		if cfg.AuthConfig.JWKSFile != "" {
+			verifier, err := container.GetService[auth.Verifier](c, "jwtVerifier")
 			if err != nil {
 				return nil, err
 			}
-			trustedChecker = c
+			bearerAuth = middleware.NewBearerAuthMiddleware(verifier, logger)
 		}
 
-		if cfg.TrustedSubnet != "" {
-			checker, err := middleware.NewTrustedSubnetChecker(cfg.TrustedSubnet, logger)
+		// Служебные маршруты раскрывают данные всех арендаторов, поэтому без проверки администратора не регистрируются.
+		adminAuth := middleware.NewAdminAuthMiddleware(cfg.AuthConfig.AdminKey, bearerAuth, logger)
+
+		var rateLimitMiddleware *middleware.RateLimitMiddleware
+		if cfg.LimitConfig.Enabled() {
+			l, err := container.GetService[limiter.Limiter](c, "limiter")
 			if err != nil {
 				return nil, err
 			}
-			r.Use(checker.AllowOnlyTrusted)
+			proxies, err := container.GetService[security.TrustedProxies](c, "trustedProxies")
+			if err != nil {
+				if proxies, err = security.ParseTrustedProxies(cfg.LimitConfig.TrustedProxies); err != nil {
+					return nil, err
+				}
+			}
+			rateLimitMiddleware = middleware.NewRateLimitMiddleware(l, logger).WithTrustedProxies(proxies)
 		}
-		subject := audit.NewAuditSubject()
-		if cfg.AuditFile != "" {
-			subject.Register(audit.NewFileAuditor(cfg.AuditFile))
+
+		r := chi.NewRouter()
+
+		r.Use(middleware.NewLoggingMiddleware(logger, registry))
+
+		trustedSubnet, err := container.GetService[security.TrustedSubnet](c, "trustedSubnet")
+		if err != nil {
+			if trustedSubnet, err = security.NewTrustedSubnet(cfg.TrustedSubnet); err != nil {
+				return nil, err
+			}
 		}
-		if cfg.AuditURL != "" {
-			subject.Register(audit.NewRemoteAuditor(cfg.AuditURL))
+		// До завершения миграций и восстановления состояния отвечают только пробы.
+		if gate, err := container.GetService[health.Gate](c, "startupGate"); err == nil {
+			r.Use(middleware.SkipPaths(middleware.NewStartupGateMiddleware(gate).RejectUntilOpen, "/healthz", "/readyz"))
 		}
-		var auditSubject audit.Subject
-		if subject != nil {
-			auditSubject = subject
+
+		// Проверка общая для всех маршрутов и пропускает запросы, пока подсеть не задана, поэтому подключается всегда:
+		// подсеть можно включить перезагрузкой конфигурации.
+		trustedChecker := middleware.NewTrustedSubnetMiddleware(trustedSubnet, logger)
+		// Пробы оркестратора приходят не из доверенной подсети и без X-Real-IP.
+		r.Use(middleware.SkipPaths(trustedChecker.AllowOnlyTrusted, "/healthz", "/readyz"))
+
+		var auditSubject audit.Subject = audit.NewAuditSubject()
+		if svc, err := container.GetService[audit.AuditSubject](c, "auditSubject"); err == nil {
+			auditSubject = svc
 		}
 
-		metricControllerJSON := handler.NewMetricsController(*metricService, handler.JSONResposeBuilder, logger, auditSubject)
-		metricControllerPlain := handler.NewMetricsController(*metricService, handler.PlainResposeBuilder, logger, auditSubject)
+		metricControllerJSON := handler.NewMetricsController(tenantServices, handler.JSONResposeBuilder, logger, auditSubject)
+		metricControllerPlain := handler.NewMetricsController(tenantServices, handler.PlainResposeBuilder, logger, auditSubject)
 
 		var signatureMiddleware *middleware.SignatureMiddleware
-		if cfg.Key != "" {
-			signer := signature.NewSign(cfg.Key)
-			signatureMiddleware = middleware.NewSignatureMiddleware(signer, logger)
+		if cfg.Key != "" || tenantMiddleware != nil {
+			var signer *signature.Signer
+			if cfg.Key != "" {
+				signer = signature.NewSign(cfg.Key)
+				if svc, err := container.GetService[signature.Signer](c, "signer"); err == nil {
+					signer = svc
+				}
+			}
+			replayGuard, err := container.GetService[security.ReplayGuard](c, "replayGuard")
+			if err != nil {
+				return nil, err
+			}
+			signatureMiddleware = middleware.NewSignatureMiddleware(signer, logger).WithReplayGuard(replayGuard).WithTelemetry(registry)
+		}
+
+		var agentSignatureMiddleware *middleware.AgentSignatureMiddleware
+		if cfg.AgentKeysDir != "" {
+			agentKeyring, err := container.GetService[security.AgentKeyring](c, "agentKeyring")
+			if err != nil {
+				return nil, err
+			}
+			replayGuard, err := container.GetService[security.ReplayGuard](c, "replayGuard")
+			if err != nil {
+				return nil, err
+			}
+			agentSignatureMiddleware = middleware.NewAgentSignatureMiddleware(agentKeyring, replayGuard, logger).WithTelemetry(registry)
 		}
 
 		var decryptMiddleware *middleware.DecryptMiddleware
+		var decrypter *middleware.Decrypter
 		if cfg.CryptoKey != "" {
-			decrypter, err := middleware.NewDecrypterFromFile(cfg.CryptoKey)
+			decrypter, err = container.GetService[middleware.Decrypter](c, "decrypter")
 			if err != nil {
 				return nil, err
 			}
-			decryptMiddleware = middleware.NewDecryptMiddleware(decrypter, logger)
+			decryptMiddleware = middleware.NewDecryptMiddleware(decrypter, logger).WithTelemetry(registry)
 		} else {
-			decryptMiddleware = middleware.NewDecryptMiddleware(nil, logger)
+			decryptMiddleware = middleware.NewDecryptMiddleware(nil, logger).WithTelemetry(registry)
 		}
 
 		r.Route("/update/{metricType}/{metricName:[a-zA-Z0-9_-]+}/{metricValue:(-?)[a-z0-9\\.]+}",
 			func(r chi.Router) {
-				if trustedChecker != nil {
-					r.Use(trustedChecker.AllowOnlyTrusted)
+				r.Use(trustedChecker.AllowOnlyTrusted)
+				if bearerAuth != nil {
+					r.Use(bearerAuth.RequireScope(auth.ScopeWrite))
+				}
+				if tenantMiddleware != nil {
+					r.Use(tenantMiddleware.ResolveTenant)
+				}
+				if rateLimitMiddleware != nil {
+					r.Use(rateLimitMiddleware.LimitRequests)
+				}
+				if agentSignatureMiddleware != nil {
+					r.Use(agentSignatureMiddleware.VerifyAgent)
 				}
 				r.Use(middleware.MetricCtxFromPath)
+				if rateLimitMiddleware != nil {
+					r.Use(rateLimitMiddleware.LimitMetrics)
+				}
 				if signatureMiddleware != nil {
 					r.Use(signatureMiddleware.AddSignature)
 				}
@@ -115,8 +191,15 @@ func RouterFactory() container.Factory[*chi.Mux] {
 		)
 
 		r.Route("/value/{metricType}/{metricName:[a-zA-Z0-9_-]+}", func(r chi.Router) {
-			if trustedChecker != nil {
-				r.Use(trustedChecker.AllowOnlyTrusted)
+			r.Use(trustedChecker.AllowOnlyTrusted)
+			if bearerAuth != nil {
+				r.Use(bearerAuth.RequireScope(auth.ScopeRead))
+			}
+			if tenantMiddleware != nil {
+				r.Use(tenantMiddleware.ResolveTenant)
+			}
+			if rateLimitMiddleware != nil {
+				r.Use(rateLimitMiddleware.LimitRequests)
 			}
 			r.Use(middleware.GzipMiddleware)
 			r.Use(decryptMiddleware.DecryptBody)
@@ -128,16 +211,29 @@ func RouterFactory() container.Factory[*chi.Mux] {
 		})
 
 		r.Route("/update", func(r chi.Router) {
-			if trustedChecker != nil {
-				r.Use(trustedChecker.AllowOnlyTrusted)
+			r.Use(trustedChecker.AllowOnlyTrusted)
+			if bearerAuth != nil {
+				r.Use(bearerAuth.RequireScope(auth.ScopeWrite))
+			}
+			if tenantMiddleware != nil {
+				r.Use(tenantMiddleware.ResolveTenant)
+			}
+			if rateLimitMiddleware != nil {
+				r.Use(rateLimitMiddleware.LimitRequests)
 			}
 			r.Use(middleware.ValidateRoute)
+			if agentSignatureMiddleware != nil {
+				r.Use(agentSignatureMiddleware.VerifyAgent)
+			}
 			if signatureMiddleware != nil {
 				r.Use(signatureMiddleware.VerifySignature)
 			}
 			r.Use(middleware.GzipMiddleware)
 			r.Use(decryptMiddleware.DecryptBody)
 			r.Use(middleware.MetricCtxFromBody)
+			if rateLimitMiddleware != nil {
+				r.Use(rateLimitMiddleware.LimitMetrics)
+			}
 			if signatureMiddleware != nil {
 				r.Use(signatureMiddleware.AddSignature)
 			}
@@ -149,16 +245,29 @@ func RouterFactory() container.Factory[*chi.Mux] {
 		})
 
 		r.Route("/updates", func(r chi.Router) {
-			if trustedChecker != nil {
-				r.Use(trustedChecker.AllowOnlyTrusted)
+			r.Use(trustedChecker.AllowOnlyTrusted)
+			if bearerAuth != nil {
+				r.Use(bearerAuth.RequireScope(auth.ScopeWrite))
+			}
+			if tenantMiddleware != nil {
+				r.Use(tenantMiddleware.ResolveTenant)
+			}
+			if rateLimitMiddleware != nil {
+				r.Use(rateLimitMiddleware.LimitRequests)
 			}
 			r.Use(middleware.ValidateRoute)
+			if agentSignatureMiddleware != nil {
+				r.Use(agentSignatureMiddleware.VerifyAgent)
+			}
 			if signatureMiddleware != nil {
 				r.Use(signatureMiddleware.VerifySignature)
 			}
 			r.Use(middleware.GzipMiddleware)
 			r.Use(decryptMiddleware.DecryptBody)
 			r.Use(middleware.MetricsListCtxFromBody)
+			if rateLimitMiddleware != nil {
+				r.Use(rateLimitMiddleware.LimitMetrics)
+			}
 			if signatureMiddleware != nil {
 				r.Use(signatureMiddleware.AddSignature)
 			}
@@ -171,8 +280,15 @@ func RouterFactory() container.Factory[*chi.Mux] {
 
 		r.Route("/value",
 			func(r chi.Router) {
-				if trustedChecker != nil {
-					r.Use(trustedChecker.AllowOnlyTrusted)
+				r.Use(trustedChecker.AllowOnlyTrusted)
+				if bearerAuth != nil {
+					r.Use(bearerAuth.RequireScope(auth.ScopeRead))
+				}
+				if tenantMiddleware != nil {
+					r.Use(tenantMiddleware.ResolveTenant)
+				}
+				if rateLimitMiddleware != nil {
+					r.Use(rateLimitMiddleware.LimitRequests)
 				}
 				if signatureMiddleware != nil {
 					r.Use(signatureMiddleware.VerifySignature)
@@ -188,6 +304,40 @@ func RouterFactory() container.Factory[*chi.Mux] {
 			},
 		)
 
+		if adminAuth.Configured() {
+			r.Route("/admin/cardinality",
+				func(r chi.Router) {
+					r.Use(trustedChecker.AllowOnlyTrusted)
+					r.Use(adminAuth.RequireAdmin)
+					r.Get("/", handler.NewCardinalityController(tenantServices).Get)
+				},
+			)
+		}
+
+		if registry != nil && adminAuth.Configured() {
+			r.Route("/metrics",
+				func(r chi.Router) {
+					r.Use(trustedChecker.AllowOnlyTrusted)
+					r.Use(adminAuth.RequireAdmin)
+					r.Get("/", registry.Handler().ServeHTTP)
+				},
+			)
+		}
+
+		if decrypter != nil && adminAuth.Configured() {
+			r.Route("/admin/keys/reload",
+				func(r chi.Router) {
+					r.Use(trustedChecker.AllowOnlyTrusted)
+					r.Use(adminAuth.RequireAdmin)
+					r.Post("/", handler.NewKeysController(decrypter, logger).Reload)
+				},
+			)
+		}
+
+		healthController := handler.NewHealthController(healthChecks)
+		r.Get("/healthz", healthController.Live)
+		r.Get("/readyz", healthController.Ready)
+
 		r.Route("/ping",
 			func(r chi.Router) {
 				r.Use(func(next http.Handler) http.Handler { return next })
@@ -197,16 +347,20 @@ func RouterFactory() container.Factory[*chi.Mux] {
 
 		r.Route("/",
 			func(r chi.Router) {
-				if trustedChecker != nil {
-					r.Use(trustedChecker.AllowOnlyTrusted)
+				r.Use(trustedChecker.AllowOnlyTrusted)
+				if bearerAuth != nil {
+					r.Use(bearerAuth.RequireScope(auth.ScopeRead))
+				}
+				if tenantMiddleware != nil {
+					r.Use(tenantMiddleware.ResolveTenant)
+				}
+				if rateLimitMiddleware != nil {
+					r.Use(rateLimitMiddleware.LimitRequests)
 				}
 				r.Use(middleware.GzipMiddleware)
 				r.Get(
 					"/",
-					handler.NewListController(
-						counterStorage,
-						gaugeStorage,
-					).Get,
+					handler.NewListController(tenantServices).Get,
 				)
 			},
 		)
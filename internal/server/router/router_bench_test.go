@@ -2,8 +2,10 @@ package router
 
 import (
 	"bytes"
+	"flag"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
@@ -17,6 +19,14 @@ import (
 	"go.uber.org/zap"
 )
 
+// TestMain убирает из os.Args флаги go test: конфигурация сервера разбирает командную строку
+// и отвергает неизвестные флаги.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Args = os.Args[:1]
+	os.Exit(m.Run())
+}
+
 func buildRouter(withSignature bool) *chi.Mux {
 	opts := map[string]any{
 		"DatabaseDsn":                "",
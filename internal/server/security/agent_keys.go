@@ -0,0 +1,198 @@
+package security
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/keyid"
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/server"
+)
+
+var (
+	ErrAgentSignatureMissing = errors.New("agent signature is required")
+	ErrUnknownAgentKey       = errors.New("unknown agent key")
+	ErrInvalidAgentSignature = errors.New("invalid agent signature")
+)
+
+// agentKeysCheckInterval ограничивает частоту проверки каталога ключей на изменение.
+const agentKeysCheckInterval = time.Second
+
+type agentKey struct {
+	name string
+	pub  crypto.PublicKey
+}
+
+// AgentKeyring — открытые ключи зарегистрированных агентов из каталога: файл <имя агента>.pub или .pem.
+// Каталог перечитывается при изменении, поэтому отзыв агента сводится к удалению его файла.
+type AgentKeyring struct {
+	dir string
+
+	mu        sync.RWMutex
+	keys      map[string]agentKey
+	stamp     string
+	lastCheck time.Time
+	now       func() time.Time
+}
+
+func NewAgentKeyring(dir string) (*AgentKeyring, error) {
+	k := &AgentKeyring{dir: dir, now: time.Now}
+	stamp, err := k.dirStamp()
+	if err != nil {
+		return nil, err
+	}
+	if err := k.load(stamp); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Verify проверяет подпись агента и возвращает его имя.
+func (k *AgentKeyring) Verify(kid string, payload []byte, encodedSignature string) (string, error) {
+	if kid == "" || encodedSignature == "" {
+		return "", ErrAgentSignatureMissing
+	}
+
+	k.reloadIfChanged()
+
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownAgentKey, kid)
+	}
+
+	if !signature.VerifyKey(key.pub, payload, encodedSignature) {
+		return "", ErrInvalidAgentSignature
+	}
+
+	return key.name, nil
+}
+
+// Agents возвращает имена зарегистрированных агентов.
+func (k *AgentKeyring) Agents() []string {
+	k.reloadIfChanged()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	names := make([]string, 0, len(k.keys))
+	for _, key := range k.keys {
+		names = append(names, key.name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (k *AgentKeyring) load(stamp string) error {
+	files, err := k.keyFiles()
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]agentKey, len(files))
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		pub, err := signature.ParsePublicKey(b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		kid, err := keyid.FromPublicKey(pub)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if existing, ok := keys[kid]; ok {
+			return fmt.Errorf("agents %s and %s share the same key", existing.name, name)
+		}
+		keys[kid] = agentKey{name: name, pub: pub}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+	k.stamp = stamp
+	k.lastCheck = k.now()
+
+	return nil
+}
+
+// reloadIfChanged перечитывает каталог, если изменился состав файлов, их размер или время модификации.
+// При ошибке чтения остаются прежние ключи.
+func (k *AgentKeyring) reloadIfChanged() {
+	k.mu.Lock()
+	now := k.now()
+	if now.Sub(k.lastCheck) < agentKeysCheckInterval {
+		k.mu.Unlock()
+		return
+	}
+	k.lastCheck = now
+	previous := k.stamp
+	k.mu.Unlock()
+
+	stamp, err := k.dirStamp()
+	if err != nil || stamp == previous {
+		return
+	}
+	_ = k.load(stamp)
+}
+
+func (k *AgentKeyring) keyFiles() ([]string, error) {
+	entries, err := os.ReadDir(k.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".pub" && ext != ".pem") {
+			continue
+		}
+		files = append(files, filepath.Join(k.dir, entry.Name()))
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func (k *AgentKeyring) dirStamp() (string, error) {
+	files, err := k.keyFiles()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+
+	return b.String(), nil
+}
+
+func NewAgentContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, server.Agent, name)
+}
+
+// AgentFromContext возвращает имя агента, подписавшего запрос своим ключом, или пустую строку.
+func AgentFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(server.Agent).(string)
+	return name
+}
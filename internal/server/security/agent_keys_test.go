@@ -0,0 +1,75 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func enrollAgent(t *testing.T, dir string, name string) *signature.KeySigner {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	signer, err := signature.NewKeySigner(priv)
+	require.NoError(t, err)
+	return signer
+}
+
+func TestAgentKeyring_VerifyAndRevoke(t *testing.T) {
+	dir := t.TempDir()
+	first := enrollAgent(t, dir, "agent-1")
+	second := enrollAgent(t, dir, "agent-2")
+
+	keyring, err := NewAgentKeyring(dir)
+	require.NoError(t, err)
+	now := time.Now()
+	keyring.now = func() time.Time { return now }
+	assert.Equal(t, []string{"agent-1", "agent-2"}, keyring.Agents())
+
+	sig, err := first.Sign([]byte("payload"))
+	require.NoError(t, err)
+	name, err := keyring.Verify(first.KeyID(), []byte("payload"), sig)
+	require.NoError(t, err)
+	assert.Equal(t, "agent-1", name)
+
+	_, err = keyring.Verify(second.KeyID(), []byte("payload"), sig)
+	assert.ErrorIs(t, err, ErrInvalidAgentSignature)
+	_, err = keyring.Verify("", []byte("payload"), sig)
+	assert.ErrorIs(t, err, ErrAgentSignatureMissing)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "agent-1.pub")))
+	_, err = keyring.Verify(first.KeyID(), []byte("payload"), sig)
+	assert.NoError(t, err, "directory is not rechecked within the interval")
+
+	now = now.Add(2 * agentKeysCheckInterval)
+	_, err = keyring.Verify(first.KeyID(), []byte("payload"), sig)
+	assert.ErrorIs(t, err, ErrUnknownAgentKey)
+	assert.Equal(t, []string{"agent-2"}, keyring.Agents())
+}
+
+func TestAgentKeyring_KeepsKeysOnBrokenFile(t *testing.T) {
+	dir := t.TempDir()
+	enrollAgent(t, dir, "agent-1")
+
+	keyring, err := NewAgentKeyring(dir)
+	require.NoError(t, err)
+	now := time.Now()
+	keyring.now = func() time.Time { return now }
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.pub"), []byte("garbage"), 0o600))
+	now = now.Add(2 * agentKeysCheckInterval)
+	assert.Equal(t, []string{"agent-1"}, keyring.Agents())
+}
@@ -0,0 +1,101 @@
+package security
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrReplayMalformed = errors.New("malformed signature timestamp or nonce")
+	ErrReplayStale     = errors.New("signature timestamp is outside of allowed window")
+	ErrReplayDetected  = errors.New("nonce has already been used")
+	ErrReplayMissing   = errors.New("signature timestamp and nonce are required")
+	ErrReplayCacheFull = errors.New("nonce cache is full")
+)
+
+// maxNonces ограничивает кэш nonce; при переполнении новые запросы отклоняются, а не вытесняют старые nonce.
+const maxNonces = 1 << 20
+
+// maxNonceLength отсекает заведомо мусорные nonce, чтобы они не раздували кэш.
+const maxNonceLength = 64
+
+// ReplayGuard отклоняет подписанные запросы со слишком старой меткой времени и повторно использованным nonce.
+// Nonce хранится, пока метка времени запроса не выйдет за окно: после этого повтор отсечёт проверка времени.
+type ReplayGuard struct {
+	window   time.Duration
+	required bool
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewReplayGuard(window time.Duration, required bool) *ReplayGuard {
+	return &ReplayGuard{
+		window:   window,
+		required: required,
+		nonces:   make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// Required сообщает, нужно ли отклонять подписи без метки времени и nonce (от старых агентов).
+func (g *ReplayGuard) Required() bool {
+	return g != nil && g.required
+}
+
+// Check проверяет метку времени (секунды Unix) и запоминает nonce; вызывать только после проверки подписи,
+// иначе посторонний сможет «сжечь» чужие nonce.
+func (g *ReplayGuard) Check(timestamp string, nonce string) error {
+	if timestamp == "" && nonce == "" {
+		if g.Required() {
+			return ErrReplayMissing
+		}
+		return nil
+	}
+	if g == nil {
+		return nil
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || nonce == "" || len(nonce) > maxNonceLength {
+		return ErrReplayMalformed
+	}
+	signedAt := time.Unix(seconds, 0)
+
+	now := g.now()
+	if signedAt.Before(now.Add(-g.window)) || signedAt.After(now.Add(g.window)) {
+		return ErrReplayStale
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sweep(now)
+
+	if _, seen := g.nonces[nonce]; seen {
+		return ErrReplayDetected
+	}
+	if len(g.nonces) >= maxNonces {
+		return ErrReplayCacheFull
+	}
+	g.nonces[nonce] = signedAt.Add(g.window)
+
+	return nil
+}
+
+func (g *ReplayGuard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < time.Second {
+		return
+	}
+	g.lastSweep = now
+
+	for nonce, expires := range g.nonces {
+		if now.After(expires) {
+			delete(g.nonces, nonce)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package security
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayGuard_Check(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	guard := NewReplayGuard(time.Minute, false)
+	guard.now = func() time.Time { return now }
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	assert.NoError(t, guard.Check(timestamp, "nonce-1"))
+	assert.ErrorIs(t, guard.Check(timestamp, "nonce-1"), ErrReplayDetected)
+	assert.NoError(t, guard.Check(timestamp, "nonce-2"))
+
+	stale := strconv.FormatInt(now.Add(-2*time.Minute).Unix(), 10)
+	assert.ErrorIs(t, guard.Check(stale, "nonce-3"), ErrReplayStale)
+	future := strconv.FormatInt(now.Add(2*time.Minute).Unix(), 10)
+	assert.ErrorIs(t, guard.Check(future, "nonce-4"), ErrReplayStale)
+
+	assert.ErrorIs(t, guard.Check("yesterday", "nonce-5"), ErrReplayMalformed)
+	assert.ErrorIs(t, guard.Check(timestamp, ""), ErrReplayMalformed)
+
+	assert.NoError(t, guard.Check("", ""), "legacy signatures are allowed unless required")
+}
+
+func TestReplayGuard_ForgetsExpiredNonces(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	guard := NewReplayGuard(time.Minute, true)
+	guard.now = func() time.Time { return now }
+
+	assert.NoError(t, guard.Check(strconv.FormatInt(now.Unix(), 10), "nonce"))
+	assert.Len(t, guard.nonces, 1)
+
+	now = now.Add(2 * time.Minute)
+	assert.NoError(t, guard.Check(strconv.FormatInt(now.Unix(), 10), "other"))
+	assert.Len(t, guard.nonces, 1)
+
+	assert.ErrorIs(t, guard.Check("", ""), ErrReplayMissing)
+}
+
+func TestReplayGuard_NilDisablesChecks(t *testing.T) {
+	var guard *ReplayGuard
+	assert.False(t, guard.Required())
+	assert.NoError(t, guard.Check("", ""))
+	assert.NoError(t, guard.Check("1", "nonce"))
+}
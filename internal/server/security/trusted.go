@@ -1,8 +1,10 @@
 package security
 
 import (
+	"net"
 	"net/netip"
 	"strings"
+	"sync/atomic"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/common/netaddr"
 )
@@ -23,6 +25,91 @@ func IsIPTrusted(prefix netip.Prefix, ipString string) (bool, error) {
 	return prefix.Contains(address), nil
 }
 
+// TrustedSubnet — доверенная подсеть, общая для HTTP и gRPC; её можно сменить без перезапуска.
+// Пустая подсеть отключает проверку.
+type TrustedSubnet struct {
+	prefix atomic.Pointer[netip.Prefix]
+}
+
+func NewTrustedSubnet(cidr string) (*TrustedSubnet, error) {
+	subnet := &TrustedSubnet{}
+	if err := subnet.Set(cidr); err != nil {
+		return nil, err
+	}
+	return subnet, nil
+}
+
+// Set меняет подсеть; при ошибке разбора остаётся прежняя.
+func (s *TrustedSubnet) Set(cidr string) error {
+	if cidr == "" {
+		s.prefix.Store(nil)
+		return nil
+	}
+	prefix, err := ParseTrustedCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	s.prefix.Store(&prefix)
+	return nil
+}
+
+// Prefix возвращает текущую подсеть; false — проверка отключена.
+func (s *TrustedSubnet) Prefix() (netip.Prefix, bool) {
+	prefix := s.prefix.Load()
+	if prefix == nil {
+		return netip.Prefix{}, false
+	}
+	return *prefix, true
+}
+
+// TrustedProxies — подсети прокси, которым разрешено сообщать адрес клиента в X-Real-IP.
+// Запросам от остальных адресов заголовок не доверяется: его может подставить сам клиент.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// ParseTrustedProxies разбирает список CIDR через запятую; пустой список не доверяет никому.
+func ParseTrustedProxies(list string) (*TrustedProxies, error) {
+	proxies := &TrustedProxies{}
+	for _, cidr := range strings.Split(list, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		prefix, err := ParseTrustedCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		proxies.prefixes = append(proxies.prefixes, prefix)
+	}
+	return proxies, nil
+}
+
+// ClientIP возвращает адрес клиента: X-Real-IP, если соединение пришло от доверенного прокси, иначе адрес соединения.
+// remoteAddr передаётся в виде host:port или просто host.
+func (p *TrustedProxies) ClientIP(remoteAddr string, realIP string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	realIP = strings.TrimSpace(realIP)
+	if p == nil || realIP == "" {
+		return host
+	}
+
+	address, err := netaddr.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(address) {
+			return realIP
+		}
+	}
+
+	return host
+}
+
 type simpleError struct{ message string }
 
 func (e *simpleError) Error() string { return e.message }
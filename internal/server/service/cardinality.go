@@ -0,0 +1,242 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrCardinalityLimit = errors.New("cardinality limit exceeded")
+
+// OverflowPolicy определяет, что делать с новой серией, не помещающейся в лимит.
+type OverflowPolicy string
+
+const (
+	OverflowReject OverflowPolicy = "reject"
+	OverflowDrop   OverflowPolicy = "drop"
+	OverflowFold   OverflowPolicy = "fold"
+)
+
+// OverflowSeries — серия, в которую сворачиваются метрики сверх лимита при политике fold.
+// Сама она в лимите не учитывается.
+const OverflowSeries = "_overflow"
+
+// maxTrackedKeys ограничивает статистику по префиксам и клиентам, чтобы она не стала новым источником роста памяти.
+const maxTrackedKeys = 10000
+
+type CardinalityConfig struct {
+	MaxNames          int
+	MaxNamesPerPrefix int
+	Overflow          OverflowPolicy
+}
+
+func (c CardinalityConfig) Enabled() bool {
+	return c.MaxNames > 0 || c.MaxNamesPerPrefix > 0
+}
+
+type CardinalityEntry struct {
+	Key       string `json:"key"`
+	Series    int    `json:"series"`
+	Overflows int    `json:"overflows"`
+}
+
+type CardinalityReport struct {
+	Series            int                `json:"series"`
+	TotalSeries       int                `json:"total_series"`
+	MaxNames          int                `json:"max_names,omitempty"`
+	MaxNamesPerPrefix int                `json:"max_names_per_prefix,omitempty"`
+	Overflow          OverflowPolicy     `json:"overflow,omitempty"`
+	Prefixes          []CardinalityEntry `json:"prefixes"`
+	Clients           []CardinalityEntry `json:"clients"`
+}
+
+type cardinalityStat struct {
+	series    int
+	overflows int
+}
+
+// seriesBudget — общий для всех арендаторов счётчик серий, по которому проверяется MaxNames.
+type seriesBudget struct {
+	mu   sync.Mutex
+	used int
+}
+
+// take занимает место под новую серию, если лимит max ещё не исчерпан.
+func (b *seriesBudget) take(max int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if max > 0 && b.used >= max {
+		return false
+	}
+	b.used++
+
+	return true
+}
+
+func (b *seriesBudget) total() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.used
+}
+
+// CardinalityGuard ограничивает число различных серий (тип + имя) в хранилище:
+// глобально по всем арендаторам и по префиксу имени в пределах арендатора,
+// а также копит статистику для поиска нарушителей.
+type CardinalityGuard struct {
+	mu       sync.Mutex
+	cfg      CardinalityConfig
+	budget   *seriesBudget
+	series   map[string]struct{}
+	prefixes map[string]*cardinalityStat
+	clients  map[string]*cardinalityStat
+}
+
+func NewCardinalityGuard(cfg CardinalityConfig) *CardinalityGuard {
+	if cfg.Overflow == "" {
+		cfg.Overflow = OverflowReject
+	}
+
+	return newCardinalityGuard(cfg, &seriesBudget{})
+}
+
+func newCardinalityGuard(cfg CardinalityConfig, budget *seriesBudget) *CardinalityGuard {
+	return &CardinalityGuard{
+		cfg:      cfg,
+		budget:   budget,
+		series:   make(map[string]struct{}),
+		prefixes: make(map[string]*cardinalityStat),
+		clients:  make(map[string]*cardinalityStat),
+	}
+}
+
+// ForTenant создаёт ограничитель для другого арендатора: статистика и лимит по префиксу у него свои,
+// а MaxNames считается по сериям всех арендаторов вместе.
+func (g *CardinalityGuard) ForTenant() *CardinalityGuard {
+	return newCardinalityGuard(g.cfg, g.budget)
+}
+
+// Admit решает судьбу записи: возвращает имя, под которым её сохранить,
+// или store == false, если запись нужно молча отбросить.
+func (g *CardinalityGuard) Admit(metricType string, name string, source string) (id string, store bool, err error) {
+	if name == OverflowSeries {
+		return name, true, nil
+	}
+
+	key := metricType + ":" + name
+	prefix := MetricPrefix(name)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, known := g.series[key]; known {
+		return name, true, nil
+	}
+
+	prefixSeries := 0
+	if stat, ok := g.prefixes[prefix]; ok {
+		prefixSeries = stat.series
+	}
+
+	overPrefix := g.cfg.MaxNamesPerPrefix > 0 && prefixSeries >= g.cfg.MaxNamesPerPrefix
+	if !overPrefix && g.budget.take(g.cfg.MaxNames) {
+		g.series[key] = struct{}{}
+		// Префикс допущенной серии учитываем всегда, иначе перестанет работать лимит по префиксу.
+		if stat := g.stat(g.prefixes, prefix, true); stat != nil {
+			stat.series++
+		}
+		if stat := g.stat(g.clients, source, false); stat != nil {
+			stat.series++
+		}
+
+		return name, true, nil
+	}
+
+	if stat := g.stat(g.prefixes, prefix, false); stat != nil {
+		stat.overflows++
+	}
+	if stat := g.stat(g.clients, source, false); stat != nil {
+		stat.overflows++
+	}
+
+	switch g.cfg.Overflow {
+	case OverflowDrop:
+		return "", false, nil
+	case OverflowFold:
+		return OverflowSeries, true, nil
+	default:
+		return "", false, fmt.Errorf("%w: %s", ErrCardinalityLimit, name)
+	}
+}
+
+// Report возвращает состояние лимитов и top крупнейших префиксов и клиентов.
+func (g *CardinalityGuard) Report(top int) CardinalityReport {
+	if g == nil {
+		return CardinalityReport{Prefixes: []CardinalityEntry{}, Clients: []CardinalityEntry{}}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return CardinalityReport{
+		Series:            len(g.series),
+		TotalSeries:       g.budget.total(),
+		MaxNames:          g.cfg.MaxNames,
+		MaxNamesPerPrefix: g.cfg.MaxNamesPerPrefix,
+		Overflow:          g.cfg.Overflow,
+		Prefixes:          topEntries(g.prefixes, top),
+		Clients:           topEntries(g.clients, top),
+	}
+}
+
+func (g *CardinalityGuard) stat(stats map[string]*cardinalityStat, key string, force bool) *cardinalityStat {
+	if key == "" {
+		return nil
+	}
+
+	stat, ok := stats[key]
+	if !ok {
+		if !force && len(stats) >= maxTrackedKeys {
+			return nil
+		}
+		stat = &cardinalityStat{}
+		stats[key] = stat
+	}
+
+	return stat
+}
+
+func topEntries(stats map[string]*cardinalityStat, top int) []CardinalityEntry {
+	entries := make([]CardinalityEntry, 0, len(stats))
+	for key, stat := range stats {
+		entries = append(entries, CardinalityEntry{Key: key, Series: stat.series, Overflows: stat.overflows})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Overflows != entries[j].Overflows {
+			return entries[i].Overflows > entries[j].Overflows
+		}
+		if entries[i].Series != entries[j].Series {
+			return entries[i].Series > entries[j].Series
+		}
+		return entries[i].Key < entries[j].Key
+	})
+
+	if top > 0 && len(entries) > top {
+		entries = entries[:top]
+	}
+
+	return entries
+}
+
+// MetricPrefix выделяет префикс имени метрики — часть до первого разделителя "_", ".", "-" или ":".
+func MetricPrefix(name string) string {
+	if i := strings.IndexAny(name, "_.-:"); i > 0 {
+		return name[:i]
+	}
+
+	return name
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	serverModel "github.com/GoLessons/go-musthave-metrics/internal/server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGuardedService(cfg CardinalityConfig) *MetricService {
+	return NewMetricService(
+		storage.NewMemStorage[serverModel.Counter](),
+		storage.NewMemStorage[serverModel.Gauge](),
+	).WithCardinalityGuard(NewCardinalityGuard(cfg))
+}
+
+func TestCardinality_Reject(t *testing.T) {
+	ms := newGuardedService(CardinalityConfig{MaxNames: 2, Overflow: OverflowReject})
+	value := 1.0
+
+	require.NoError(t, ms.Save(*model.NewGauge("a", &value)))
+	require.NoError(t, ms.Save(*model.NewGauge("b", &value)))
+	require.NoError(t, ms.Save(*model.NewGauge("a", &value)), "known series are always accepted")
+
+	err := ms.Save(*model.NewGauge("c", &value))
+	assert.ErrorIs(t, err, ErrCardinalityLimit)
+
+	_, err = ms.Read(model.Gauge, "c")
+	assert.Error(t, err)
+}
+
+func TestCardinality_Drop(t *testing.T) {
+	ms := newGuardedService(CardinalityConfig{MaxNames: 1, Overflow: OverflowDrop})
+	value := 1.0
+
+	require.NoError(t, ms.Save(*model.NewGauge("a", &value)))
+	require.NoError(t, ms.Save(*model.NewGauge("b", &value)))
+
+	gauges, err := ms.GetAllGauges()
+	require.NoError(t, err)
+	assert.Len(t, gauges, 1)
+}
+
+func TestCardinality_FoldPerPrefix(t *testing.T) {
+	ms := newGuardedService(CardinalityConfig{MaxNamesPerPrefix: 1, Overflow: OverflowFold})
+	delta := int64(2)
+
+	require.NoError(t, ms.SaveFrom(*model.NewCounter("http_a", &delta), "10.0.0.1"))
+	require.NoError(t, ms.SaveFrom(*model.NewCounter("http_b", &delta), "10.0.0.2"))
+	require.NoError(t, ms.SaveFrom(*model.NewCounter("http_c", &delta), "10.0.0.2"))
+	require.NoError(t, ms.SaveFrom(*model.NewCounter("db_a", &delta), "10.0.0.1"))
+
+	overflow, err := ms.Read(model.Counter, OverflowSeries)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), *overflow.Delta)
+
+	report := ms.Cardinality().Report(1)
+	assert.Equal(t, 2, report.Series)
+	require.Len(t, report.Prefixes, 1)
+	assert.Equal(t, CardinalityEntry{Key: "http", Series: 1, Overflows: 2}, report.Prefixes[0])
+	require.Len(t, report.Clients, 1)
+	assert.Equal(t, CardinalityEntry{Key: "10.0.0.2", Series: 0, Overflows: 2}, report.Clients[0])
+}
+
+func TestCardinality_MaxNamesIsSharedByTenants(t *testing.T) {
+	def := newGuardedService(CardinalityConfig{MaxNames: 2})
+	tenants := NewTenantMetricServices(def)
+	value := 1.0
+
+	require.NoError(t, def.Save(*model.NewGauge("a", &value)))
+	require.NoError(t, tenants.ForTenant("team-a").Save(*model.NewGauge("a", &value)), "same name of another tenant is a separate series")
+	assert.ErrorIs(t, tenants.ForTenant("team-b").Save(*model.NewGauge("b", &value)), ErrCardinalityLimit)
+
+	report := tenants.ForTenant("team-a").Cardinality().Report(1)
+	assert.Equal(t, 1, report.Series)
+	assert.Equal(t, 2, report.TotalSeries)
+}
+
+func TestCardinality_PrefixLimitIsPerTenant(t *testing.T) {
+	def := newGuardedService(CardinalityConfig{MaxNamesPerPrefix: 1})
+	tenants := NewTenantMetricServices(def)
+	value := 1.0
+
+	require.NoError(t, def.Save(*model.NewGauge("http_a", &value)))
+	require.NoError(t, tenants.ForTenant("team-a").Save(*model.NewGauge("http_b", &value)))
+	assert.ErrorIs(t, def.Save(*model.NewGauge("http_c", &value)), ErrCardinalityLimit)
+}
+
+func TestMetricPrefix(t *testing.T) {
+	assert.Equal(t, "http", MetricPrefix("http_requests_total"))
+	assert.Equal(t, "app", MetricPrefix("app.latency"))
+	assert.Equal(t, "Alloc", MetricPrefix("Alloc"))
+	assert.Equal(t, "_overflow", MetricPrefix("_overflow"))
+}
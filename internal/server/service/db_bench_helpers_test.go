@@ -36,11 +36,12 @@ func ensureSchema(db *sql.DB) error {
 	const createSchema = `CREATE SCHEMA IF NOT EXISTS "metrics";`
 	const createTable = `
 CREATE TABLE IF NOT EXISTS "metrics"."metrics" (
+    "tenant" text NOT NULL DEFAULT '',
     "name"  text NOT NULL,
     "type"  text NOT NULL,
     "delta" bigint DEFAULT NULL,
     "value" double precision,
-    PRIMARY KEY ("name","type")
+    PRIMARY KEY ("tenant","name","type")
 );`
 	if _, err := db.Exec(createSchema); err != nil {
 		return err
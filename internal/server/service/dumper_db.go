@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"sync"
 
-	"github.com/GoLessons/go-musthave-metrics/internal/model"
 	"github.com/Masterminds/squirrel"
 	"go.uber.org/zap"
 )
@@ -24,21 +23,24 @@ func NewDBMetricDumper(db *sql.DB, logger *zap.Logger) *dbMetricDumper {
 	}
 }
 
-func (d *dbMetricDumper) Dump(metrics []model.Metrics) error {
+func (d *dbMetricDumper) Dump(metrics TenantMetrics) error {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	if len(metrics) == 0 {
-		return nil
-	}
-
 	insert := squirrel.Insert("metrics.metrics").
-		Columns("name", "type", "delta", "value").
+		Columns("tenant", "name", "type", "delta", "value").
 		PlaceholderFormat(squirrel.Dollar).
-		Suffix("ON CONFLICT (name,type) DO UPDATE SET delta = EXCLUDED.delta, value = EXCLUDED.value")
-
-	for _, metric := range metrics {
-		insert = insert.Values(metric.ID, metric.MType, metric.Delta, metric.Value)
+		Suffix("ON CONFLICT (tenant,name,type) DO UPDATE SET delta = EXCLUDED.delta, value = EXCLUDED.value")
+
+	rows := 0
+	for tenantID, tenantMetrics := range metrics {
+		for _, metric := range tenantMetrics {
+			insert = insert.Values(tenantID, metric.ID, metric.MType, metric.Delta, metric.Value)
+			rows++
+		}
+	}
+	if rows == 0 {
+		return nil
 	}
 
 	queryString, args, err := insert.ToSql()
@@ -21,7 +21,7 @@ func BenchmarkDBMetricDumper_Dump(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		if err := d.Dump(metrics); err != nil {
+		if err := d.Dump(TenantMetrics{"": metrics}); err != nil {
 			b.Fatalf("dump error: %v", err)
 		}
 	}
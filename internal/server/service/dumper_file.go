@@ -5,7 +5,6 @@ import (
 	"os"
 	"sync"
 
-	"github.com/GoLessons/go-musthave-metrics/internal/model"
 	"github.com/goccy/go-json"
 )
 
@@ -20,7 +19,8 @@ func NewFileMetricDumper(filePath string) *fileMetricDumper {
 	}
 }
 
-func (d *fileMetricDumper) Dump(metrics []model.Metrics) error {
+// Dump пишет снимок объектом «арендатор → метрики» через временный файл.
+func (d *fileMetricDumper) Dump(metrics TenantMetrics) error {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
@@ -25,6 +25,6 @@ func BenchmarkFileMetricDumper_Dump(b *testing.B) {
 
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		_ = d.Dump(metrics)
+		_ = d.Dump(TenantMetrics{"": metrics})
 	}
 }
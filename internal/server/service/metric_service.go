@@ -6,11 +6,14 @@ import (
 	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
 	"github.com/GoLessons/go-musthave-metrics/internal/model"
 	serverModel "github.com/GoLessons/go-musthave-metrics/internal/server/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
 )
 
 type MetricService struct {
 	counterStorage storage.Storage[serverModel.Counter]
 	gaugeStorage   storage.Storage[serverModel.Gauge]
+	guard          *CardinalityGuard
+	telemetry      *telemetry.Registry
 }
 
 func NewMetricService(
@@ -23,12 +26,61 @@ func NewMetricService(
 	}
 }
 
+// WithCardinalityGuard включает лимиты кардинальности для всех путей записи через сервис.
+func (ms *MetricService) WithCardinalityGuard(guard *CardinalityGuard) *MetricService {
+	ms.guard = guard
+	return ms
+}
+
+func (ms *MetricService) Cardinality() *CardinalityGuard {
+	return ms.guard
+}
+
+// WithTelemetry включает учёт длительности и ошибок сохранения и восстановления состояния.
+func (ms *MetricService) WithTelemetry(registry *telemetry.Registry) *MetricService {
+	ms.telemetry = registry
+	return ms
+}
+
+// Size возвращает число хранимых counter и gauge.
+func (ms *MetricService) Size() (counters, gauges int) {
+	return storageLen(ms.counterStorage), storageLen(ms.gaugeStorage)
+}
+
+func storageLen[T any](s storage.Storage[T]) int {
+	if sized, ok := s.(interface{ Len() int }); ok {
+		return sized.Len()
+	}
+	all, err := s.GetAll()
+	if err != nil {
+		return 0
+	}
+
+	return len(all)
+}
+
 func (ms *MetricService) Save(metric model.Metrics) error {
+	return ms.SaveFrom(metric, "")
+}
+
+// SaveFrom сохраняет метрику; source — адрес клиента, по которому копится статистика кардинальности.
+func (ms *MetricService) SaveFrom(metric model.Metrics, source string) error {
 	err := ms.validate(metric)
 	if err != nil {
 		return err
 	}
 
+	if ms.guard != nil {
+		id, store, err := ms.guard.Admit(metric.MType, metric.ID, source)
+		if err != nil {
+			return err
+		}
+		if !store {
+			return nil
+		}
+		metric.ID = id
+	}
+
 	switch metric.MType {
 	case model.Counter:
 		var counter serverModel.Counter
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -14,16 +16,22 @@ import (
 	"github.com/GoLessons/go-musthave-metrics/pkg/repeater"
 )
 
+// shutdownStoreTimeout ограничивает финальное сохранение состояния при остановке.
+const shutdownStoreTimeout = 5 * time.Second
+
+// TenantMetrics — снимок метрик по арендаторам; пустой ключ соответствует арендатору по умолчанию.
+type TenantMetrics map[string][]model.Metrics
+
 type MetricDumper interface {
-	Dump([]model.Metrics) error
+	Dump(TenantMetrics) error
 }
 
 type MetricRestorer interface {
-	Restore() ([]model.Metrics, error)
+	Restore() (TenantMetrics, error)
 }
 
 type MetricStorageService struct {
-	metricService  *MetricService
+	metricServices *TenantMetricServices
 	metricDumper   MetricDumper
 	metricRestorer MetricRestorer
 	shutdownCh     chan os.Signal
@@ -32,12 +40,12 @@ type MetricStorageService struct {
 }
 
 func NewMetricStorageService(
-	metricService *MetricService,
+	metricServices *TenantMetricServices,
 	metricDumper MetricDumper,
 	metricRestorer MetricRestorer,
 ) *MetricStorageService {
 	return &MetricStorageService{
-		metricService:  metricService,
+		metricServices: metricServices,
 		metricDumper:   metricDumper,
 		metricRestorer: metricRestorer,
 		shutdownCh:     make(chan os.Signal, 1),
@@ -72,37 +80,42 @@ func (s *MetricStorageService) autoStore(storeInterval uint64) {
 	ticker := time.NewTicker(time.Duration(storeInterval) * time.Second)
 	defer ticker.Stop()
 
+	// Остановка прерывает повторы текущего сохранения, чтобы не ждать их при завершении.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	try := repeater.NewRepeater(func(err error) {
 		fmt.Printf("error dumping metrics: %v\n", err)
 	})
-	repeatStrategy := repeater.NewFixedDelaysStrategy(
+	repeatStrategy := repeater.NewExponentialBackoffStrategy(
 		database.NewPostgresErrorClassifier().IsRetriable,
 		time.Second*1,
-		time.Second*3,
 		time.Second*5,
+		3,
 	)
+	store := func() (any, error) {
+		err := StoreState(s.metricServices, s.metricDumper)
+		return nil, err
+	}
 
 	for {
 		select {
 		case <-ticker.C:
-			_, err := try.Repeat(
-				repeatStrategy,
-				func() (any, error) {
-					err := StoreState(s.metricService, s.metricDumper)
-					return nil, err
-				},
-			)
+			_, err := try.RepeatContext(ctx, repeatStrategy, store)
 			if err != nil {
 				fmt.Printf("error dumping metrics after retries: %v\n", err)
 			}
 		case <-s.stopCh:
-			_, err := try.Repeat(
-				repeatStrategy,
-				func() (any, error) {
-					err := StoreState(s.metricService, s.metricDumper)
-					return nil, err
-				},
-			)
+			finalCtx, finalCancel := context.WithTimeout(context.Background(), shutdownStoreTimeout)
+			_, err := try.RepeatContext(finalCtx, repeatStrategy, store)
+			finalCancel()
 			if err != nil {
 				fmt.Printf("error dumping metrics during shutdown after retries: %v\n", err)
 			}
@@ -122,32 +135,48 @@ func (s *MetricStorageService) shutdown() {
 	}
 }
 
-func StoreState(metricService *MetricService, metricDumper MetricDumper) error {
-	counters, err := metricService.GetAllCounters()
-	if err != nil {
-		return fmt.Errorf("failed to get counters: %w", err)
-	}
+// StoreState сохраняет метрики всех арендаторов одним снимком; длительность и ошибка учитываются один раз на снимок.
+func StoreState(metricServices *TenantMetricServices, metricDumper MetricDumper) (err error) {
+	start := time.Now()
+	defer func() { metricServices.Telemetry().ObserveDump(time.Since(start), err) }()
 
-	gauges, err := metricService.GetAllGauges()
-	if err != nil {
-		return fmt.Errorf("failed to get gauges: %w", err)
+	snapshot := TenantMetrics{}
+	for tenantID, metricService := range metricServices.All() {
+		counters, err := metricService.GetAllCounters()
+		if err != nil {
+			return fmt.Errorf("failed to get counters: %w", err)
+		}
+
+		gauges, err := metricService.GetAllGauges()
+		if err != nil {
+			return fmt.Errorf("failed to get gauges: %w", err)
+		}
+
+		snapshot[tenantID] = convertMetrics(counters, gauges)
 	}
 
-	metrics := convertMetrics(counters, gauges)
-	return metricDumper.Dump(metrics)
+	return metricDumper.Dump(snapshot)
 }
 
-func RestoreState(metricService *MetricService, metricRestorer MetricRestorer) error {
-	metrics, err := metricRestorer.Restore()
+// RestoreState раскладывает сохранённые метрики по сервисам их арендаторов.
+func RestoreState(metricServices *TenantMetricServices, metricRestorer MetricRestorer) (err error) {
+	start := time.Now()
+	defer func() { metricServices.Telemetry().ObserveRestore(time.Since(start), err) }()
+
+	snapshot, err := metricRestorer.Restore()
 	if err != nil {
 		return fmt.Errorf("failed to restore metrics: %w", err)
 	}
 
-	fmt.Println("Restored metrics:", metrics)
-
-	for _, metric := range metrics {
-		if err := metricService.Save(metric); err != nil {
-			return fmt.Errorf("failed to save restored metric %s: %w", metric.ID, err)
+	for tenantID, metrics := range snapshot {
+		metricService := metricServices.ForTenant(tenantID)
+		for _, metric := range metrics {
+			if err := metricService.Save(metric); err != nil {
+				if errors.Is(err, ErrCardinalityLimit) {
+					continue
+				}
+				return fmt.Errorf("failed to save restored metric %s: %w", metric.ID, err)
+			}
 		}
 	}
 
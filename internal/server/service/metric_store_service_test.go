@@ -0,0 +1,76 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	serverModel "github.com/GoLessons/go-musthave-metrics/internal/server/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTenantServices() *TenantMetricServices {
+	return NewTenantMetricServices(NewMetricService(
+		storage.NewMemStorage[serverModel.Counter](),
+		storage.NewMemStorage[serverModel.Gauge](),
+	))
+}
+
+func TestStoreState_PersistsAllTenants(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	delta := int64(5)
+	value := 1.5
+
+	saved := newTenantServices()
+	require.NoError(t, saved.Default().Save(*model.NewCounter("requests", &delta)))
+	require.NoError(t, saved.ForTenant("team-a").Save(*model.NewGauge("load", &value)))
+	require.NoError(t, StoreState(saved, NewFileMetricDumper(path)))
+
+	restored := newTenantServices()
+	require.NoError(t, RestoreState(restored, NewFileMetricRestorer(path)))
+
+	counter, err := restored.Default().Read(model.Counter, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, delta, *counter.Delta)
+
+	gauge, err := restored.ForTenant("team-a").Read(model.Gauge, "load")
+	require.NoError(t, err)
+	assert.Equal(t, value, *gauge.Value)
+
+	_, err = restored.Default().Read(model.Gauge, "load")
+	assert.Error(t, err)
+}
+
+func TestRestoreState_LegacyFileBelongsToDefaultTenant(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id":"requests","type":"counter","delta":3}]`), 0644))
+
+	restored := newTenantServices()
+	require.NoError(t, RestoreState(restored, NewFileMetricRestorer(path)))
+
+	counter, err := restored.Default().Read(model.Counter, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), *counter.Delta)
+	assert.Len(t, restored.All(), 1)
+}
+
+func TestTenantMetricServices_ShareTelemetry(t *testing.T) {
+	registry := telemetry.NewRegistry()
+	services := NewTenantMetricServices(NewMetricService(
+		storage.NewMemStorage[serverModel.Counter](),
+		storage.NewMemStorage[serverModel.Gauge](),
+	).WithTelemetry(registry))
+
+	assert.Same(t, registry, services.ForTenant("team-a").telemetry)
+
+	require.NoError(t, StoreState(services, NewFileMetricDumper(filepath.Join(t.TempDir(), "metrics.json"))))
+
+	var out strings.Builder
+	require.NoError(t, registry.WriteText(&out))
+	assert.Contains(t, out.String(), telemetry.DumpDuration+"_count 1\n", "one dump of all tenants is observed once")
+}
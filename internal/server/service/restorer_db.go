@@ -21,11 +21,11 @@ func NewDBMetricRestorer(db *sql.DB) *dbMetricRestorer {
 	}
 }
 
-func (r *dbMetricRestorer) Restore() ([]model.Metrics, error) {
+func (r *dbMetricRestorer) Restore() (TenantMetrics, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	query := squirrel.Select("name", "type", "delta", "value").
+	query := squirrel.Select("tenant", "name", "type", "delta", "value").
 		From("metrics.metrics")
 
 	rows, err := query.RunWith(r.db).QueryContext(context.TODO())
@@ -48,14 +48,15 @@ func (r *dbMetricRestorer) Restore() ([]model.Metrics, error) {
 	return metrics, nil
 }
 
-func (r *dbMetricRestorer) hydrate(rows *sql.Rows) ([]model.Metrics, error) {
-	var metrics []model.Metrics
+func (r *dbMetricRestorer) hydrate(rows *sql.Rows) (TenantMetrics, error) {
+	metrics := TenantMetrics{}
 	for rows.Next() {
+		var tenantID string
 		var metric model.Metrics
 		var delta sql.NullInt64
 		var value sql.NullFloat64
 
-		if err := rows.Scan(&metric.ID, &metric.MType, &delta, &value); err != nil {
+		if err := rows.Scan(&tenantID, &metric.ID, &metric.MType, &delta, &value); err != nil {
 			return nil, fmt.Errorf("failed to scan metric row: %w", err)
 		}
 
@@ -72,7 +73,7 @@ func (r *dbMetricRestorer) hydrate(rows *sql.Rows) ([]model.Metrics, error) {
 			}
 		}
 
-		metrics = append(metrics, metric)
+		metrics[tenantID] = append(metrics[tenantID], metric)
 	}
 	return metrics, nil
 }
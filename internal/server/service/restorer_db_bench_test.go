@@ -15,7 +15,7 @@ func BenchmarkDBMetricRestorer_Restore(b *testing.B) {
 	}
 
 	seed := makeBenchMetrics(2000)
-	if err := NewDBMetricDumper(db, zap.NewNop()).Dump(seed); err != nil {
+	if err := NewDBMetricDumper(db, zap.NewNop()).Dump(TenantMetrics{"": seed}); err != nil {
 		b.Fatalf("failed to seed metrics: %v", err)
 	}
 
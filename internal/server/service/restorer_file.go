@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"sync"
@@ -20,22 +21,29 @@ func NewFileMetricRestorer(filePath string) *fileMetricRestorer {
 	}
 }
 
-func (r *fileMetricRestorer) Restore() (metrics []model.Metrics, err error) {
+// Restore читает снимок арендаторов. Файл прежнего формата — массив метрик — относится к арендатору по умолчанию.
+func (r *fileMetricRestorer) Restore() (TenantMetrics, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if _, err := os.Stat(r.filePath); os.IsNotExist(err) {
-		return metrics, nil
+	data, err := os.ReadFile(r.filePath)
+	if os.IsNotExist(err) {
+		return TenantMetrics{}, nil
 	}
-
-	file, err := os.OpenFile(r.filePath, os.O_RDONLY, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
-	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&metrics); err != nil {
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		var metrics []model.Metrics
+		if err := json.Unmarshal(data, &metrics); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metrics: %w", err)
+		}
+		return TenantMetrics{"": metrics}, nil
+	}
+
+	metrics := TenantMetrics{}
+	if err := json.Unmarshal(data, &metrics); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal metrics: %w", err)
 	}
 
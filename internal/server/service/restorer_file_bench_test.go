@@ -22,7 +22,7 @@ func BenchmarkFileMetricRestorer_Restore(b *testing.B) {
 			metrics[i] = *model.NewGauge("g_bench_"+strconv.Itoa(i), &value)
 		}
 	}
-	_ = d.Dump(metrics)
+	_ = d.Dump(TenantMetrics{"": metrics})
 
 	r := NewFileMetricRestorer(tmp)
 	b.ReportAllocs()
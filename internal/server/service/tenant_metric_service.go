@@ -0,0 +1,78 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
+	serverModel "github.com/GoLessons/go-musthave-metrics/internal/server/model"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
+)
+
+// TenantMetricServices хранит изолированные сервисы метрик для каждого арендатора.
+// Пустой идентификатор соответствует арендатору по умолчанию.
+type TenantMetricServices struct {
+	mu       sync.RWMutex
+	def      *MetricService
+	services map[string]*MetricService
+}
+
+func NewTenantMetricServices(defaultService *MetricService) *TenantMetricServices {
+	return &TenantMetricServices{
+		def:      defaultService,
+		services: make(map[string]*MetricService),
+	}
+}
+
+func (t *TenantMetricServices) ForTenant(tenantID string) *MetricService {
+	if tenantID == "" {
+		return t.def
+	}
+
+	t.mu.RLock()
+	svc, ok := t.services[tenantID]
+	t.mu.RUnlock()
+	if ok {
+		return svc
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if svc, ok := t.services[tenantID]; ok {
+		return svc
+	}
+
+	svc = NewMetricService(
+		storage.NewMemStorage[serverModel.Counter](),
+		storage.NewMemStorage[serverModel.Gauge](),
+	).WithTelemetry(t.def.telemetry)
+	if t.def.guard != nil {
+		svc.WithCardinalityGuard(t.def.guard.ForTenant())
+	}
+	t.services[tenantID] = svc
+
+	return svc
+}
+
+func (t *TenantMetricServices) Default() *MetricService {
+	return t.def
+}
+
+// Telemetry возвращает реестр, общий для сервисов всех арендаторов.
+func (t *TenantMetricServices) Telemetry() *telemetry.Registry {
+	return t.def.telemetry
+}
+
+// All возвращает снимок сервисов всех арендаторов, включая арендатора по умолчанию.
+func (t *TenantMetricServices) All() map[string]*MetricService {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	result := make(map[string]*MetricService, len(t.services)+1)
+	result[""] = t.def
+	for id, svc := range t.services {
+		result[id] = svc
+	}
+
+	return result
+}
@@ -0,0 +1,209 @@
+package telemetry
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// DefaultBuckets — границы гистограмм длительности в секундах, как у клиента Prometheus.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type family struct {
+	kind   string
+	help   string
+	series map[string]*series
+}
+
+type series struct {
+	labels []string
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Registry хранит метрики работы самого сервера и отдаёт их в текстовом формате Prometheus.
+// Метки передаются парами ключ-значение. Методы безопасны для nil: без реестра учёт отключён.
+type Registry struct {
+	mu         sync.Mutex
+	families   map[string]*family
+	collectors []func()
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// OnCollect добавляет функцию, которая обновляет gauge перед каждой выдачей метрик.
+func (r *Registry) OnCollect(collect func()) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, collect)
+}
+
+func (r *Registry) Add(name string, delta float64, labels ...string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seriesOf(kindCounter, name, labels).value += delta
+}
+
+func (r *Registry) Set(name string, value float64, labels ...string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seriesOf(kindGauge, name, labels).value = value
+}
+
+func (r *Registry) Observe(name string, value float64, labels ...string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.seriesOf(kindHistogram, name, labels)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(DefaultBuckets))
+	}
+	for i, bound := range DefaultBuckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func (r *Registry) seriesOf(kind, name string, labels []string) *series {
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{kind: kind, help: help[name], series: make(map[string]*series)}
+		r.families[name] = f
+	}
+
+	key := strings.Join(labels, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: labels}
+		f.series[key] = s
+	}
+
+	return s
+}
+
+// WriteText пишет все метрики в текстовом формате экспозиции Prometheus.
+func (r *Registry) WriteText(w io.Writer) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	collectors := append([]func(){}, r.collectors...)
+	r.mu.Unlock()
+	for _, collect := range collectors {
+		collect()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, name := range sortedKeys(r.families) {
+		f := r.families[name]
+		if f.help != "" {
+			bw.WriteString("# HELP " + name + " " + f.help + "\n")
+		}
+		bw.WriteString("# TYPE " + name + " " + f.kind + "\n")
+
+		for _, key := range sortedKeys(f.series) {
+			s := f.series[key]
+			if f.kind != kindHistogram {
+				writeSample(bw, name, s.labels, s.value)
+				continue
+			}
+			for i, bound := range DefaultBuckets {
+				writeSample(bw, name+"_bucket", append(s.labels[:len(s.labels):len(s.labels)], "le", formatFloat(bound)), float64(s.counts[i]))
+			}
+			writeSample(bw, name+"_bucket", append(s.labels[:len(s.labels):len(s.labels)], "le", "+Inf"), float64(s.count))
+			writeSample(bw, name+"_sum", s.labels, s.sum)
+			writeSample(bw, name+"_count", s.labels, float64(s.count))
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Handler отдаёт метрики по HTTP для сборщика Prometheus.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(w); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	})
+}
+
+func writeSample(w *bufio.Writer, name string, labels []string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(labels[i] + `="` + escapeLabel(labels[i+1]) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, r.WriteText(&b))
+	return b.String()
+}
+
+func TestRegistry_CountersAndGauges(t *testing.T) {
+	r := NewRegistry()
+	r.DecryptFailed(TransportHTTP)
+	r.DecryptFailed(TransportHTTP)
+	r.SignatureFailed(TransportGRPC, CheckAgent)
+	r.StorageSize("", 3, 5)
+
+	out := render(t, r)
+
+	assert.Contains(t, out, "# TYPE metrics_server_decrypt_failures_total counter\n")
+	assert.Contains(t, out, `metrics_server_decrypt_failures_total{transport="http"} 2`+"\n")
+	assert.Contains(t, out, `metrics_server_signature_failures_total{transport="grpc",check="agent"} 1`+"\n")
+	assert.Contains(t, out, "# TYPE metrics_server_storage_metrics gauge\n")
+	assert.Contains(t, out, `metrics_server_storage_metrics{tenant="default",type="counter"} 3`+"\n")
+	assert.Contains(t, out, `metrics_server_storage_metrics{tenant="default",type="gauge"} 5`+"\n")
+}
+
+func TestRegistry_Histogram(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveHTTP("/update/", http.MethodPost, http.StatusOK, 20*time.Millisecond)
+	r.ObserveHTTP("/update/", http.MethodPost, http.StatusOK, 2*time.Second)
+
+	out := render(t, r)
+
+	assert.Contains(t, out, `metrics_server_http_requests_total{route="/update/",method="POST",code="200"} 2`+"\n")
+	assert.Contains(t, out, "# TYPE metrics_server_http_request_duration_seconds histogram\n")
+	assert.Contains(t, out, `metrics_server_http_request_duration_seconds_bucket{route="/update/",method="POST",le="0.01"} 0`+"\n")
+	assert.Contains(t, out, `metrics_server_http_request_duration_seconds_bucket{route="/update/",method="POST",le="0.025"} 1`+"\n")
+	assert.Contains(t, out, `metrics_server_http_request_duration_seconds_bucket{route="/update/",method="POST",le="+Inf"} 2`+"\n")
+	assert.Contains(t, out, `metrics_server_http_request_duration_seconds_sum{route="/update/",method="POST"} 2.02`+"\n")
+	assert.Contains(t, out, `metrics_server_http_request_duration_seconds_count{route="/update/",method="POST"} 2`+"\n")
+}
+
+func TestRegistry_DumpErrorsAndCollectors(t *testing.T) {
+	r := NewRegistry()
+	size := 1
+	r.OnCollect(func() { r.StorageSize("acme", size, 0) })
+	r.ObserveDump(time.Millisecond, nil)
+	r.ObserveDump(time.Millisecond, errors.New("disk full"))
+	r.ObserveRestore(time.Millisecond, nil)
+
+	size = 7
+	out := render(t, r)
+
+	assert.Contains(t, out, "metrics_server_dump_errors_total 1\n")
+	assert.Contains(t, out, "metrics_server_dump_duration_seconds_count 2\n")
+	assert.Contains(t, out, "metrics_server_restore_duration_seconds_count 1\n")
+	assert.NotContains(t, out, "metrics_server_restore_errors_total")
+	assert.Contains(t, out, `metrics_server_storage_metrics{tenant="acme",type="counter"} 7`+"\n")
+}
+
+func TestRegistry_EscapesLabels(t *testing.T) {
+	r := NewRegistry()
+	r.AuditFailed("a\"b\\c\n")
+
+	assert.Contains(t, render(t, r), `metrics_server_audit_failures_total{auditor="a\"b\\c\n"} 1`+"\n")
+}
+
+func TestRegistry_NilIsNoop(t *testing.T) {
+	var r *Registry
+	r.ObserveHTTP("/", http.MethodGet, http.StatusOK, time.Millisecond)
+	r.AuditFailed("file")
+	r.OnCollect(func() {})
+
+	assert.Empty(t, render(t, r))
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveGRPC("/metrics.Metrics/UpdateMetrics", "OK", time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	assert.Contains(t, rec.Body.String(), `metrics_server_grpc_requests_total{method="/metrics.Metrics/UpdateMetrics",code="OK"} 1`)
+}
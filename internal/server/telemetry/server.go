@@ -0,0 +1,131 @@
+package telemetry
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Имена метрик работы сервера.
+const (
+	HTTPRequests        = "metrics_server_http_requests_total"
+	HTTPRequestDuration = "metrics_server_http_request_duration_seconds"
+	GRPCRequests        = "metrics_server_grpc_requests_total"
+	GRPCRequestDuration = "metrics_server_grpc_request_duration_seconds"
+	DecryptFailures     = "metrics_server_decrypt_failures_total"
+	SignatureFailures   = "metrics_server_signature_failures_total"
+	StorageMetrics      = "metrics_server_storage_metrics"
+	DumpDuration        = "metrics_server_dump_duration_seconds"
+	DumpErrors          = "metrics_server_dump_errors_total"
+	RestoreDuration     = "metrics_server_restore_duration_seconds"
+	RestoreErrors       = "metrics_server_restore_errors_total"
+	AuditFailures       = "metrics_server_audit_failures_total"
+	AuditSpills         = "metrics_server_audit_spilled_total"
+)
+
+// Транспорты, по которым считаются ошибки расшифровки и подписи.
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
+// Причины, по которым событие аудита ушло в запасной файл.
+const (
+	SpillQueueFull   = "queue_full"
+	SpillUndelivered = "undelivered"
+	SpillClosed      = "closed"
+)
+
+// Виды отклонённых подписей.
+const (
+	CheckHMAC   = "hmac"
+	CheckReplay = "replay"
+	CheckAgent  = "agent"
+)
+
+var standardMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+var help = map[string]string{
+	HTTPRequests:        "HTTP requests by route pattern, method and status code.",
+	HTTPRequestDuration: "HTTP request latency by route pattern and method.",
+	GRPCRequests:        "gRPC requests by method and status code.",
+	GRPCRequestDuration: "gRPC request latency by method.",
+	DecryptFailures:     "Requests rejected because the body could not be decrypted.",
+	SignatureFailures:   "Requests rejected by signature or replay checks.",
+	StorageMetrics:      "Metrics held in memory by tenant and type.",
+	DumpDuration:        "Duration of state dump attempts.",
+	DumpErrors:          "Failed state dump attempts.",
+	RestoreDuration:     "Duration of state restore attempts.",
+	RestoreErrors:       "Failed state restore attempts.",
+	AuditFailures:       "Audit events an auditor failed to deliver.",
+	AuditSpills:         "Audit events written to the remote auditor fallback file, by reason.",
+}
+
+// ObserveHTTP учитывает HTTP-запрос; route — шаблон маршрута chi, а не путь, чтобы не плодить серии.
+// Метод тоже приходит от клиента, поэтому нестандартные методы считаются как "other".
+func (r *Registry) ObserveHTTP(route, method string, code int, took time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	if !standardMethods[method] {
+		method = "other"
+	}
+	r.Add(HTTPRequests, 1, "route", route, "method", method, "code", strconv.Itoa(code))
+	r.Observe(HTTPRequestDuration, took.Seconds(), "route", route, "method", method)
+}
+
+func (r *Registry) ObserveGRPC(method, code string, took time.Duration) {
+	r.Add(GRPCRequests, 1, "method", method, "code", code)
+	r.Observe(GRPCRequestDuration, took.Seconds(), "method", method)
+}
+
+func (r *Registry) DecryptFailed(transport string) {
+	r.Add(DecryptFailures, 1, "transport", transport)
+}
+
+func (r *Registry) SignatureFailed(transport, check string) {
+	r.Add(SignatureFailures, 1, "transport", transport, "check", check)
+}
+
+// StorageSize обновляет число хранимых метрик арендатора; пустой идентификатор — арендатор по умолчанию.
+func (r *Registry) StorageSize(tenantID string, counters, gauges int) {
+	if tenantID == "" {
+		tenantID = "default"
+	}
+	r.Set(StorageMetrics, float64(counters), "tenant", tenantID, "type", "counter")
+	r.Set(StorageMetrics, float64(gauges), "tenant", tenantID, "type", "gauge")
+}
+
+// ObserveDump учитывает одну попытку сохранения состояния.
+func (r *Registry) ObserveDump(took time.Duration, err error) {
+	r.Observe(DumpDuration, took.Seconds())
+	if err != nil {
+		r.Add(DumpErrors, 1)
+	}
+}
+
+// ObserveRestore учитывает одну попытку восстановления состояния.
+func (r *Registry) ObserveRestore(took time.Duration, err error) {
+	r.Observe(RestoreDuration, took.Seconds())
+	if err != nil {
+		r.Add(RestoreErrors, 1)
+	}
+}
+
+func (r *Registry) AuditFailed(auditor string) {
+	r.Add(AuditFailures, 1, "auditor", auditor)
+}
+
+func (r *Registry) AuditSpilled(reason string) {
+	r.Add(AuditSpills, 1, "reason", reason)
+}
@@ -0,0 +1,116 @@
+package tenant
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/server"
+	fileconfig "github.com/GoLessons/go-musthave-metrics/pkg/file-config"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type Tenant struct {
+	ID     string `json:"id"`
+	APIKey string `json:"api_key"`
+	Key    string `json:"key"`
+
+	signer *signature.Signer
+}
+
+// Signer возвращает подписчика с секретом арендатора или nil, если секрет не задан.
+func (t *Tenant) Signer() *signature.Signer {
+	return t.signer
+}
+
+type Registry struct {
+	byAPIKey map[[sha256.Size]byte]*Tenant
+	byID     map[string]*Tenant
+}
+
+func NewRegistry(tenants ...Tenant) (*Registry, error) {
+	r := &Registry{
+		byAPIKey: make(map[[sha256.Size]byte]*Tenant, len(tenants)),
+		byID:     make(map[string]*Tenant, len(tenants)),
+	}
+
+	for _, t := range tenants {
+		if t.ID == "" {
+			return nil, fmt.Errorf("tenant id is empty")
+		}
+		if t.APIKey == "" {
+			return nil, fmt.Errorf("tenant %s: api key is empty", t.ID)
+		}
+		if _, exists := r.byID[t.ID]; exists {
+			return nil, fmt.Errorf("tenant %s: duplicate id", t.ID)
+		}
+
+		digest := sha256.Sum256([]byte(t.APIKey))
+		if _, exists := r.byAPIKey[digest]; exists {
+			return nil, fmt.Errorf("tenant %s: duplicate api key", t.ID)
+		}
+
+		tenant := t
+		if tenant.Key != "" {
+			tenant.signer = signature.NewSign(tenant.Key)
+		}
+		r.byAPIKey[digest] = &tenant
+		r.byID[tenant.ID] = &tenant
+	}
+
+	return r, nil
+}
+
+func LoadRegistry(path string) (*Registry, error) {
+	tenants, err := fileconfig.Load[[]Tenant](path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenants: %w", err)
+	}
+
+	return NewRegistry(tenants...)
+}
+
+// ByAPIKey ищет арендатора по ключу; ключи хранятся в виде хэшей,
+// поэтому время поиска не зависит от совпадающего префикса.
+func (r *Registry) ByAPIKey(apiKey string) (*Tenant, bool) {
+	if apiKey == "" {
+		return nil, false
+	}
+
+	t, ok := r.byAPIKey[sha256.Sum256([]byte(apiKey))]
+	return t, ok
+}
+
+func (r *Registry) ByID(id string) (*Tenant, bool) {
+	t, ok := r.byID[id]
+	return t, ok
+}
+
+func (r *Registry) IDs() []string {
+	ids := make([]string, 0, len(r.byID))
+	for id := range r.byID {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, server.Tenant, t)
+}
+
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(server.Tenant).(*Tenant)
+	return t, ok && t != nil
+}
+
+// IDFromContext возвращает идентификатор арендатора или пустую строку для арендатора по умолчанию.
+func IDFromContext(ctx context.Context) string {
+	if t, ok := FromContext(ctx); ok {
+		return t.ID
+	}
+
+	return ""
+}
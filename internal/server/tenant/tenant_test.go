@@ -0,0 +1,77 @@
+package tenant
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRegistry_LookupByAPIKey(t *testing.T) {
+	registry, err := NewRegistry(
+		Tenant{ID: "team-a", APIKey: "key-a", Key: "secret-a"},
+		Tenant{ID: "team-b", APIKey: "key-b"},
+	)
+	require.NoError(t, err)
+
+	a, ok := registry.ByAPIKey("key-a")
+	require.True(t, ok)
+	assert.Equal(t, "team-a", a.ID)
+	assert.NotNil(t, a.Signer())
+
+	b, ok := registry.ByAPIKey("key-b")
+	require.True(t, ok)
+	assert.Equal(t, "team-b", b.ID)
+	assert.Nil(t, b.Signer())
+
+	_, ok = registry.ByAPIKey("unknown")
+	assert.False(t, ok)
+	_, ok = registry.ByAPIKey("")
+	assert.False(t, ok)
+}
+
+func TestNewRegistry_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenants []Tenant
+	}{
+		{name: "empty id", tenants: []Tenant{{APIKey: "k"}}},
+		{name: "empty api key", tenants: []Tenant{{ID: "a"}}},
+		{name: "duplicate id", tenants: []Tenant{{ID: "a", APIKey: "k1"}, {ID: "a", APIKey: "k2"}}},
+		{name: "duplicate api key", tenants: []Tenant{{ID: "a", APIKey: "k"}, {ID: "b", APIKey: "k"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegistry(tt.tenants...)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestLoadRegistry_FromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	data := `[{"id":"team-a","api_key":"key-a","key":"secret-a"}]`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	registry, err := LoadRegistry(path)
+	require.NoError(t, err)
+
+	got, ok := registry.ByID("team-a")
+	require.True(t, ok)
+	assert.Equal(t, "key-a", got.APIKey)
+	assert.Equal(t, []string{"team-a"}, registry.IDs())
+}
+
+func TestContext_RoundTrip(t *testing.T) {
+	assert.Equal(t, "", IDFromContext(context.Background()))
+
+	ctx := NewContext(context.Background(), &Tenant{ID: "team-a"})
+	got, ok := FromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "team-a", got.ID)
+	assert.Equal(t, "team-a", IDFromContext(ctx))
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAgentKey(t *testing.T, keysDir string, name string) *signature.KeySigner {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	if keysDir != "" {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(keysDir, name+".pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	}
+
+	signer, err := signature.NewKeySigner(priv)
+	require.NoError(t, err)
+	return signer
+}
+
+func agentSignedHeaders(t *testing.T, signer *signature.KeySigner, payload []byte) map[string]string {
+	t.Helper()
+	timestamp, nonce, err := signature.NewNonce(time.Now())
+	require.NoError(t, err)
+	sig, err := signer.Sign(signature.SignedPayload(timestamp, nonce, payload))
+	require.NoError(t, err)
+
+	return map[string]string{
+		"Content-Type":                 "application/json",
+		signature.TimestampHeader:      timestamp,
+		signature.NonceHeader:          nonce,
+		signature.AgentKeyIDHeader:     signer.KeyID(),
+		signature.AgentSignatureHeader: sig,
+	}
+}
+
+func TestAgentSignature(t *testing.T) {
+	keysDir := t.TempDir()
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+	enrolled := newAgentKey(t, keysDir, "agent-1")
+	stranger := newAgentKey(t, "", "stranger")
+
+	I, err := NewTester(t, &map[string]any{
+		"AgentKeysDir": keysDir,
+		"AuditFile":    auditFile,
+	})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	var delta int64 = 3
+	body, err := json.Marshal(model.Metrics{ID: "signed_counter", MType: model.Counter, Delta: &delta})
+	require.NoError(t, err)
+
+	send := func(endpoint string, payload []byte, headers map[string]string) int {
+		var requestBody any
+		if payload != nil {
+			requestBody = payload
+		}
+		resp, err := I.DoRequest(http.MethodPost, endpoint, requestBody, headers)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, send("/update", body, agentSignedHeaders(t, enrolled, body)))
+	assert.Equal(t, http.StatusUnauthorized, send("/update", body, agentSignedHeaders(t, stranger, body)))
+	assert.Equal(t, http.StatusUnauthorized, send("/update", body, map[string]string{"Content-Type": "application/json"}))
+
+	path := "/update/gauge/signed_gauge/1.5"
+	assert.Equal(t, http.StatusOK, send(path, nil, agentSignedHeaders(t, enrolled, []byte(path))))
+	assert.Equal(t, http.StatusUnauthorized, send("/update/gauge/signed_gauge/9.5", nil, agentSignedHeaders(t, enrolled, []byte(path))), "signature is bound to the path")
+
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(auditFile)
+		return err == nil && strings.Contains(string(data), `"agent":"agent-1"`)
+	}, time.Second, 10*time.Millisecond)
+}
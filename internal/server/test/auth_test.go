@@ -0,0 +1,105 @@
+package test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jwtIssuer struct {
+	t   *testing.T
+	key *rsa.PrivateKey
+}
+
+func newJWTIssuer(t *testing.T) (*jwtIssuer, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks, err := json.Marshal(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	return &jwtIssuer{t: t, key: key}, path
+}
+
+func (i *jwtIssuer) token(scope string) string {
+	i.t.Helper()
+	segment := func(v any) string {
+		raw, err := json.Marshal(v)
+		require.NoError(i.t, err)
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+
+	input := segment(map[string]string{"alg": "RS256", "kid": "test"}) + "." +
+		segment(map[string]any{"sub": "test", "exp": time.Now().Add(time.Hour).Unix(), "scope": scope})
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	require.NoError(i.t, err)
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestBearerAuth_Scopes(t *testing.T) {
+	issuer, jwksPath := newJWTIssuer(t)
+	I, err := NewTester(t, &map[string]any{
+		"Key":                 "",
+		"AuthConfig.JWKSFile": jwksPath,
+	})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "Write without token → 401", method: http.MethodPost, path: "/update/gauge/g/1", expectedStatus: http.StatusUnauthorized},
+		{name: "Write with invalid token → 401", method: http.MethodPost, path: "/update/gauge/g/1", token: "a.b.c", expectedStatus: http.StatusUnauthorized},
+		{name: "Write with read scope → 403", method: http.MethodPost, path: "/update/gauge/g/1", token: issuer.token("metrics:read"), expectedStatus: http.StatusForbidden},
+		{name: "Write with write scope → 200", method: http.MethodPost, path: "/update/gauge/g/1", token: issuer.token("metrics:write"), expectedStatus: http.StatusOK},
+		{name: "Read without token → 401", method: http.MethodGet, path: "/value/gauge/g", expectedStatus: http.StatusUnauthorized},
+		{name: "Read with write scope → 403", method: http.MethodGet, path: "/value/gauge/g", token: issuer.token("metrics:write"), expectedStatus: http.StatusForbidden},
+		{name: "Read with read scope → 200", method: http.MethodGet, path: "/value/gauge/g", token: issuer.token("metrics:read"), expectedStatus: http.StatusOK},
+		{name: "Admin with read scope → 403", method: http.MethodGet, path: "/admin/cardinality", token: issuer.token("metrics:read"), expectedStatus: http.StatusForbidden},
+		{name: "Admin with admin scope → 200", method: http.MethodGet, path: "/admin/cardinality", token: issuer.token("metrics:admin"), expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{"Content-Type": "text/plain"}
+			if tt.token != "" {
+				headers["Authorization"] = "Bearer " + tt.token
+			}
+
+			resp, err := I.DoRequest(tt.method, tt.path, nil, headers)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
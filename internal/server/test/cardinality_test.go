@@ -0,0 +1,68 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinality_RejectAndReport(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{
+		"Key":                  "",
+		"Cardinality.MaxNames": uint64(1),
+		"AuthConfig.AdminKey":  "admin-secret",
+	})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	resp, err := I.Post("/update/gauge/first/1", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = I.Post("/update/gauge/second/1", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp, err = I.DoRequest(http.MethodGet, "/admin/cardinality", nil, map[string]string{})
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = I.DoRequest(http.MethodGet, "/admin/cardinality", nil, map[string]string{"X-Admin-Key": "admin-secret"})
+	require.NoError(t, err)
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var reports []struct {
+		Series   int `json:"series"`
+		Prefixes []struct {
+			Key       string `json:"key"`
+			Overflows int    `json:"overflows"`
+		} `json:"prefixes"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &reports))
+	require.Len(t, reports, 1)
+	assert.Equal(t, 1, reports[0].Series)
+	require.NotEmpty(t, reports[0].Prefixes)
+	assert.Equal(t, "second", reports[0].Prefixes[0].Key)
+	assert.Equal(t, 1, reports[0].Prefixes[0].Overflows)
+}
+
+func TestCardinality_NotRegisteredWithoutAdminAuth(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{"Key": ""})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	resp, err := I.DoRequest(http.MethodGet, "/admin/cardinality", nil, map[string]string{})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
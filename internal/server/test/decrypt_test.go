@@ -295,3 +295,36 @@ func TestUpdatesEncrypted_NoGzip_Success(t *testing.T) {
 		t.Fatalf("unexpected counter value: %s", string(counterVal))
 	}
 }
+
+func TestKeysReload_RequiresAdmin(t *testing.T) {
+	for name, tc := range map[string]struct {
+		adminKey string
+		headers  map[string]string
+		want     int
+	}{
+		"not registered without admin auth": {want: http.StatusNotFound},
+		"missing admin key":                 {adminKey: "admin-secret", headers: map[string]string{}, want: http.StatusUnauthorized},
+		"valid admin key":                   {adminKey: "admin-secret", headers: map[string]string{"X-Admin-Key": "admin-secret"}, want: http.StatusOK},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tester, err := NewTester(t, &map[string]any{
+				"Key":                 "",
+				"CryptoKey":           privateKeyPath,
+				"AuthConfig.AdminKey": tc.adminKey,
+			})
+			if err != nil {
+				t.Fatalf("tester init: %v", err)
+			}
+			defer tester.Shutdown()
+
+			resp, err := tester.DoRequest(http.MethodPost, "/admin/keys/reload", nil, tc.headers)
+			if err != nil {
+				t.Fatalf("post reload: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.want {
+				t.Fatalf("unexpected status: %d, want %d", resp.StatusCode, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/server/audit"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (tester *tester) readiness(t *testing.T, path string) (int, health.Report) {
+	t.Helper()
+	resp, err := tester.DoRequest(http.MethodGet, path, nil, map[string]string{})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(raw, &report), string(raw))
+	return resp.StatusCode, report
+}
+
+func TestHealth_ReadinessFollowsStartup(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{"Key": "", "TrustedSubnet": "10.0.0.0/8"})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	restore := I.health.State("restore")
+	dumper := I.health.State("dumper")
+	dumper.Set(nil)
+
+	code, report := I.readiness(t, "/healthz")
+	assert.Equal(t, http.StatusOK, code, "liveness does not depend on checks nor on trusted subnet")
+	assert.Equal(t, health.StatusOK, report.Status)
+
+	code, report = I.readiness(t, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusPending, report.Status)
+	assert.Equal(t, health.StatusPending, report.Checks["restore"].Status)
+	assert.Equal(t, health.StatusOK, report.Checks["dumper"].Status)
+
+	restore.Set(nil)
+	code, report = I.readiness(t, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, report.Status)
+
+	dumper.Set(assert.AnError)
+	code, report = I.readiness(t, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, assert.AnError.Error(), report.Checks["dumper"].Error)
+}
+
+func TestHealth_AuditSinkUnreachable(t *testing.T) {
+	subject := audit.NewAuditSubject(audit.NewFileAuditor(filepath.Join(t.TempDir(), "missing", "audit.log")))
+	checks := health.New()
+	checks.Register("audit", subject.Ping)
+
+	report := checks.Check(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, health.StatusFail, report.Checks["audit"].Status)
+	assert.NotEmpty(t, report.Checks["audit"].Error)
+
+	subject = audit.NewAuditSubject(audit.NewFileAuditor(filepath.Join(t.TempDir(), "audit.log")))
+	checks.Register("audit", subject.Ping)
+	assert.True(t, checks.Check(context.Background()).Ready())
+}
@@ -0,0 +1,15 @@
+package test
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// TestMain убирает из os.Args флаги go test: конфигурация сервера разбирает командную строку
+// и отвергает неизвестные флаги.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Args = os.Args[:1]
+	os.Exit(m.Run())
+}
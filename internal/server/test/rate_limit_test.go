@@ -0,0 +1,41 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit_BatchAndSeriesQuotas(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{
+		"Key":                      "",
+		"LimitConfig.MaxBatchSize": uint64(2),
+		"LimitConfig.MaxSeries":    uint64(2),
+	})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	value := 1.0
+	send := func(metrics ...model.Metrics) *http.Response {
+		body, err := json.Marshal(metrics)
+		require.NoError(t, err)
+		resp, err := I.DoRequest(http.MethodPost, "/updates", body, map[string]string{"Content-Type": "application/json"})
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := send(*model.NewGauge("a", &value), *model.NewGauge("b", &value), *model.NewGauge("c", &value))
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+	resp = send(*model.NewGauge("a", &value), *model.NewGauge("b", &value))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = send(*model.NewGauge("c", &value))
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
 	"github.com/GoLessons/go-musthave-metrics/internal/model"
@@ -31,21 +32,7 @@ func TestSignatureVerification(t *testing.T) {
 
 	t.Log("!Request: ", string(body))
 
-	signer := signature.NewSign("test-secret-key")
-	correctHash, err := signer.Hash(body)
-	require.NoError(t, err)
-
-	t.Log("!CorrectHash: ", correctHash)
-
-	resp, err := I.DoRequest(
-		http.MethodPost,
-		"/update",
-		body,
-		map[string]string{
-			"Content-Type": "application/json",
-			"HashSHA256":   correctHash,
-		},
-	)
+	resp, err := I.DoRequest(http.MethodPost, "/update", body, signedHeaders(t, "", "test-secret-key", body))
 	require.NoError(t, err)
 	require.NotNil(t, resp)
 	defer resp.Body.Close()
@@ -67,6 +54,11 @@ func TestSignatureVerification(t *testing.T) {
 	defer resp.Body.Close()
 
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = I.DoRequest(http.MethodPost, "/update", body, map[string]string{"Content-Type": "application/json"})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "unsigned request must be rejected when a key is set")
 }
 
 func TestSignatureInResponse(t *testing.T) {
@@ -87,18 +79,7 @@ func TestSignatureInResponse(t *testing.T) {
 	require.NoError(t, err)
 
 	signer := signature.NewSign("test-secret-key")
-	hash, err := signer.Hash(body)
-	require.NoError(t, err)
-
-	resp, err := I.DoRequest(
-		http.MethodPost,
-		"/update",
-		body,
-		map[string]string{
-			"Content-Type": "application/json",
-			"HashSHA256":   hash,
-		},
-	)
+	resp, err := I.DoRequest(http.MethodPost, "/update", body, signedHeaders(t, "", "test-secret-key", body))
 	require.NoError(t, err)
 	require.NotNil(t, resp)
 	defer resp.Body.Close()
@@ -116,3 +97,76 @@ func TestSignatureInResponse(t *testing.T) {
 
 	assert.Equal(t, calculatedHash, respHash)
 }
+
+func TestSignatureReplayProtection(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{
+		"Key": "test-secret-key",
+	})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	var counterDelta int64 = 5
+	body, err := json.Marshal(model.Metrics{ID: "replayed_counter", MType: model.Counter, Delta: &counterDelta})
+	require.NoError(t, err)
+
+	signer := signature.NewSign("test-secret-key")
+	send := func(timestamp string, nonce string, payload []byte) int {
+		hash, err := signer.Hash(payload)
+		require.NoError(t, err)
+		headers := map[string]string{
+			"Content-Type": "application/json",
+			"HashSHA256":   hash,
+		}
+		if timestamp != "" {
+			headers[signature.TimestampHeader] = timestamp
+			headers[signature.NonceHeader] = nonce
+		}
+		resp, err := I.DoRequest(http.MethodPost, "/update", body, headers)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	timestamp, nonce, err := signature.NewNonce(time.Now())
+	require.NoError(t, err)
+	signed := signature.SignedPayload(timestamp, nonce, body)
+
+	assert.Equal(t, http.StatusOK, send(timestamp, nonce, signed))
+	assert.Equal(t, http.StatusBadRequest, send(timestamp, nonce, signed), "replayed request must be rejected")
+
+	staleTimestamp, staleNonce, err := signature.NewNonce(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, send(staleTimestamp, staleNonce, signature.SignedPayload(staleTimestamp, staleNonce, body)))
+
+	assert.Equal(t, http.StatusBadRequest, send("", "", body), "legacy signature without nonce must be rejected by default")
+
+	query, err := json.Marshal(model.Metrics{ID: "replayed_counter", MType: model.Counter})
+	require.NoError(t, err)
+	resp, err := I.DoRequest(http.MethodPost, "/value", query, signedHeaders(t, "", "test-secret-key", query))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var stored model.Metrics
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stored))
+	require.NotNil(t, stored.Delta)
+	assert.EqualValues(t, 5, *stored.Delta, "counter must be incremented only once")
+}
+
+func TestSignatureLegacyOptOut(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{
+		"Key":                       "test-secret-key",
+		"ReplayConfig.RequireNonce": false,
+	})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	var counterDelta int64 = 1
+	body, err := json.Marshal(model.Metrics{ID: "legacy_counter", MType: model.Counter, Delta: &counterDelta})
+	require.NoError(t, err)
+	hash, err := signature.NewSign("test-secret-key").Hash(body)
+	require.NoError(t, err)
+
+	resp, err := I.DoRequest(http.MethodPost, "/update", body, map[string]string{"Content-Type": "application/json", "HashSHA256": hash})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "legacy signature is accepted only after the explicit opt-out")
+}
@@ -0,0 +1,38 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerMetrics_RequireAdmin(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{"Key": ""})
+	require.NoError(t, err)
+
+	resp, err := I.DoRequest(http.MethodGet, "/metrics", nil, map[string]string{})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "not registered without admin auth")
+	I.Shutdown()
+
+	I, err = NewTester(t, &map[string]any{"Key": "", "AuthConfig.AdminKey": "admin-secret"})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	resp, err = I.DoRequest(http.MethodGet, "/metrics", nil, map[string]string{})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = I.DoRequest(http.MethodGet, "/metrics", nil, map[string]string{"X-Admin-Key": "admin-secret"})
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "metrics_server_http_requests_total")
+}
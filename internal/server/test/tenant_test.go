@@ -0,0 +1,124 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoLessons/go-musthave-metrics/internal/common/signature"
+	"github.com/GoLessons/go-musthave-metrics/internal/model"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTenants(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	data := `[
+		{"id":"team-a","api_key":"key-a","key":"secret-a"},
+		{"id":"team-b","api_key":"key-b","key":"secret-b"}
+	]`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+// signedHeaders подписывает тело с меткой времени и nonce, как это делает агент.
+func signedHeaders(t *testing.T, apiKey string, secret string, body []byte) map[string]string {
+	t.Helper()
+	timestamp, nonce, err := signature.NewNonce(time.Now())
+	require.NoError(t, err)
+	hash, err := signature.NewSign(secret).Hash(signature.SignedPayload(timestamp, nonce, body))
+	require.NoError(t, err)
+	headers := map[string]string{
+		"Content-Type":            "application/json",
+		"HashSHA256":              hash,
+		signature.TimestampHeader: timestamp,
+		signature.NonceHeader:     nonce,
+	}
+	if apiKey != "" {
+		headers["X-API-Key"] = apiKey
+	}
+	return headers
+}
+
+func TestTenants_IsolatedStorage(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{
+		"Key":         "",
+		"TenantsFile": writeTenants(t),
+	})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	delta := int64(5)
+	body, err := json.Marshal(model.NewCounter("shared_counter", &delta))
+	require.NoError(t, err)
+
+	resp, err := I.DoRequest(http.MethodPost, "/update", body, signedHeaders(t, "key-a", "secret-a", body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	query, err := json.Marshal(model.Metrics{ID: "shared_counter", MType: model.Counter})
+	require.NoError(t, err)
+
+	resp, err = I.DoRequest(http.MethodPost, "/value", query, signedHeaders(t, "key-a", "secret-a", query))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got model.Metrics
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.NotNil(t, got.Delta)
+	assert.Equal(t, delta, *got.Delta)
+
+	resp, err = I.DoRequest(http.MethodPost, "/value", query, signedHeaders(t, "key-b", "secret-b", query))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	_, err = I.testStorageCounter.Get("shared_counter")
+	assert.Error(t, err, "tenant metrics must not leak into default storage")
+}
+
+func TestTenants_RequireAPIKey(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{
+		"Key":         "",
+		"TenantsFile": writeTenants(t),
+	})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	resp, err := I.DoRequest(http.MethodPost, "/update/counter/c1/1", nil, map[string]string{"Content-Type": "text/plain"})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = I.DoRequest(http.MethodPost, "/update/counter/c1/1", nil, map[string]string{"Content-Type": "text/plain", "X-API-Key": "wrong"})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestTenants_RejectForeignSignature(t *testing.T) {
+	I, err := NewTester(t, &map[string]any{
+		"Key":         "",
+		"TenantsFile": writeTenants(t),
+	})
+	require.NoError(t, err)
+	defer I.Shutdown()
+
+	delta := int64(1)
+	body, err := json.Marshal(model.NewCounter("c", &delta))
+	require.NoError(t, err)
+
+	resp, err := I.DoRequest(http.MethodPost, "/update", body, signedHeaders(t, "key-a", "secret-b", body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
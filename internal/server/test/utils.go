@@ -14,9 +14,11 @@ import (
 	"github.com/GoLessons/go-musthave-metrics/internal/common/storage"
 	"github.com/GoLessons/go-musthave-metrics/internal/config"
 	serverConfig "github.com/GoLessons/go-musthave-metrics/internal/server/config"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/health"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/model"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/router"
 	"github.com/GoLessons/go-musthave-metrics/internal/server/service"
+	"github.com/GoLessons/go-musthave-metrics/internal/server/telemetry"
 	"github.com/GoLessons/go-musthave-metrics/pkg/container"
 	"github.com/go-chi/chi/v5"
 	"github.com/goccy/go-json"
@@ -29,6 +31,7 @@ type tester struct {
 	httpClient         *http.Client
 	testStorageCounter *storage.MemStorage[model.Counter]
 	testStorageGauge   *storage.MemStorage[model.Gauge]
+	health             *health.Health
 }
 
 func NewTester(t *testing.T, options *map[string]any) (*tester, error) {
@@ -38,8 +41,10 @@ func NewTester(t *testing.T, options *map[string]any) (*tester, error) {
 	}
 	var testStorageCounter = storage.NewMemStorage[model.Counter]()
 	var testStorageGauge = storage.NewMemStorage[model.Gauge]()
-	metricService := service.NewMetricService(testStorageCounter, testStorageGauge)
+	metricService := service.NewMetricService(testStorageCounter, testStorageGauge).
+		WithCardinalityGuard(config.NewCardinalityGuard(cfg))
 	serverLogger, _ := logger.NewLogger(zap.NewDevelopmentConfig())
+	healthChecks := health.New()
 
 	c := container.NewSimpleContainer(map[string]any{
 		"logger":         serverLogger,
@@ -47,8 +52,20 @@ func NewTester(t *testing.T, options *map[string]any) (*tester, error) {
 		"counterStorage": testStorageCounter,
 		"gaugeStorage":   testStorageGauge,
 		"metricService":  metricService,
+		"health":         healthChecks,
+		"telemetry":      telemetry.NewRegistry(),
 	})
 	container.SimpleRegisterFactory(&c, "db", config.DBFactory())
+	container.SimpleRegisterFactory(&c, "tenantRegistry", config.TenantRegistryFactory())
+	container.SimpleRegisterFactory(&c, "limiter", config.LimiterFactory())
+	container.SimpleRegisterFactory(&c, "jwtVerifier", config.JWTVerifierFactory())
+	container.SimpleRegisterFactory(&c, "decrypter", config.DecrypterFactory())
+	container.SimpleRegisterFactory(&c, "replayGuard", config.ReplayGuardFactory())
+	container.SimpleRegisterFactory(&c, "agentKeyring", config.AgentKeyringFactory())
+	container.SimpleRegisterFactory(&c, "auditSubject", config.AuditSubjectFactory())
+	container.SimpleRegisterFactory(&c, "trustedSubnet", config.TrustedSubnetFactory())
+	container.SimpleRegisterFactory(&c, "trustedProxies", config.TrustedProxiesFactory())
+	container.SimpleRegisterFactory(&c, "signer", config.SignerFactory())
 	container.SimpleRegisterFactory(&c, "router", router.RouterFactory())
 
 	r, err := container.GetService[chi.Mux](c, "router")
@@ -62,6 +79,7 @@ func NewTester(t *testing.T, options *map[string]any) (*tester, error) {
 		httpClient:         &http.Client{},
 		testStorageCounter: testStorageCounter,
 		testStorageGauge:   testStorageGauge,
+		health:             healthChecks,
 	}, nil
 }
 
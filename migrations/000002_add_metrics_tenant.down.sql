@@ -0,0 +1,5 @@
+DELETE FROM "metrics"."metrics" WHERE "tenant" <> '';
+
+ALTER TABLE "metrics"."metrics" DROP CONSTRAINT IF EXISTS "metrics_pkey";
+ALTER TABLE "metrics"."metrics" ADD PRIMARY KEY ("name", "type");
+ALTER TABLE "metrics"."metrics" DROP COLUMN IF EXISTS "tenant";
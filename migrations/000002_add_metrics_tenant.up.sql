@@ -0,0 +1,4 @@
+ALTER TABLE "metrics"."metrics" ADD COLUMN IF NOT EXISTS "tenant" text NOT NULL DEFAULT '';
+
+ALTER TABLE "metrics"."metrics" DROP CONSTRAINT IF EXISTS "metrics_pkey";
+ALTER TABLE "metrics"."metrics" ADD PRIMARY KEY ("tenant", "name", "type");
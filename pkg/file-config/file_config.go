@@ -59,11 +59,13 @@ func Save[T any](path string, v T) error {
 	return os.Rename(tmp.Name(), path)
 }
 
+// LoadInto читает файл конфигурации поверх значений v. Формат выбирается по расширению
+// (.json, .yaml/.yml, .toml), ссылки ${VAR} подставляются из окружения, неизвестные поля — ошибка.
 func LoadInto(path string, v any) error {
-	bs, err := os.ReadFile(path)
+	tree, err := Read(path)
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal(bs, v)
+	return Decode(tree, v)
 }
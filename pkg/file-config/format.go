@@ -0,0 +1,120 @@
+package fileconfig
+
+import (
+	"bytes"
+	"encoding"
+	stdjson "encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	unmarshalerType     = reflect.TypeFor[stdjson.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// Read разбирает файл конфигурации в дерево значений: формат по расширению,
+// ${VAR} подставлены в строковые значения. Файл без расширения читается как JSON.
+func Read(path string) (map[string]any, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := parse(strings.ToLower(filepath.Ext(path)), bs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if _, err := interpolateTree(tree, os.LookupEnv); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return tree, nil
+}
+
+func parse(ext string, bs []byte) (map[string]any, error) {
+	switch ext {
+	case ".yaml", ".yml":
+		var doc any
+		if err := yaml.Unmarshal(bs, &doc); err != nil {
+			return nil, err
+		}
+		return rootOf(normalizeYAML(doc))
+	case ".toml":
+		var doc any
+		if err := toml.Unmarshal(bs, &doc); err != nil {
+			return nil, err
+		}
+		return rootOf(doc)
+	case ".json", "":
+		var doc any
+		if err := json.Unmarshal(bs, &doc); err != nil {
+			return nil, err
+		}
+		return rootOf(doc)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", ext)
+	}
+}
+
+func rootOf(doc any) (map[string]any, error) {
+	switch root := doc.(type) {
+	case nil:
+		return map[string]any{}, nil
+	case map[string]any:
+		return root, nil
+	default:
+		return nil, fmt.Errorf("config root must be an object, got %T", doc)
+	}
+}
+
+// normalizeYAML приводит ключи вложенных отображений к строкам, чтобы дерево кодировалось в JSON.
+func normalizeYAML(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, item := range value {
+			value[key] = normalizeYAML(item)
+		}
+		return value
+	case map[any]any:
+		out := make(map[string]any, len(value))
+		for key, item := range value {
+			out[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return out
+	case []any:
+		for i, item := range value {
+			value[i] = normalizeYAML(item)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// Decode переносит дерево в v по правилам encoding/json; неизвестные поля — ошибка.
+func Decode(tree map[string]any, v any) error {
+	if target := reflect.TypeOf(v); target != nil {
+		coerce(tree, target)
+	}
+
+	bs, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	// Строгий разбор идёт через encoding/json: goccy/go-json с DisallowUnknownFields
+	// на широких структурах не находит поля, если регистр ключа не совпадает с именем.
+	decoder := stdjson.NewDecoder(bytes.NewReader(bs))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
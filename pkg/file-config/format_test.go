@@ -0,0 +1,147 @@
+package fileconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type nested struct {
+	Interval uint64 `json:"interval"`
+	Enabled  bool   `json:"enabled"`
+}
+
+type document struct {
+	Address string            `json:"address"`
+	Count   int               `json:"count"`
+	Nested  nested            `json:"nested"`
+	Tags    []string          `json:"tags"`
+	Rules   map[string]string `json:"rules"`
+}
+
+func writeFile(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+	return path
+}
+
+func TestLoadInto_Formats(t *testing.T) {
+	want := document{
+		Address: "localhost:8080",
+		Count:   3,
+		Nested:  nested{Interval: 10, Enabled: true},
+		Tags:    []string{"a", "b"},
+		Rules:   map[string]string{"Cpu*": "max"},
+	}
+
+	files := map[string]string{
+		"cfg.json": `{"address": "localhost:8080", "count": 3, "nested": {"interval": 10, "enabled": true},
+			"tags": ["a", "b"], "rules": {"Cpu*": "max"}}`,
+		"cfg.yaml": "address: localhost:8080\ncount: 3\nnested:\n  interval: 10\n  enabled: true\ntags: [a, b]\nrules:\n  Cpu*: max\n",
+		"cfg.yml":  "address: localhost:8080\ncount: 3\nnested: {interval: 10, enabled: true}\ntags:\n  - a\n  - b\nrules: {Cpu*: max}\n",
+		"cfg.toml": "address = \"localhost:8080\"\ncount = 3\ntags = [\"a\", \"b\"]\n\n[nested]\ninterval = 10\nenabled = true\n\n[rules]\n\"Cpu*\" = \"max\"\n",
+	}
+	for name, body := range files {
+		t.Run(name, func(t *testing.T) {
+			var got document
+			require.NoError(t, LoadInto(writeFile(t, name, body), &got))
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestLoadInto_KeepsDefaults(t *testing.T) {
+	got := document{Address: "default", Nested: nested{Interval: 5, Enabled: true}}
+	require.NoError(t, LoadInto(writeFile(t, "cfg.yaml", "nested:\n  interval: 7\n"), &got))
+
+	require.Equal(t, "default", got.Address)
+	require.Equal(t, nested{Interval: 7, Enabled: true}, got.Nested)
+}
+
+func TestLoadInto_UnknownFields(t *testing.T) {
+	for name, body := range map[string]string{
+		"cfg.json": `{"address": "x", "adress": "typo"}`,
+		"cfg.yaml": "nested:\n  intreval: 1\n",
+		"cfg.toml": "[nested]\nenable = true\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			var got document
+			require.Error(t, LoadInto(writeFile(t, name, body), &got))
+		})
+	}
+}
+
+func TestLoadInto_UnsupportedFormat(t *testing.T) {
+	var got document
+	require.Error(t, LoadInto(writeFile(t, "cfg.ini", "address=x"), &got))
+}
+
+func TestLoadInto_Interpolation(t *testing.T) {
+	t.Setenv("CFG_ADDRESS", "env-host:9090")
+	t.Setenv("CFG_COUNT", "42")
+
+	var got document
+	path := writeFile(t, "cfg.yaml", "address: ${CFG_ADDRESS}\ncount: ${CFG_COUNT}\ntags: [\"${CFG_MISSING:-fallback}\"]\n")
+	require.NoError(t, LoadInto(path, &got))
+	require.Equal(t, "env-host:9090", got.Address)
+	require.Equal(t, 42, got.Count)
+	require.Equal(t, []string{"fallback"}, got.Tags)
+
+	require.Error(t, LoadInto(writeFile(t, "cfg.json", `{"address": "${CFG_MISSING}"}`), &got))
+}
+
+func TestLoadInto_InterpolationSkipsComments(t *testing.T) {
+	var got document
+	path := writeFile(t, "cfg.yaml", "# адрес берётся из ${CFG_UNDOCUMENTED}\naddress: x # ${CFG_UNDOCUMENTED}\n")
+	require.NoError(t, LoadInto(path, &got))
+	require.Equal(t, "x", got.Address)
+
+	path = writeFile(t, "cfg.toml", "# ${CFG_UNDOCUMENTED}\naddress = \"x\"\n")
+	require.NoError(t, LoadInto(path, &got))
+	require.Equal(t, "x", got.Address)
+}
+
+func TestLoadInto_InterpolationCannotInjectStructure(t *testing.T) {
+	t.Setenv("CFG_ADDRESS", "host\ncount: 7")
+	t.Setenv("CFG_QUOTED", `x", "count": 7, "tags": ["y`)
+
+	var got document
+	require.NoError(t, LoadInto(writeFile(t, "cfg.yaml", "address: ${CFG_ADDRESS}\n"), &got))
+	require.Equal(t, "host\ncount: 7", got.Address)
+	require.Zero(t, got.Count)
+
+	got = document{}
+	require.NoError(t, LoadInto(writeFile(t, "cfg.json", `{"address": "${CFG_QUOTED}"}`), &got))
+	require.Equal(t, `x", "count": 7, "tags": ["y`, got.Address)
+	require.Zero(t, got.Count)
+	require.Empty(t, got.Tags)
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		values := map[string]string{"HOST": "db", "EMPTY": ""}
+		v, ok := values[name]
+		return v, ok
+	}
+
+	cases := map[string]string{
+		"postgres://${HOST}/metrics": "postgres://db/metrics",
+		"${EMPTY:-none}":             "none",
+		"${EMPTY}":                   "",
+		"${UNSET:-}":                 "",
+		"cost: $5, $${HOST}":         "cost: $5, ${HOST}",
+	}
+	for in, want := range cases {
+		got, err := Interpolate(in, lookup)
+		require.NoError(t, err, in)
+		require.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"${UNSET}", "${HOST", "${}"} {
+		_, err := Interpolate(in, lookup)
+		require.Error(t, err, in)
+	}
+}
@@ -0,0 +1,182 @@
+package fileconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// expanded — строковое значение, в котором были подставлены переменные окружения.
+// Decode приводит его к числу или bool, если этого требует поле назначения.
+type expanded string
+
+// Interpolate подставляет в строку значения переменных окружения: ${VAR} и ${VAR:-default};
+// default используется, если переменная не задана или пуста. $${ оставляет ${ как есть.
+// Ссылка на незаданную переменную без значения по умолчанию — ошибка.
+func Interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+		if start > 0 && s[start-1] == '$' {
+			out.WriteString(s[:start])
+			out.WriteString("{")
+			s = s[start+2:]
+			continue
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ at offset %d", start)
+		}
+		out.WriteString(s[:start])
+
+		expr := s[start+2 : start+end]
+		name, fallback, hasFallback := strings.Cut(expr, ":-")
+		if name == "" {
+			return "", fmt.Errorf("empty variable name in ${%s}", expr)
+		}
+		value, ok := lookup(name)
+		switch {
+		case ok && value != "":
+			out.WriteString(value)
+		case hasFallback:
+			out.WriteString(fallback)
+		case ok:
+		default:
+			return "", fmt.Errorf("variable %s is not set", name)
+		}
+
+		s = s[start+end+1:]
+	}
+}
+
+// interpolateTree подставляет переменные только в строковые значения уже разобранного дерева:
+// комментарии и ключи не раскрываются, а значение переменной не может изменить структуру файла.
+func interpolateTree(v any, lookup func(string) (string, bool)) (any, error) {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, item := range value {
+			out, err := interpolateTree(item, lookup)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			value[key] = out
+		}
+		return value, nil
+	case []any:
+		for i, item := range value {
+			out, err := interpolateTree(item, lookup)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			value[i] = out
+		}
+		return value, nil
+	case string:
+		if !strings.Contains(value, "${") {
+			return value, nil
+		}
+		out, err := Interpolate(value, lookup)
+		if err != nil {
+			return nil, err
+		}
+		return expanded(out), nil
+	default:
+		return value, nil
+	}
+}
+
+// coerce приводит подставленные строки к числам и bool там, где этого ждёт поле типа t,
+// чтобы `count: ${COUNT}` работало так же, как `count: 42`. Остальные значения не меняются.
+func coerce(v any, t reflect.Type) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return v
+	}
+
+	switch value := v.(type) {
+	case map[string]any:
+		for key, item := range value {
+			if elem, ok := childType(t, key); ok {
+				value[key] = coerce(item, elem)
+			}
+		}
+		return value
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range value {
+				value[i] = coerce(item, t.Elem())
+			}
+		}
+		return value
+	case expanded:
+		return coerceScalar(string(value), t.Kind())
+	default:
+		return value
+	}
+}
+
+func coerceScalar(raw string, kind reflect.Kind) any {
+	switch kind {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(raw, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f
+		}
+	}
+	return raw
+}
+
+// childType находит тип значения по ключу так же, как encoding/json: по тегу или имени поля без учёта регистра.
+func childType(t reflect.Type, key string) (reflect.Type, bool) {
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem(), true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous && f.Tag.Get("json") == "" {
+				embedded := f.Type
+				if embedded.Kind() == reflect.Pointer {
+					embedded = embedded.Elem()
+				}
+				if elem, ok := childType(embedded, key); ok {
+					return elem, true
+				}
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if strings.EqualFold(name, key) {
+				return f.Type, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,79 @@
+package fileconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTOML(t *testing.T) {
+	src := `
+# agent config
+address = "localhost:8080" # inline comment
+report_interval = 1_000
+ratio = 0.5
+mask = 0xff
+enabled = true
+path = 'C:\agent\spool'
+escaped = "tab\tquote\"\u00e9"
+text = """
+first \
+  second"""
+dotted.key = "v"
+matrix = [
+  [1, 2],
+  ["a", 'b'], # trailing comma allowed
+]
+point = { x = 1, y = { z = "deep" } }
+
+[readers.cgroup]
+enabled = true
+
+[[destinations]]
+name = "primary"
+
+[[destinations]]
+name = "backup"
+[destinations.limits]
+rate = 5
+`
+	got, err := parse(".toml", []byte(src))
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]any{
+		"address":         "localhost:8080",
+		"report_interval": int64(1000),
+		"ratio":           0.5,
+		"mask":            int64(255),
+		"enabled":         true,
+		"path":            `C:\agent\spool`,
+		"escaped":         "tab\tquote\"é",
+		"text":            "first second",
+		"dotted":          map[string]any{"key": "v"},
+		"matrix":          []any{[]any{int64(1), int64(2)}, []any{"a", "b"}},
+		"point":           map[string]any{"x": int64(1), "y": map[string]any{"z": "deep"}},
+		"readers":         map[string]any{"cgroup": map[string]any{"enabled": true}},
+		"destinations": []any{
+			map[string]any{"name": "primary"},
+			map[string]any{"name": "backup", "limits": map[string]any{"rate": int64(5)}},
+		},
+	}, got)
+}
+
+func TestParseTOML_Errors(t *testing.T) {
+	for name, src := range map[string]string{
+		"duplicate key":     "a = 1\na = 2\n",
+		"missing equals":    "a 1\n",
+		"unterminated":      "a = \"text\n",
+		"trailing garbage":  "a = 1 b\n",
+		"table over value":  "a = 1\n[a]\n",
+		"unclosed header":   "[a\n",
+		"bad escape":        `a = "\q"` + "\n",
+		"unterminated list": "a = [1, 2\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parse(".toml", []byte(src))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package repeater
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen возвращается вместо вызова действия, пока размыкатель разомкнут.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker размыкается после threshold неудач подряд и в течение cooldown
+// отклоняет вызовы без обращения к ресурсу. Затем пропускает один пробный вызов (half-open):
+// успех замыкает цепь, неудача снова размыкает её на cooldown.
+type CircuitBreaker struct {
+	threshold uint
+	cooldown  time.Duration
+	isFailure func(error) bool
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures uint
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// NewCircuitBreaker создаёт размыкатель; isFailure отбирает ошибки, говорящие о недоступности ресурса
+// (nil — любые ошибки). Прочие ошибки не влияют на состояние.
+func NewCircuitBreaker(threshold uint, cooldown time.Duration, isFailure func(error) bool) *CircuitBreaker {
+	if threshold == 0 {
+		threshold = 1
+	}
+
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		isFailure: isFailure,
+		now:       time.Now,
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return StateHalfOpen
+	}
+
+	return b.state
+}
+
+// Wrap возвращает действие, выполняемое через размыкатель. Nil-размыкатель возвращает action как есть.
+func (b *CircuitBreaker) Wrap(action Action) Action {
+	if b == nil {
+		return action
+	}
+
+	return func() (any, error) {
+		if err := b.allow(); err != nil {
+			return nil, err
+		}
+
+		result, err := action()
+		b.record(err)
+
+		return result, err
+	}
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := err != nil && (b.isFailure == nil || b.isFailure(err))
+	if !failed {
+		b.state = StateClosed
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+		b.probing = false
+	}
+}
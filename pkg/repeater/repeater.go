@@ -1,6 +1,11 @@
 package repeater
 
-import "time"
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
 
 type repeater struct {
 	onError []OnError
@@ -21,30 +26,58 @@ type Action func() (any, error)
 type OnError func(err error)
 
 func (r *repeater) Repeat(strategy Strategy, action Action) (result any, err error) {
+	return r.RepeatContext(context.Background(), strategy, action)
+}
+
+// RepeatContext повторяет action по стратегии, прерывая ожидание между попытками при отмене ctx.
+// После последней попытки пауза не выдерживается.
+func (r *repeater) RepeatContext(ctx context.Context, strategy Strategy, action Action) (result any, err error) {
 	attempts := strategy.Attempts()
 	infinity := attempts == 0
 	for attempt := uint(1); infinity || attempt <= attempts; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, interrupted(ctxErr, err)
+		}
+
 		result, err = action()
-		if err != nil {
-			for _, onError := range r.onError {
-				onError(err)
-			}
+		if err == nil {
+			return result, nil
+		}
 
-			if !strategy.Retriable(err) {
-				return nil, err
-			}
+		for _, onError := range r.onError {
+			onError(err)
+		}
+
+		if !strategy.Retriable(err) {
+			return nil, err
+		}
 
-			if strategy.Delay(attempt) > 0 {
-				time.Sleep(strategy.Delay(attempt))
+		if !infinity && attempt == attempts {
+			break
+		}
+
+		if delay := strategy.Delay(attempt); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, interrupted(ctx.Err(), err)
+			case <-timer.C:
 			}
-		} else {
-			return result, nil
 		}
 	}
 
 	return result, err
 }
 
+func interrupted(ctxErr error, lastErr error) error {
+	if lastErr == nil {
+		return ctxErr
+	}
+
+	return fmt.Errorf("%w: %w", ctxErr, lastErr)
+}
+
 type fixedDelaysStrategy struct {
 	delays      []time.Duration
 	attempts    uint
@@ -70,3 +103,54 @@ func (s *fixedDelaysStrategy) Retriable(err error) bool {
 func (s *fixedDelaysStrategy) Attempts() uint {
 	return s.attempts
 }
+
+// exponentialBackoffStrategy выбирает паузу равномерно от нуля до base*2^(iter-1), но не больше maxDelay
+// (full jitter), чтобы агенты, потерявшие сервер одновременно, не возвращались к нему синхронно.
+type exponentialBackoffStrategy struct {
+	base        time.Duration
+	maxDelay    time.Duration
+	attempts    uint
+	isRetriable func(error) bool
+}
+
+func NewExponentialBackoffStrategy(isRetriable func(error) bool, base time.Duration, maxDelay time.Duration, attempts uint) *exponentialBackoffStrategy {
+	if maxDelay < base {
+		maxDelay = base
+	}
+
+	return &exponentialBackoffStrategy{base: base, maxDelay: maxDelay, attempts: attempts, isRetriable: isRetriable}
+}
+
+func (s *exponentialBackoffStrategy) Delay(iter uint) time.Duration {
+	ceiling := s.Ceiling(iter)
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return rand.N(ceiling + 1)
+}
+
+// Ceiling возвращает верхнюю границу паузы перед попыткой iter+1.
+func (s *exponentialBackoffStrategy) Ceiling(iter uint) time.Duration {
+	if iter == 0 || s.base <= 0 {
+		return 0
+	}
+
+	ceiling := s.base
+	for i := uint(1); i < iter; i++ {
+		if ceiling >= s.maxDelay/2 {
+			return s.maxDelay
+		}
+		ceiling *= 2
+	}
+
+	return min(ceiling, s.maxDelay)
+}
+
+func (s *exponentialBackoffStrategy) Retriable(err error) bool {
+	return s.isRetriable(err)
+}
+
+func (s *exponentialBackoffStrategy) Attempts() uint {
+	return s.attempts
+}
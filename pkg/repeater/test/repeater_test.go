@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -127,3 +128,86 @@ func (s *mockInfiniteStrategy) Attempts() uint {
 func (s *mockInfiniteStrategy) Retriable(err error) bool {
 	return true
 }
+
+func TestRepeatDoesNotWaitAfterLastAttempt(t *testing.T) {
+	r := repeater.NewRepeater()
+	strategy := repeater.NewFixedDelaysStrategy(func(error) bool { return true }, time.Millisecond, time.Second)
+
+	started := time.Now()
+	_, err := r.Repeat(strategy, func() (any, error) { return nil, errors.New("ошибка") })
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(started), 500*time.Millisecond)
+}
+
+func TestRepeatContextAbortsOnCancel(t *testing.T) {
+	r := repeater.NewRepeater()
+	strategy := repeater.NewFixedDelaysStrategy(func(error) bool { return true }, time.Minute, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	lastErr := errors.New("временная ошибка")
+	callCount := 0
+	started := time.Now()
+	_, err := r.RepeatContext(ctx, strategy, func() (any, error) {
+		callCount++
+		return nil, lastErr
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, lastErr)
+	assert.Equal(t, 1, callCount)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestExponentialBackoffStrategy(t *testing.T) {
+	strategy := repeater.NewExponentialBackoffStrategy(func(error) bool { return true }, 100*time.Millisecond, time.Second, 5)
+
+	assert.Equal(t, uint(5), strategy.Attempts())
+	assert.Equal(t, 100*time.Millisecond, strategy.Ceiling(1))
+	assert.Equal(t, 200*time.Millisecond, strategy.Ceiling(2))
+	assert.Equal(t, 800*time.Millisecond, strategy.Ceiling(4))
+	assert.Equal(t, time.Second, strategy.Ceiling(5), "Пауза ограничена сверху")
+	assert.Equal(t, time.Second, strategy.Ceiling(100), "Большие номера попыток не переполняют задержку")
+
+	for iter := uint(1); iter <= 5; iter++ {
+		for i := 0; i < 100; i++ {
+			delay := strategy.Delay(iter)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.LessOrEqual(t, delay, strategy.Ceiling(iter))
+		}
+	}
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	unavailable := errors.New("connection refused")
+	rejected := errors.New("bad request")
+	breaker := repeater.NewCircuitBreaker(2, 30*time.Millisecond, func(err error) bool { return err == unavailable })
+
+	calls := 0
+	call := func(err error) error {
+		_, result := breaker.Wrap(func() (any, error) {
+			calls++
+			return nil, err
+		})()
+		return result
+	}
+
+	assert.ErrorIs(t, call(rejected), rejected)
+	assert.ErrorIs(t, call(unavailable), unavailable)
+	assert.Equal(t, repeater.StateClosed, breaker.State(), "Ошибки, не означающие недоступность, не размыкают цепь")
+
+	assert.ErrorIs(t, call(unavailable), unavailable)
+	assert.Equal(t, repeater.StateOpen, breaker.State())
+	assert.ErrorIs(t, call(nil), repeater.ErrCircuitOpen)
+	assert.Equal(t, 3, calls, "Разомкнутая цепь не вызывает действие")
+
+	time.Sleep(40 * time.Millisecond)
+	assert.Equal(t, repeater.StateHalfOpen, breaker.State())
+	assert.ErrorIs(t, call(unavailable), unavailable, "Пробный вызов пропускается")
+	assert.Equal(t, repeater.StateOpen, breaker.State(), "Неудачная проба снова размыкает цепь")
+
+	time.Sleep(40 * time.Millisecond)
+	assert.NoError(t, call(nil))
+	assert.Equal(t, repeater.StateClosed, breaker.State())
+}
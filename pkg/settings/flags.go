@@ -0,0 +1,84 @@
+package settings
+
+import (
+	"flag"
+	"reflect"
+
+	"github.com/spf13/pflag"
+)
+
+// flagValue запоминает значение флага, не записывая его в поле: флаги применяются в Load поверх окружения.
+type flagValue struct {
+	loader *Loader
+	field  *field
+	name   string
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.field == nil {
+		return ""
+	}
+	if v.field.value.Kind() == reflect.String {
+		return v.field.value.String()
+	}
+	return formatValue(v.field.value)
+}
+
+// Set проверяет значение сразу, чтобы ошибка указывала на флаг при разборе командной строки.
+func (v *flagValue) Set(raw string) error {
+	if err := setString(reflect.New(v.field.value.Type()).Elem(), raw); err != nil {
+		return err
+	}
+	v.loader.flags[v.field.path] = flagHit{raw: raw, name: v.name}
+
+	return nil
+}
+
+// Type нужен pflag для справки.
+func (v *flagValue) Type() string {
+	return v.field.value.Type().String()
+}
+
+// boolFlagValue позволяет писать логический флаг без значения. Отдельный тип нужен потому,
+// что pflag по наличию IsBoolFlag считает флаг логическим и иначе выводит значения по умолчанию.
+type boolFlagValue struct {
+	flagValue
+}
+
+func (v *boolFlagValue) IsBoolFlag() bool {
+	return true
+}
+
+func newFlagValue(l *Loader, f *field, name string) pflag.Value {
+	v := flagValue{loader: l, field: f, name: name}
+	if f.value.Kind() == reflect.Bool {
+		return &boolFlagValue{flagValue: v}
+	}
+	return &v
+}
+
+// DefineFlags объявляет флаги полей с тегом flag в наборе стандартного пакета flag; сокращение — отдельный флаг.
+func (l *Loader) DefineFlags(fs *flag.FlagSet) {
+	for _, f := range l.fields {
+		if f.flag == "" {
+			continue
+		}
+		fs.Var(newFlagValue(l, f, "-"+f.flag), f.flag, f.usage)
+		if f.short != "" {
+			fs.Var(newFlagValue(l, f, "-"+f.short), f.short, f.usage+" (short)")
+		}
+	}
+}
+
+// DefinePFlags объявляет флаги полей с тегом flag в наборе pflag (cobra).
+func (l *Loader) DefinePFlags(fs *pflag.FlagSet) {
+	for _, f := range l.fields {
+		if f.flag == "" {
+			continue
+		}
+		defined := fs.VarPF(newFlagValue(l, f, "--"+f.flag), f.flag, f.short, f.usage)
+		if f.value.Kind() == reflect.Bool {
+			defined.NoOptDefVal = "true"
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package settings
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/goccy/go-json"
+)
+
+const secretMask = "******"
+
+// Entry — итоговое значение поля и его источник.
+type Entry struct {
+	Path   string
+	Value  string
+	Source string
+	// Origin — имя в источнике: переменная окружения, флаг или путь к файлу.
+	Origin string
+}
+
+// Report перечисляет поля в порядке объявления в структуре.
+type Report []Entry
+
+func (l *Loader) report() Report {
+	report := make(Report, 0, len(l.fields))
+	for _, f := range l.fields {
+		entry := Entry{Path: f.path, Value: formatValue(f.value), Source: SourceDefault}
+		if o, ok := l.origins[f.path]; ok {
+			entry.Source, entry.Origin = o.source, o.name
+		}
+		if f.secret && !f.value.IsZero() {
+			entry.Value = secretMask
+		}
+		report = append(report, entry)
+	}
+
+	return report
+}
+
+// Lookup возвращает запись поля по пути.
+func (r Report) Lookup(path string) (Entry, bool) {
+	for _, entry := range r {
+		if entry.Path == path {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// WriteTo печатает отчёт таблицей: путь, значение, источник.
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, entry := range r {
+		source := entry.Source
+		if entry.Origin != "" {
+			source += " " + entry.Origin
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", entry.Path, entry.Value, source)
+	}
+	if err := tw.Flush(); err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+func formatValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		return strconv.Quote(value.String())
+	case reflect.Map, reflect.Slice, reflect.Struct, reflect.Pointer, reflect.Interface:
+		bs, err := json.Marshal(value.Interface())
+		if err != nil {
+			return fmt.Sprint(value.Interface())
+		}
+		return string(bs)
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
@@ -0,0 +1,272 @@
+// Package settings собирает конфигурацию из нескольких источников по тегам полей структуры.
+//
+// Приоритет источников: значения по умолчанию < файл < окружение < флаги < явные переопределения.
+// Значения по умолчанию — то, что лежит в структуре до загрузки. Теги полей:
+//
+//	env:"ADDRESS"              — переменная окружения; пустое значение не учитывается;
+//	flag:"address,a"           — флаг и необязательное однобуквенное сокращение;
+//	usage:"HTTP server address" — описание флага;
+//	secret:"true"              — значение скрывается в отчёте.
+//
+// Вложенные структуры обходятся рекурсивно, путь поля — имена через точку (DumpConfig.StoreInterval).
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	fileconfig "github.com/GoLessons/go-musthave-metrics/pkg/file-config"
+)
+
+// Источники значений в порядке возрастания приоритета.
+const (
+	SourceDefault  = "default"
+	SourceFile     = "file"
+	SourceEnv      = "env"
+	SourceFlag     = "flag"
+	SourceOverride = "override"
+)
+
+var ErrNotStructPointer = errors.New("settings: target must be a pointer to struct")
+
+type field struct {
+	path     string
+	fileKeys []string
+	env      string
+	flag     string
+	short    string
+	usage    string
+	secret   bool
+	value    reflect.Value
+}
+
+// origin — откуда взято значение поля: источник и имя в нём (переменная, флаг, файл).
+type origin struct {
+	source string
+	name   string
+}
+
+type flagHit struct {
+	raw  string
+	name string
+}
+
+// Loader применяет источники к структуре target и запоминает, откуда взято каждое значение.
+type Loader struct {
+	target    any
+	fields    []*field
+	file      string
+	lookupEnv func(string) (string, bool)
+	overrides map[string]any
+	flags     map[string]flagHit
+	origins   map[string]origin
+}
+
+// New готовит загрузку в target — указатель на структуру, уже заполненную значениями по умолчанию.
+func New(target any) (*Loader, error) {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotStructPointer
+	}
+
+	l := &Loader{
+		target:    target,
+		lookupEnv: os.LookupEnv,
+		flags:     make(map[string]flagHit),
+		origins:   make(map[string]origin),
+	}
+	collect(value.Elem(), "", nil, &l.fields)
+
+	return l, nil
+}
+
+func collect(value reflect.Value, prefix string, fileKeys []string, fields *[]*field) {
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		sf := structType.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		fileKey := sf.Name
+		if tag, _, _ := strings.Cut(sf.Tag.Get("json"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			fileKey = tag
+		}
+		path := prefix + sf.Name
+		keys := append(fileKeys[:len(fileKeys):len(fileKeys)], fileKey)
+
+		if sf.Type.Kind() == reflect.Struct {
+			collect(value.Field(i), path+".", keys, fields)
+			continue
+		}
+
+		f := &field{
+			path:     path,
+			fileKeys: keys,
+			env:      sf.Tag.Get("env"),
+			usage:    sf.Tag.Get("usage"),
+			secret:   sf.Tag.Get("secret") == "true",
+			value:    value.Field(i),
+		}
+		f.flag, f.short, _ = strings.Cut(sf.Tag.Get("flag"), ",")
+		*fields = append(*fields, f)
+	}
+}
+
+// WithFile задаёт файл конфигурации; пустой путь — без файла.
+func (l *Loader) WithFile(path string) *Loader {
+	l.file = path
+	return l
+}
+
+// WithEnv заменяет источник переменных окружения, по умолчанию os.LookupEnv.
+func (l *Loader) WithEnv(lookup func(string) (string, bool)) *Loader {
+	l.lookupEnv = lookup
+	return l
+}
+
+// WithOverrides задаёт значения по пути поля, которые перекрывают все источники; тип значения должен совпадать с типом поля.
+func (l *Loader) WithOverrides(values map[string]any) *Loader {
+	l.overrides = values
+	return l
+}
+
+// Load применяет файл, окружение, флаги и переопределения и возвращает отчёт об источниках.
+func (l *Loader) Load() (Report, error) {
+	if l.file != "" {
+		if err := l.loadFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range l.fields {
+		if f.env == "" {
+			continue
+		}
+		raw, ok := l.lookupEnv(f.env)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setString(f.value, raw); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга %s: %w", f.env, err)
+		}
+		l.origins[f.path] = origin{source: SourceEnv, name: f.env}
+	}
+
+	for _, f := range l.fields {
+		hit, ok := l.flags[f.path]
+		if !ok {
+			continue
+		}
+		if err := setString(f.value, hit.raw); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга флага %s: %w", hit.name, err)
+		}
+		l.origins[f.path] = origin{source: SourceFlag, name: hit.name}
+	}
+
+	for path, v := range l.overrides {
+		f := l.field(path)
+		if f == nil {
+			return nil, fmt.Errorf("неизвестное поле конфигурации %s", path)
+		}
+		value := reflect.ValueOf(v)
+		if !value.IsValid() || !value.Type().AssignableTo(f.value.Type()) {
+			return nil, fmt.Errorf("поле %s имеет тип %s, передан %T", path, f.value.Type(), v)
+		}
+		f.value.Set(value)
+		l.origins[path] = origin{source: SourceOverride}
+	}
+
+	return l.report(), nil
+}
+
+func (l *Loader) loadFile() error {
+	tree, err := fileconfig.Read(l.file)
+	if err != nil {
+		return err
+	}
+	if err := fileconfig.Decode(tree, l.target); err != nil {
+		return fmt.Errorf("%s: %w", l.file, err)
+	}
+
+	for _, f := range l.fields {
+		if present(tree, f.fileKeys) {
+			l.origins[f.path] = origin{source: SourceFile, name: l.file}
+		}
+	}
+
+	return nil
+}
+
+// present сообщает, задан ли ключ в дереве файла; имена сравниваются без учёта регистра, как при разборе JSON.
+func present(tree map[string]any, keys []string) bool {
+	var node any = tree
+	for _, key := range keys {
+		table, ok := node.(map[string]any)
+		if !ok {
+			return false
+		}
+		found := false
+		for name, child := range table {
+			if strings.EqualFold(name, key) {
+				node, found = child, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (l *Loader) field(path string) *field {
+	for _, f := range l.fields {
+		if f.path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+func setString(target reflect.Value, raw string) error {
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		target.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetFloat(n)
+	default:
+		return fmt.Errorf("тип %s не задаётся строкой", target.Type())
+	}
+
+	return nil
+}
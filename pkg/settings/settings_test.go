@@ -0,0 +1,156 @@
+package settings
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dump struct {
+	Interval uint64 `env:"STORE_INTERVAL" flag:"store-interval,i" usage:"Store interval in seconds"`
+	Restore  bool   `env:"RESTORE" flag:"restore,r" usage:"Restore state"`
+}
+
+type sample struct {
+	Address string  `env:"ADDRESS" flag:"address,a" usage:"Server address"`
+	Key     string  `env:"KEY" flag:"key" secret:"true"`
+	Ratio   float64 `env:"RATIO"`
+	Dump    dump
+	Rules   map[string]string `json:"rules"`
+	Ignored string            `json:"-"`
+}
+
+func defaults() *sample {
+	return &sample{Address: "localhost:8080", Dump: dump{Interval: 300}}
+}
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := values[name]
+		return v, ok
+	}
+}
+
+func writeConfig(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+	return path
+}
+
+func TestLoader_Precedence(t *testing.T) {
+	cfg := defaults()
+	loader, err := New(cfg)
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader.DefineFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-a", "flag:1", "-restore"}))
+
+	path := writeConfig(t, "cfg.yaml", "address: file:1\nkey: secret\ndump:\n  interval: 60\n  restore: false\nrules:\n  Cpu*: max\n")
+	report, err := loader.
+		WithFile(path).
+		WithEnv(env(map[string]string{"ADDRESS": "env:1", "STORE_INTERVAL": "30", "RATIO": ""})).
+		Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "flag:1", cfg.Address)
+	assert.Equal(t, "secret", cfg.Key)
+	assert.EqualValues(t, 30, cfg.Dump.Interval)
+	assert.True(t, cfg.Dump.Restore)
+	assert.Zero(t, cfg.Ratio)
+	assert.Equal(t, map[string]string{"Cpu*": "max"}, cfg.Rules)
+
+	expect := map[string]Entry{
+		"Address":       {Path: "Address", Value: `"flag:1"`, Source: SourceFlag, Origin: "-a"},
+		"Key":           {Path: "Key", Value: secretMask, Source: SourceFile, Origin: path},
+		"Ratio":         {Path: "Ratio", Value: "0", Source: SourceDefault},
+		"Dump.Interval": {Path: "Dump.Interval", Value: "30", Source: SourceEnv, Origin: "STORE_INTERVAL"},
+		"Dump.Restore":  {Path: "Dump.Restore", Value: "true", Source: SourceFlag, Origin: "-restore"},
+		"Rules":         {Path: "Rules", Value: `{"Cpu*":"max"}`, Source: SourceFile, Origin: path},
+	}
+	for path, want := range expect {
+		got, ok := report.Lookup(path)
+		require.True(t, ok, path)
+		assert.Equal(t, want, got)
+	}
+	_, ok := report.Lookup("Ignored")
+	assert.False(t, ok)
+}
+
+func TestLoader_PFlags(t *testing.T) {
+	cfg := defaults()
+	loader, err := New(cfg)
+	require.NoError(t, err)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	loader.DefinePFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-i", "15", "--restore", "--address=pflag:1"}))
+
+	report, err := loader.WithEnv(env(map[string]string{"ADDRESS": "env:1"})).Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "pflag:1", cfg.Address)
+	assert.EqualValues(t, 15, cfg.Dump.Interval)
+	assert.True(t, cfg.Dump.Restore)
+	entry, _ := report.Lookup("Dump.Interval")
+	assert.Equal(t, "--store-interval", entry.Origin)
+}
+
+func TestLoader_Errors(t *testing.T) {
+	loader, err := New(defaults())
+	require.NoError(t, err)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&strings.Builder{})
+	loader.DefineFlags(fs)
+	assert.Error(t, fs.Parse([]string{"-store-interval=soon"}), "flag values are validated while parsing")
+
+	_, err = loader.WithEnv(env(map[string]string{"RESTORE": "maybe"})).Load()
+	assert.ErrorContains(t, err, "RESTORE")
+
+	loader, _ = New(defaults())
+	_, err = loader.WithFile(writeConfig(t, "cfg.json", `{"adress": "typo"}`)).WithEnv(env(nil)).Load()
+	assert.Error(t, err)
+
+	loader, _ = New(defaults())
+	_, err = loader.WithEnv(env(nil)).WithOverrides(map[string]any{"Dump.Interval": 5}).Load()
+	assert.ErrorContains(t, err, "Dump.Interval")
+
+	_, err = New(sample{})
+	assert.ErrorIs(t, err, ErrNotStructPointer)
+}
+
+func TestLoader_Overrides(t *testing.T) {
+	cfg := defaults()
+	loader, err := New(cfg)
+	require.NoError(t, err)
+
+	report, err := loader.
+		WithEnv(env(map[string]string{"STORE_INTERVAL": "30"})).
+		WithOverrides(map[string]any{"Dump.Interval": uint64(5), "Key": ""}).
+		Load()
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 5, cfg.Dump.Interval)
+	entry, _ := report.Lookup("Dump.Interval")
+	assert.Equal(t, SourceOverride, entry.Source)
+}
+
+func TestReport_WriteTo(t *testing.T) {
+	report := Report{
+		{Path: "Address", Value: `"localhost:8080"`, Source: SourceDefault},
+		{Path: "Dump.Interval", Value: "30", Source: SourceEnv, Origin: "STORE_INTERVAL"},
+	}
+
+	var out strings.Builder
+	_, err := report.WriteTo(&out)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Address        \"localhost:8080\"  default\nDump.Interval  30                env STORE_INTERVAL\n", out.String())
+}